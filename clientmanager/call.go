@@ -37,29 +37,66 @@ func getResponseBody[Response any](raw []byte, contentType string) (Response, er
 	return response, nil
 }
 
-func execute(ctx context.Context, endpoint string, cOptions callOptions) (*http.Response, *logmanager.TxnRecord, error) {
+func dispatch(ctx context.Context, endpoint string, cOptions callOptions) (*http.Response, *logmanager.TxnRecord, error) {
 	if err := cOptions.validate(); err != nil {
 		return nil, nil, err
 	}
 
+	if cOptions.canHedge() {
+		return executeHedged(ctx, endpoint, cOptions)
+	}
+
+	res, txn, err := execute(ctx, endpoint, cOptions)
+	if err != nil {
+		txn.NoticeError(err)
+
+		return nil, nil, err
+	}
+
+	return res, txn, nil
+}
+
+// execute builds and sends a single request inside its own logmanager segment.
+// On error the segment is returned without being ended, so the caller decides how to log it.
+func execute(ctx context.Context, endpoint string, cOptions callOptions) (*http.Response, *logmanager.TxnRecord, error) {
 	req, err := cOptions.getRequest(ctx, endpoint)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	txn := logmanager.StartApiSegment(logmanager.ApiSegment{
+		Name:    cOptions.segmentName(endpoint),
 		Request: req,
+		Host:    cOptions.unixSocket,
 	})
 	if txn == nil {
 		return nil, nil, errors.New("transaction from the request context cannot be empty")
 	}
+	if len(cOptions.maskingConfigs) > 0 {
+		if body := cOptions.loggedRequestBody(); body != nil {
+			host := req.Host
+			if cOptions.unixSocket != "" {
+				host = cOptions.unixSocket
+			}
+			txn.SetWebRequestRawMasked(body, logmanager.WebRequest{
+				Header: req.Header,
+				URL:    req.URL,
+				Method: req.Method,
+				Host:   host,
+			}, cOptions.maskingConfigs)
+		}
+	}
+
+	var probe *metricsProbe
+	if cOptions.metrics != nil {
+		probe = startMetricsProbe(cOptions.metrics, cOptions.metricLabels(req, endpoint))
+		req = probe.withTrace(req)
+	}
 
 	res, err := cOptions.client.Do(req) // #nosec G704 - This is a client library, SSRF protection is caller's responsibility
+	probe.finish(res, err)
 	if err != nil {
-		txn.NoticeError(err)
-		txn.End()
-
-		return nil, nil, err
+		return nil, txn, err
 	}
 
 	txn.SetResponse(res)
@@ -67,12 +104,22 @@ func execute(ctx context.Context, endpoint string, cOptions callOptions) (*http.
 	return res, txn, nil
 }
 
+// readBody reads the response body, truncated at maxBytes when it is positive.
+func readBody(res *http.Response, maxBytes int64) []byte {
+	var reader io.Reader = res.Body
+	if maxBytes > 0 {
+		reader = io.LimitReader(res.Body, maxBytes)
+	}
+	raw, _ := io.ReadAll(reader)
+	return raw
+}
+
 func call[Response any](
 	ctx context.Context,
 	endpoint string,
 	cOptions callOptions,
 ) (*BaseResponse[Response], error) {
-	res, txn, err := execute(ctx, endpoint, cOptions)
+	res, txn, err := dispatch(ctx, endpoint, cOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -81,11 +128,7 @@ func call[Response any](
 		_ = res.Body.Close()
 	}()
 
-	var reader io.Reader = res.Body
-	if cOptions.maxResponseBytes > 0 {
-		reader = io.LimitReader(res.Body, cOptions.maxResponseBytes)
-	}
-	raw, _ := io.ReadAll(reader)
+	raw := readBody(res, cOptions.maxResponseBytes)
 
 	response, err := getResponseBody[Response](raw, res.Header.Get("Content-Type"))
 	if err != nil {
@@ -2,22 +2,28 @@ package clientmanager
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/Azure/go-ntlmssp"
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
 	"github.com/dghubble/oauth1"
 	"github.com/icholy/digest"
 	validator "github.com/go-playground/validator/v10"
 	"golang.org/x/oauth2"
 )
 
+// unixSocketHost is the host used for requests over a Unix socket when WithHost is not set.
+const unixSocketHost = "http://localhost"
+
 var (
 	validate     *validator.Validate
 	validateOnce sync.Once
@@ -34,12 +40,25 @@ type callOptions struct {
 	multipartForm         MultipartForm        // Enhanced multipart support
 	requestBody           any
 	urlValues             url.Values
+	pathParams            map[string]string
+	query                 url.Values
+	paramsErr             error
 	bodyReader            io.Reader
 	bodyReaderContentType string
 	dialerControl         func(network, address string, c syscall.RawConn) error
 	dialTimeout           time.Duration
 	dialKeepAlive         time.Duration
+	dialer                DialFunc
+	unixSocket            string
 	maxResponseBytes      int64
+	metrics               MetricsRecorder
+	upstreamName          string
+	hedgeDelay            time.Duration
+	maxHedges             int
+	segment               string
+	operationName         string
+	persistedQuery        bool
+	maskingConfigs        []logmanager.MaskingConfig
 }
 
 func (c *callOptions) setOptions(options ...Option) {
@@ -50,15 +69,36 @@ func (c *callOptions) setOptions(options ...Option) {
 }
 
 // resolve applies deferred settings that depend on the combination of options.
-// Currently it rebuilds the transport dialer when a Control function is set.
+// Currently it rebuilds the transport dialer when a custom dialer, a Unix socket
+// or a Control function is set.
 func (c *callOptions) resolve() {
-	if c.dialerControl == nil {
+	if c.dialer == nil && c.unixSocket == "" && c.dialerControl == nil {
 		return
 	}
 	tr := c.baseTransport()
 	if tr == nil {
 		return
 	}
+	switch {
+	case c.dialer != nil:
+		tr.DialContext = c.dialer
+	case c.unixSocket != "":
+		dialer := c.netDialer()
+		socket := c.unixSocket
+		tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		// The socket is the target, so an environment proxy must not be used.
+		tr.Proxy = nil
+	default:
+		dialer := c.netDialer()
+		dialer.Control = c.dialerControl
+		tr.DialContext = dialer.DialContext
+	}
+}
+
+// netDialer returns a dialer using the WithDialContext values, or the library defaults.
+func (c *callOptions) netDialer() *net.Dialer {
 	timeout := c.dialTimeout
 	if timeout <= 0 {
 		timeout = 2 * time.Second
@@ -67,12 +107,11 @@ func (c *callOptions) resolve() {
 	if keepAlive <= 0 {
 		keepAlive = 60 * time.Second
 	}
-	tr.DialContext = (&net.Dialer{
+	return &net.Dialer{
 		Timeout:   timeout,
 		KeepAlive: keepAlive,
 		DualStack: true,
-		Control:   c.dialerControl,
-	}).DialContext
+	}
 }
 
 // baseTransport walks through transport wrappers (digest, NTLM, OAuth1/2) to
@@ -115,6 +154,24 @@ func (c callOptions) validate() error {
 	return nil
 }
 
+// loggedRequestBody returns the JSON request body as a generic value for masked logging,
+// or nil when the body is not sent as JSON.
+func (c callOptions) loggedRequestBody() any {
+	if c.requestBody == nil || c.bodyReader != nil || c.isFormURLEncoded ||
+		len(c.files) > 0 || len(c.multipartForm.Files) > 0 || len(c.multipartForm.Values) > 0 {
+		return nil
+	}
+	data, err := json.Marshal(c.requestBody)
+	if err != nil {
+		return nil
+	}
+	var body any
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil
+	}
+	return body
+}
+
 func (c callOptions) getRequestBody() (io.Reader, string, error) {
 	switch {
 	case c.bodyReader != nil:
@@ -147,15 +204,36 @@ func (c callOptions) getRequestBody() (io.Reader, string, error) {
 }
 
 func (c callOptions) addURLValues() string {
-	if c.urlValues != nil {
-		return "?" + c.urlValues.Encode()
+	if c.urlValues == nil && c.query == nil {
+		return ""
+	}
+	values := make(url.Values, len(c.urlValues)+len(c.query))
+	for k, v := range c.urlValues {
+		values[k] = append(values[k], v...)
+	}
+	for k, v := range c.query {
+		values[k] = append(values[k], v...)
+	}
+	return "?" + values.Encode()
+}
+
+// segmentName returns the logmanager segment name for the endpoint. When path parameters
+// are used, the unexpanded template is returned to keep the name cardinality low.
+// An explicit segment name, such as a GraphQL operation name, takes precedence.
+func (c callOptions) segmentName(endpoint string) string {
+	if c.segment != "" {
+		return c.segment
+	}
+	if c.pathParams != nil {
+		return endpoint
 	}
 	return ""
 }
 
 func (c callOptions) setRequestHeaders(req *http.Request, contentType string) error {
 	if c.headers != nil {
-		req.Header = c.headers
+		// Each request gets its own copy, hedged attempts set their headers concurrently
+		req.Header = c.headers.Clone()
 	}
 	if contentType != "" {
 		req.Header.Set("Content-Type", contentType)
@@ -169,13 +247,26 @@ func (c callOptions) setRequestHeaders(req *http.Request, contentType string) er
 }
 
 func (c callOptions) getRequest(ctx context.Context, endpoint string) (*http.Request, error) {
+	if c.paramsErr != nil {
+		return nil, c.paramsErr
+	}
+
 	body, contentType, err := c.getRequestBody()
 	if err != nil {
 		return nil, err
 	}
 
+	if c.pathParams != nil {
+		if endpoint, err = expandPath(endpoint, c.pathParams); err != nil {
+			return nil, err
+		}
+	}
 	endpoint += c.addURLValues()
-	req, err := http.NewRequestWithContext(ctx, c.method, c.host+endpoint, body)
+	host := c.host
+	if host == "" && c.unixSocket != "" && strings.HasPrefix(endpoint, "/") {
+		host = unixSocketHost
+	}
+	req, err := http.NewRequestWithContext(ctx, c.method, host+endpoint, body)
 	if err != nil {
 		return nil, err
 	}
@@ -2,12 +2,11 @@ package clientmanager
 
 import (
 	"context"
-	"io"
 	"net/http"
 )
 
 func callStream(ctx context.Context, endpoint string, cOptions callOptions) (*StreamResponse, error) {
-	res, txn, err := execute(ctx, endpoint, cOptions)
+	res, txn, err := dispatch(ctx, endpoint, cOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -21,7 +20,7 @@ func callStream(ctx context.Context, endpoint string, cOptions callOptions) (*St
 }
 
 func callBytes(ctx context.Context, endpoint string, cOptions callOptions) (*BaseResponse[[]byte], error) {
-	res, txn, err := execute(ctx, endpoint, cOptions)
+	res, txn, err := dispatch(ctx, endpoint, cOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -30,11 +29,7 @@ func callBytes(ctx context.Context, endpoint string, cOptions callOptions) (*Bas
 		_ = res.Body.Close()
 	}()
 
-	var reader io.Reader = res.Body
-	if cOptions.maxResponseBytes > 0 {
-		reader = io.LimitReader(res.Body, cOptions.maxResponseBytes)
-	}
-	raw, _ := io.ReadAll(reader)
+	raw := readBody(res, cOptions.maxResponseBytes)
 
 	return &BaseResponse[[]byte]{
 		StatusCode: res.StatusCode,
@@ -0,0 +1,190 @@
+package clientmanager
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+)
+
+const (
+	defaultCertificateCheckInterval = time.Minute
+	defaultCertificateExpiryWarning = 7 * 24 * time.Hour
+	certificateExpiryWarningEvery   = time.Hour
+)
+
+// CertificateFiles describes a client certificate pair and an optional CA bundle on disk,
+// such as mounted secret files that are rotated in place.
+type CertificateFiles struct {
+	CertFile      string        // required. PEM certificate chain
+	KeyFile       string        // required. PEM private key
+	CAFile        string        // optional. PEM CA bundle used to verify the server instead of the system roots. Read once
+	CheckInterval time.Duration // optional. How often the files are checked for changes. Default is 1 minute
+	ExpiryWarning time.Duration // optional. How long before expiry a warning is logged. Default is 7 days
+}
+
+// Reloader loads the files and returns a CertificateReloader serving them.
+func (p CertificateFiles) Reloader() (*CertificateReloader, error) {
+	if p.CertFile == "" || p.KeyFile == "" {
+		return nil, errors.New("certificate and key files are required")
+	}
+	if p.CheckInterval <= 0 {
+		p.CheckInterval = defaultCertificateCheckInterval
+	}
+	if p.ExpiryWarning <= 0 {
+		p.ExpiryWarning = defaultCertificateExpiryWarning
+	}
+
+	r := &CertificateReloader{files: p}
+	if p.CAFile != "" {
+		pem, err := os.ReadFile(filepath.Clean(p.CAFile)) // #nosec G304 - file paths from user configuration
+		if err != nil {
+			return nil, err
+		}
+		r.roots = x509.NewCertPool()
+		if !r.roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", p.CAFile)
+		}
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// CertificateReloader serves the newest certificate pair from CertificateFiles.
+// The certificate and key files are re-read during a TLS handshake when they changed
+// since the last check, so rotated certificates are picked up without a restart.
+type CertificateReloader struct {
+	files CertificateFiles
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	leaf        *x509.Certificate
+	roots       *x509.CertPool
+	fileStates  []fileState
+	lastCheck   time.Time
+	lastWarning time.Time
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func (r *CertificateReloader) statFiles() ([]fileState, error) {
+	states := make([]fileState, 0, 2)
+	for _, path := range []string{r.files.CertFile, r.files.KeyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, fileState{modTime: info.ModTime(), size: info.Size()})
+	}
+	return states, nil
+}
+
+// Reload reads the certificate pair immediately. The current pair is kept when reading fails.
+func (r *CertificateReloader) Reload() error {
+	states, err := r.statFiles()
+	if err != nil {
+		return err
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.files.CertFile, r.files.KeyFile)
+	if err != nil {
+		return err
+	}
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		return err
+	}
+	certificate.Leaf = leaf
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.certificate = &certificate
+	r.leaf = leaf
+	r.fileStates = states
+	r.lastCheck = time.Now()
+
+	return nil
+}
+
+// NotAfter returns the expiry of the certificate currently served, for health checks.
+func (r *CertificateReloader) NotAfter() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.leaf.NotAfter
+}
+
+// GetClientCertificate implements tls.Config.GetClientCertificate.
+func (r *CertificateReloader) GetClientCertificate(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.refresh(info.Context())
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.certificate, nil
+}
+
+// refresh reloads the files when they changed since the last check and logs expiry warnings.
+func (r *CertificateReloader) refresh(ctx context.Context) {
+	r.mu.Lock()
+	if time.Since(r.lastCheck) < r.files.CheckInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCheck = time.Now()
+	previous := r.fileStates
+	r.mu.Unlock()
+
+	states, err := r.statFiles()
+	if err == nil && !sameFileStates(previous, states) {
+		err = r.Reload()
+	}
+	if err != nil {
+		logmanager.ErrorWithContext(ctx, fmt.Errorf("reload client certificate %s: %w", r.files.CertFile, err))
+	}
+
+	r.warnExpiry(ctx)
+}
+
+func (r *CertificateReloader) warnExpiry(ctx context.Context) {
+	r.mu.Lock()
+	leaf := r.leaf
+	remaining := time.Until(leaf.NotAfter)
+	if remaining > r.files.ExpiryWarning || time.Since(r.lastWarning) < certificateExpiryWarningEvery {
+		r.mu.Unlock()
+		return
+	}
+	r.lastWarning = time.Now()
+	r.mu.Unlock()
+
+	logmanager.InfoWithContext(ctx, "client certificate expires soon", map[string]string{
+		"cert_file": r.files.CertFile,
+		"subject":   leaf.Subject.String(),
+		"not_after": leaf.NotAfter.Format(time.RFC3339),
+		"remaining": remaining.Round(time.Minute).String(),
+	})
+}
+
+func sameFileStates(a, b []fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package clientmanager_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/clientmanager"
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key signed by the CA.
+func (ca testCA) issue(t *testing.T, commonName string, notAfter time.Time, server bool) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestWithCertificateFiles(t *testing.T) {
+	app := logmanager.NewApplication()
+	txn := app.Start("test", "cli", logmanager.TxnTypeOther)
+	ctx := txn.ToContext(context.Background())
+	defer txn.End()
+
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "server", time.Now().Add(time.Hour), true)
+	serverPair, err := tls.X509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverPair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	dir := t.TempDir()
+	certPath, keyPath, caPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	firstExpiry := time.Now().Add(3 * 24 * time.Hour).Truncate(time.Second)
+	cert, key := ca.issue(t, "client-v1", firstExpiry, false)
+	modTime := time.Now().Add(-time.Minute)
+	writeFile(t, certPath, cert, modTime)
+	writeFile(t, keyPath, key, modTime)
+	writeFile(t, caPath, ca.pem, modTime)
+
+	reloader, err := clientmanager.CertificateFiles{
+		CertFile:      certPath,
+		KeyFile:       keyPath,
+		CAFile:        caPath,
+		CheckInterval: time.Millisecond,
+	}.Reloader()
+	require.NoError(t, err)
+	assert.True(t, firstExpiry.Equal(reloader.NotAfter()))
+
+	newClient := func() clientmanager.ClientManager[string] {
+		return clientmanager.New[string](
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithCertificateReloader(reloader),
+			clientmanager.WithDisabledHTTP2(),
+		)
+	}
+
+	res, err := newClient().Call(ctx, "/")
+	require.NoError(t, err)
+	assert.Equal(t, "client-v1", res.Body)
+
+	t.Run("rotated certificate is served", func(t *testing.T) {
+		secondExpiry := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+		cert, key := ca.issue(t, "client-v2", secondExpiry, false)
+		writeFile(t, certPath, cert, time.Now())
+		writeFile(t, keyPath, key, time.Now())
+		time.Sleep(5 * time.Millisecond)
+
+		res, err := newClient().Call(ctx, "/")
+		require.NoError(t, err)
+		assert.Equal(t, "client-v2", res.Body)
+		assert.True(t, secondExpiry.Equal(reloader.NotAfter()))
+	})
+
+	t.Run("broken files keep the current certificate", func(t *testing.T) {
+		writeFile(t, certPath, []byte("not a certificate"), time.Now().Add(time.Minute))
+		time.Sleep(5 * time.Millisecond)
+
+		res, err := newClient().Call(ctx, "/")
+		require.NoError(t, err)
+		assert.Equal(t, "client-v2", res.Body)
+	})
+
+	t.Run("missing files", func(t *testing.T) {
+		_, err := clientmanager.WithCertificateFiles(filepath.Join(dir, "missing.crt"), keyPath, "")
+		assert.Error(t, err)
+
+		_, err = clientmanager.CertificateFiles{CertFile: certPath}.Reloader()
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid CA bundle", func(t *testing.T) {
+		badCA := filepath.Join(dir, "bad-ca.crt")
+		writeFile(t, badCA, []byte("garbage"), time.Now())
+		cert, key := ca.issue(t, "client-v3", time.Now().Add(time.Hour), false)
+		writeFile(t, certPath, cert, time.Now())
+		writeFile(t, keyPath, key, time.Now())
+
+		_, err := clientmanager.WithCertificateFiles(certPath, keyPath, badCA)
+		assert.ErrorContains(t, err, "no certificates found")
+
+		option, err := clientmanager.WithCertificateFiles(certPath, keyPath, caPath)
+		require.NoError(t, err)
+		res, err := clientmanager.New[string](clientmanager.WithHost(ts.URL), option).Call(ctx, "/")
+		require.NoError(t, err)
+		assert.Equal(t, "client-v3", res.Body)
+	})
+}
@@ -0,0 +1,800 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const clientmanagerImport = "github.com/SALT-Indonesia/salt-pkg/clientmanager"
+
+// generator turns an OpenAPI document into the source of a Go package.
+type generator struct {
+	doc     *document
+	pkg     string
+	types   map[string]string // declared type name -> declaration
+	imports map[string]bool
+	out     bytes.Buffer
+}
+
+// generate returns the formatted Go source of the client package for doc.
+func generate(doc *document, pkg string) ([]byte, error) {
+	g := &generator{
+		doc:     doc,
+		pkg:     pkg,
+		types:   map[string]string{},
+		imports: map[string]bool{clientmanagerImport: true, "context": true, "net/http": true},
+	}
+
+	// Component names are reserved first, so inline types never take them.
+	for _, name := range sortedKeys(doc.Components.Schemas) {
+		g.types[goName(name)] = ""
+	}
+	for _, name := range sortedKeys(doc.Components.Schemas) {
+		if err := g.componentType(name, doc.Components.Schemas[name]); err != nil {
+			return nil, fmt.Errorf("schema %q: %w", name, err)
+		}
+	}
+
+	var body bytes.Buffer
+	g.client(&body)
+	if err := g.operations(&body); err != nil {
+		return nil, err
+	}
+
+	g.header()
+	for _, name := range sortedKeys(g.types) {
+		g.out.WriteString(g.types[name])
+	}
+	g.out.Write(body.Bytes())
+
+	source, err := format.Source(g.out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w\n%s", err, g.out.Bytes())
+	}
+	return source, nil
+}
+
+func (g *generator) header() {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by clientgen. DO NOT EDIT.\n\n")
+	if title := g.doc.Info.Title; title != "" {
+		fmt.Fprintf(&b, "// Package %s is the client of %s", g.pkg, title)
+		if g.doc.Info.Version != "" {
+			fmt.Fprintf(&b, " %s", g.doc.Info.Version)
+		}
+		b.WriteString(".\n")
+	}
+	fmt.Fprintf(&b, "package %s\n\nimport (\n", g.pkg)
+	for _, path := range sortedKeys(g.imports) {
+		if path != clientmanagerImport {
+			fmt.Fprintf(&b, "\t%q\n", path)
+		}
+	}
+	fmt.Fprintf(&b, "\n\t%q\n)\n\n", clientmanagerImport)
+	g.out.Write(b.Bytes())
+}
+
+// comment writes text as a Go comment, one line per line of text.
+func comment(b *bytes.Buffer, indent, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			fmt.Fprintf(b, "%s//\n", indent)
+			continue
+		}
+		fmt.Fprintf(b, "%s// %s\n", indent, line)
+	}
+}
+
+// declare registers a type declaration under a free name derived from name.
+func (g *generator) declare(name string, decl func(name string) (string, error)) (string, error) {
+	unique := name
+	for i := 2; ; i++ {
+		if _, taken := g.types[unique]; !taken {
+			break
+		}
+		unique = name + strconv.Itoa(i)
+	}
+	g.types[unique] = ""
+	code, err := decl(unique)
+	if err != nil {
+		return "", err
+	}
+	g.types[unique] = code
+	return unique, nil
+}
+
+func (g *generator) schemaRef(ref string) (string, *schema, error) {
+	name, err := refName(ref, "schemas")
+	if err != nil {
+		return "", nil, err
+	}
+	resolved, ok := g.doc.Components.Schemas[name]
+	if !ok {
+		return "", nil, fmt.Errorf("schema %q not found", ref)
+	}
+	return goName(name), resolved, nil
+}
+
+func isStringEnum(s *schema) bool {
+	return len(s.Enum) > 0 && (s.Type == "" || s.Type == "string")
+}
+
+func isStruct(s *schema) bool {
+	return len(s.Properties) > 0 || len(s.AllOf) > 1 || (len(s.AllOf) == 1 && s.AllOf[0].Ref == "")
+}
+
+func (g *generator) componentType(name string, s *schema) error {
+	typeName := goName(name)
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// %s is the %q schema.\n", typeName, name)
+	if s.Description != "" {
+		b.WriteString("//\n")
+		comment(&b, "", s.Description)
+	}
+
+	switch {
+	case s.Ref != "":
+		target, _, err := g.schemaRef(s.Ref)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "type %s = %s\n\n", typeName, target)
+	case isStringEnum(s):
+		g.enum(&b, typeName, s)
+	case isStruct(s):
+		if err := g.structType(&b, typeName, s); err != nil {
+			return err
+		}
+	default:
+		expr, err := g.goType(s, typeName)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "type %s %s\n\n", typeName, expr)
+	}
+
+	g.types[typeName] = b.String()
+	return nil
+}
+
+func (g *generator) enum(b *bytes.Buffer, typeName string, s *schema) {
+	fmt.Fprintf(b, "type %s string\n\nconst (\n", typeName)
+	for _, value := range s.Enum {
+		text := fmt.Sprint(value)
+		fmt.Fprintf(b, "\t%s%s %s = %q\n", typeName, goName(text), typeName, text)
+	}
+	b.WriteString(")\n\n")
+}
+
+// structType writes a struct declaration. allOf references are embedded, inline allOf
+// members are merged into the struct.
+func (g *generator) structType(b *bytes.Buffer, typeName string, s *schema) error {
+	fmt.Fprintf(b, "type %s struct {\n", typeName)
+
+	properties := map[string]*schema{}
+	required := map[string]bool{}
+	parts := append([]*schema{s}, s.AllOf...)
+	for _, part := range parts {
+		if part.Ref != "" {
+			embedded, _, err := g.schemaRef(part.Ref)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(b, "\t%s\n", embedded)
+			continue
+		}
+		for name, property := range part.Properties {
+			properties[name] = property
+		}
+		for _, name := range part.Required {
+			required[name] = true
+		}
+	}
+
+	for _, name := range sortedKeys(properties) {
+		property := properties[name]
+		fieldType, err := g.fieldType(property, typeName+goName(name), required[name])
+		if err != nil {
+			return fmt.Errorf("property %q: %w", name, err)
+		}
+		if property.Description != "" {
+			comment(b, "\t", property.Description)
+		}
+		tag := name
+		if !required[name] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(b, "\t%s %s `json:%q", goName(name), fieldType, tag)
+		if rules := g.validateRules(property, required[name]); rules != "" {
+			fmt.Fprintf(b, " validate:%q", rules)
+		}
+		b.WriteString("`\n")
+	}
+	b.WriteString("}\n\n")
+	return nil
+}
+
+// fieldType returns the type of a struct field or parameter. Optional and nullable
+// scalars and structs become pointers so their absence can be told from the zero value.
+func (g *generator) fieldType(s *schema, hint string, required bool) (string, error) {
+	expr, err := g.goType(s, hint)
+	if err != nil {
+		return "", err
+	}
+	if (!required || (s != nil && s.Nullable)) && !strings.HasPrefix(expr, "[]") && !strings.HasPrefix(expr, "map[") &&
+		expr != "any" && expr != "json.RawMessage" {
+		return "*" + expr, nil
+	}
+	return expr, nil
+}
+
+// goType returns the Go type expression of s. Inline objects and enums are declared as
+// named types derived from hint.
+func (g *generator) goType(s *schema, hint string) (string, error) {
+	if s == nil {
+		return "any", nil
+	}
+	if s.Ref != "" {
+		name, _, err := g.schemaRef(s.Ref)
+		return name, err
+	}
+	if len(s.AllOf) == 1 && s.AllOf[0].Ref != "" && len(s.Properties) == 0 {
+		return g.goType(s.AllOf[0], hint)
+	}
+	if len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		g.imports["encoding/json"] = true
+		return "json.RawMessage", nil
+	}
+	if isStringEnum(s) {
+		return g.declare(hint, func(name string) (string, error) {
+			var b bytes.Buffer
+			fmt.Fprintf(&b, "// %s is an enum value.\n", name)
+			g.enum(&b, name, s)
+			return b.String(), nil
+		})
+	}
+	if isStruct(s) {
+		return g.declare(hint, func(name string) (string, error) {
+			var b bytes.Buffer
+			fmt.Fprintf(&b, "// %s is an inline object.\n", name)
+			err := g.structType(&b, name, s)
+			return b.String(), err
+		})
+	}
+
+	switch s.Type {
+	case "object", "":
+		additional, err := s.additional()
+		if err != nil {
+			return "", err
+		}
+		if additional != nil {
+			value, err := g.goType(additional, hint+"Value")
+			if err != nil {
+				return "", err
+			}
+			return "map[string]" + value, nil
+		}
+		if s.Type == "object" {
+			return "map[string]any", nil
+		}
+		return "any", nil
+	case "array":
+		item, err := g.goType(s.Items, hint+"Item")
+		if err != nil {
+			return "", err
+		}
+		return "[]" + item, nil
+	case "string":
+		switch s.Format {
+		case "date-time", "date":
+			g.imports["time"] = true
+			return "time.Time", nil
+		case "byte":
+			return "[]byte", nil
+		}
+		return "string", nil
+	case "integer":
+		switch s.Format {
+		case "int32":
+			return "int32", nil
+		case "int64":
+			return "int64", nil
+		}
+		return "int", nil
+	case "number":
+		if s.Format == "float" {
+			return "float32", nil
+		}
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	}
+	return "", fmt.Errorf("unsupported schema type %q", s.Type)
+}
+
+// resolve follows a schema reference.
+func (g *generator) resolve(s *schema) *schema {
+	for s != nil && s.Ref != "" {
+		_, resolved, err := g.schemaRef(s.Ref)
+		if err != nil {
+			return s
+		}
+		s = resolved
+	}
+	return s
+}
+
+// validateRules returns the go-playground/validator rules of a field.
+func (g *generator) validateRules(s *schema, required bool) string {
+	target := g.resolve(s)
+	var rules []string
+
+	switch target.Type {
+	case "string":
+		if target.MinLength != nil {
+			rules = append(rules, "min="+strconv.Itoa(*target.MinLength))
+		}
+		if target.MaxLength != nil {
+			rules = append(rules, "max="+strconv.Itoa(*target.MaxLength))
+		}
+		switch target.Format {
+		case "email":
+			rules = append(rules, "email")
+		case "uuid":
+			rules = append(rules, "uuid")
+		case "uri", "url":
+			rules = append(rules, "url")
+		}
+		if oneOf := enumRule(target.Enum); oneOf != "" {
+			rules = append(rules, oneOf)
+		}
+	case "integer", "number":
+		if target.Minimum != nil {
+			rules = append(rules, "gte="+strconv.FormatFloat(*target.Minimum, 'f', -1, 64))
+		}
+		if target.Maximum != nil {
+			rules = append(rules, "lte="+strconv.FormatFloat(*target.Maximum, 'f', -1, 64))
+		}
+		if oneOf := enumRule(target.Enum); oneOf != "" {
+			rules = append(rules, oneOf)
+		}
+	case "array":
+		if target.MinItems != nil {
+			rules = append(rules, "min="+strconv.Itoa(*target.MinItems))
+		}
+		if target.MaxItems != nil {
+			rules = append(rules, "max="+strconv.Itoa(*target.MaxItems))
+		}
+		if item := g.resolve(target.Items); item != nil && isStruct(item) {
+			rules = append(rules, "dive")
+		}
+	}
+
+	// The zero value of numbers and booleans is a valid value, so they are never "required".
+	requiredRule := required && target.Type != "boolean" && target.Type != "integer" && target.Type != "number"
+	switch {
+	case requiredRule:
+		rules = append([]string{"required"}, rules...)
+	case len(rules) > 0:
+		rules = append([]string{"omitempty"}, rules...)
+	}
+	return strings.Join(rules, ",")
+}
+
+func enumRule(values []any) string {
+	if len(values) == 0 {
+		return ""
+	}
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = fmt.Sprint(value)
+		if strings.ContainsAny(texts[i], " ,|") {
+			return ""
+		}
+	}
+	return "oneof=" + strings.Join(texts, " ")
+}
+
+// client writes the Client type, its constructor and the security scheme wiring.
+func (g *generator) client(b *bytes.Buffer) {
+	schemes := g.doc.Components.SecuritySchemes
+
+	b.WriteString("// Client calls the operations of the API. Options given to NewClient apply to every call,\n")
+	b.WriteString("// options given to an operation apply to that call only.\n")
+	b.WriteString("type Client struct {\n\thost string\n")
+	if len(schemes) > 0 {
+		b.WriteString("\tsecurity Security\n")
+	}
+	b.WriteString("\toptions []clientmanager.Option\n}\n\n")
+
+	if len(schemes) == 0 {
+		b.WriteString("// NewClient returns a client sending requests to host.\n")
+		b.WriteString("func NewClient(host string, options ...clientmanager.Option) *Client {\n")
+		b.WriteString("\treturn &Client{host: host, options: options}\n}\n\n")
+	} else {
+		g.security(b, schemes)
+	}
+
+	b.WriteString("func (c *Client) call(ctx context.Context, endpoint string, options []clientmanager.Option) (*clientmanager.BaseResponse[[]byte], error) {\n")
+	b.WriteString("\topts := append([]clientmanager.Option{clientmanager.WithHost(c.host)}, c.options...)\n")
+	b.WriteString("\treturn clientmanager.CallBytes(ctx, endpoint, append(opts, options...)...)\n}\n\n")
+}
+
+func (g *generator) security(b *bytes.Buffer, schemes map[string]*securityScheme) {
+	b.WriteString("// Security holds the credentials of the security schemes. Schemes left empty are not sent.\n")
+	b.WriteString("type Security struct {\n")
+	for _, name := range sortedKeys(schemes) {
+		scheme := schemes[name]
+		field := goName(name)
+		switch {
+		case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "basic"):
+			fmt.Fprintf(b, "\t// %sUsername and %sPassword are the HTTP basic credentials of %q.\n", field, field, name)
+			fmt.Fprintf(b, "\t%sUsername string\n\t%sPassword string\n", field, field)
+		case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer"),
+			scheme.Type == "oauth2", scheme.Type == "openIdConnect":
+			fmt.Fprintf(b, "\t// %s is the bearer token of %q.\n\t%s string\n", field, name, field)
+		case scheme.Type == "apiKey":
+			fmt.Fprintf(b, "\t// %s is sent as the %q %s of %q.\n\t%s string\n", field, scheme.Name, scheme.In, name, field)
+		}
+	}
+	b.WriteString("}\n\n")
+
+	b.WriteString("// NewClient returns a client sending requests to host with the given credentials.\n")
+	b.WriteString("func NewClient(host string, security Security, options ...clientmanager.Option) *Client {\n")
+	b.WriteString("\treturn &Client{host: host, security: security, options: options}\n}\n\n")
+
+	b.WriteString(`// auth returns the authentication of the first security requirement whose credentials are all set.
+func (c *Client) auth(requirements [][]string) clientmanager.Auth {
+	for _, requirement := range requirements {
+		if len(requirement) == 0 {
+			return nil
+		}
+		auths := make([]clientmanager.Auth, 0, len(requirement))
+		for _, scheme := range requirement {
+			if auth := c.schemeAuth(scheme); auth != nil {
+				auths = append(auths, auth)
+			}
+		}
+		if len(auths) == len(requirement) {
+			return func(r *http.Request) error {
+				for _, auth := range auths {
+					if err := auth(r); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+`)
+	b.WriteString("func (c *Client) schemeAuth(scheme string) clientmanager.Auth {\n\tswitch scheme {\n")
+	for _, name := range sortedKeys(schemes) {
+		scheme := schemes[name]
+		field := "c.security." + goName(name)
+		switch {
+		case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "basic"):
+			fmt.Fprintf(b, "\tcase %q:\n\t\tif %sUsername != \"\" {\n\t\t\treturn clientmanager.AuthBasic(%sUsername, %sPassword)\n\t\t}\n",
+				name, field, field, field)
+		case scheme.Type == "http" && strings.EqualFold(scheme.Scheme, "bearer"),
+			scheme.Type == "oauth2", scheme.Type == "openIdConnect":
+			fmt.Fprintf(b, "\tcase %q:\n\t\tif %s != \"\" {\n\t\t\treturn clientmanager.AuthBearer(%s)\n\t\t}\n", name, field, field)
+		case scheme.Type == "apiKey" && scheme.In == "cookie":
+			fmt.Fprintf(b, "\tcase %q:\n\t\tif %s != \"\" {\n\t\t\treturn func(r *http.Request) error {\n", name, field)
+			fmt.Fprintf(b, "\t\t\t\tr.AddCookie(&http.Cookie{Name: %q, Value: %s})\n\t\t\t\treturn nil\n\t\t\t}\n\t\t}\n", scheme.Name, field)
+		case scheme.Type == "apiKey":
+			fmt.Fprintf(b, "\tcase %q:\n\t\tif %s != \"\" {\n\t\t\treturn clientmanager.AuthAPIKey(%q, %s, %t)\n\t\t}\n",
+				name, field, scheme.Name, field, scheme.In == "query")
+		}
+	}
+	b.WriteString("\t}\n\treturn nil\n}\n\n")
+}
+
+// param is a resolved operation parameter.
+type param struct {
+	*parameter
+	field  string
+	goType string
+}
+
+func (g *generator) operations(b *bytes.Buffer) error {
+	seen := map[string]string{}
+	for _, path := range sortedKeys(g.doc.Paths) {
+		item := g.doc.Paths[path]
+		for _, entry := range item.operations() {
+			name := operationName(entry.method, path, entry.op.OperationID)
+			if previous, ok := seen[name]; ok {
+				return fmt.Errorf("operation %s %s: method name %s already used by %s", entry.method, path, name, previous)
+			}
+			seen[name] = entry.method + " " + path
+			if err := g.operation(b, name, entry.method, path, item, entry.op); err != nil {
+				return fmt.Errorf("operation %s %s: %w", entry.method, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parameters merges the path item and operation parameters; the operation wins on conflicts.
+func (g *generator) parameters(name string, item *pathItem, op *operation) ([]param, error) {
+	var params []param
+	index := map[string]int{}
+	for _, p := range append(append([]*parameter{}, item.Parameters...), op.Parameters...) {
+		resolved, err := g.doc.parameter(p)
+		if err != nil {
+			return nil, err
+		}
+		if resolved.In == "cookie" {
+			continue
+		}
+		required := resolved.Required || resolved.In == "path"
+		goType, err := g.fieldType(resolved.Schema, name+"Params"+goName(resolved.Name), required)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %w", resolved.Name, err)
+		}
+		entry := param{parameter: resolved, field: goName(resolved.Name), goType: goType}
+		key := resolved.In + ":" + resolved.Name
+		if i, ok := index[key]; ok {
+			params[i] = entry
+			continue
+		}
+		index[key] = len(params)
+		params = append(params, entry)
+	}
+	return params, nil
+}
+
+func (g *generator) paramsType(name string, params []param) (string, error) {
+	return g.declare(name+"Params", func(typeName string) (string, error) {
+		return g.paramsDecl(name, typeName, params), nil
+	})
+}
+
+func (g *generator) paramsDecl(name, typeName string, params []param) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// %s holds the parameters of %s.\n", typeName, name)
+	fmt.Fprintf(&b, "type %s struct {\n", typeName)
+	for _, p := range params {
+		if p.Description != "" {
+			comment(&b, "\t", p.Description)
+		}
+		var tag string
+		switch p.In {
+		case "path":
+			tag = fmt.Sprintf("path:%q", p.Name)
+		case "query":
+			options := ",omitempty"
+			if p.Explode != nil && !*p.Explode && strings.HasPrefix(p.goType, "[]") {
+				options += ",comma"
+			}
+			tag = fmt.Sprintf("query:%q", p.Name+options)
+			if s := g.resolve(p.Schema); s != nil && s.Format == "date" {
+				tag += ` layout:"2006-01-02"`
+			}
+		case "header":
+			tag = fmt.Sprintf("header:%q", p.Name)
+		}
+		fmt.Fprintf(&b, "\t%s %s `%s`\n", p.field, p.goType, tag)
+	}
+	b.WriteString("}\n\n")
+	return b.String()
+}
+
+// headerValue returns the expression converting a header parameter to a string.
+func (g *generator) headerValue(expr, goType string) string {
+	switch goType {
+	case "string":
+		return expr
+	case "[]string":
+		g.imports["strings"] = true
+		return "strings.Join(" + expr + `, ",")`
+	}
+	g.imports["fmt"] = true
+	return "fmt.Sprint(" + expr + ")"
+}
+
+// statusCase returns the switch condition matching a response code such as "404" or "4XX".
+func statusCase(code string) (string, error) {
+	if len(code) == 3 && strings.EqualFold(code[1:], "XX") && code[0] >= '1' && code[0] <= '5' {
+		class := int(code[0] - '0')
+		return fmt.Sprintf("res.StatusCode >= %d && res.StatusCode < %d", class*100, (class+1)*100), nil
+	}
+	status, err := strconv.Atoi(code)
+	if err != nil {
+		return "", fmt.Errorf("invalid response code %q", code)
+	}
+	return fmt.Sprintf("res.StatusCode == %d", status), nil
+}
+
+func isSuccessCode(code string) bool {
+	return strings.HasPrefix(code, "2")
+}
+
+func (g *generator) operation(b *bytes.Buffer, name, method, path string, item *pathItem, op *operation) error {
+	params, err := g.parameters(name, item, op)
+	if err != nil {
+		return err
+	}
+	var paramsType string
+	if len(params) > 0 {
+		if paramsType, err = g.paramsType(name, params); err != nil {
+			return err
+		}
+	}
+
+	var bodyType, bodyContentType string
+	if op.RequestBody != nil {
+		requestBody, err := g.doc.requestBody(op.RequestBody)
+		if err != nil {
+			return err
+		}
+		if s := jsonSchema(requestBody.Content); s != nil {
+			if bodyType, err = g.goType(s, name+"Request"); err != nil {
+				return fmt.Errorf("request body: %w", err)
+			}
+		} else if contentTypes := sortedKeys(requestBody.Content); len(contentTypes) > 0 {
+			g.imports["io"] = true
+			bodyType, bodyContentType = "io.Reader", contentTypes[0]
+		}
+	}
+
+	// The first 2xx response with a JSON schema sets the result type of every 2xx response.
+	resultType := "[]byte"
+	var errorCases []string
+	errorDefault := "string"
+	for _, code := range sortedKeys(op.Responses) {
+		res, err := g.doc.response(op.Responses[code])
+		if err != nil {
+			return err
+		}
+		s := jsonSchema(res.Content)
+		switch {
+		case isSuccessCode(code):
+			if s != nil && resultType == "[]byte" {
+				if resultType, err = g.goType(s, name+"Response"); err != nil {
+					return fmt.Errorf("response %s: %w", code, err)
+				}
+			}
+		case code == "default":
+			if s != nil {
+				if errorDefault, err = g.goType(s, name+"Error"); err != nil {
+					return fmt.Errorf("response %s: %w", code, err)
+				}
+			}
+		case s != nil:
+			errorType, err := g.goType(s, name+"Error"+code)
+			if err != nil {
+				return fmt.Errorf("response %s: %w", code, err)
+			}
+			condition, err := statusCase(code)
+			if err != nil {
+				return err
+			}
+			errorCases = append(errorCases, fmt.Sprintf("\tcase %s:\n\t\treturn nil, clientmanager.NewResponseError[%s](res)\n", condition, errorType))
+		}
+	}
+	// Exact status codes are matched before ranges.
+	sort.SliceStable(errorCases, func(i, j int) bool {
+		return !strings.Contains(errorCases[i], ">=") && strings.Contains(errorCases[j], ">=")
+	})
+
+	if op.Summary != "" {
+		comment(b, "", name+" "+lowerFirst(op.Summary))
+		if op.Description != "" {
+			b.WriteString("//\n")
+			comment(b, "", op.Description)
+		}
+		fmt.Fprintf(b, "//\n//\t%s %s\n", method, path)
+	} else {
+		fmt.Fprintf(b, "// %s sends %s %s.\n", name, method, path)
+		if op.Description != "" {
+			b.WriteString("//\n")
+			comment(b, "", op.Description)
+		}
+	}
+	if len(errorCases) > 0 || errorDefault != "string" {
+		b.WriteString("//\n// Error responses are returned as *clientmanager.ResponseError with the typed error body.\n")
+	}
+	if op.Deprecated {
+		b.WriteString("//\n// Deprecated: the operation is deprecated by the API.\n")
+	}
+
+	fmt.Fprintf(b, "func (c *Client) %s(ctx context.Context", name)
+	if len(params) > 0 {
+		fmt.Fprintf(b, ", params %s", paramsType)
+	}
+	if bodyType != "" {
+		fmt.Fprintf(b, ", body %s", bodyType)
+	}
+	fmt.Fprintf(b, ", options ...clientmanager.Option) (*clientmanager.BaseResponse[%s], error) {\n", resultType)
+
+	fmt.Fprintf(b, "\topts := []clientmanager.Option{\n\t\tclientmanager.WithMethod(%s),\n", methodConstant(method))
+	hasIn := func(in string) bool {
+		for _, p := range params {
+			if p.In == in {
+				return true
+			}
+		}
+		return false
+	}
+	if hasIn("path") {
+		b.WriteString("\t\tclientmanager.WithPathParams(params),\n")
+	}
+	if hasIn("query") {
+		b.WriteString("\t\tclientmanager.WithQuery(params),\n")
+	}
+	switch {
+	case bodyContentType != "":
+		fmt.Fprintf(b, "\t\tclientmanager.WithBodyReader(body, %q),\n", bodyContentType)
+	case bodyType != "":
+		b.WriteString("\t\tclientmanager.WithRequestBody(body),\n")
+	}
+	b.WriteString("\t}\n")
+
+	if hasIn("header") {
+		b.WriteString("\theader := http.Header{}\n")
+		for _, p := range params {
+			if p.In != "header" {
+				continue
+			}
+			if strings.HasPrefix(p.goType, "*") {
+				fmt.Fprintf(b, "\tif params.%s != nil {\n\t\theader.Set(%q, %s)\n\t}\n",
+					p.field, p.Name, g.headerValue("*params."+p.field, strings.TrimPrefix(p.goType, "*")))
+				continue
+			}
+			fmt.Fprintf(b, "\theader.Set(%q, %s)\n", p.Name, g.headerValue("params."+p.field, p.goType))
+		}
+		b.WriteString("\topts = append(opts, clientmanager.WithHeaders(header))\n")
+	}
+
+	requirements := g.doc.Security
+	if op.Security != nil {
+		requirements = *op.Security
+	}
+	if len(requirements) > 0 && len(g.doc.Components.SecuritySchemes) > 0 {
+		var alternatives []string
+		for _, requirement := range requirements {
+			quoted := make([]string, 0, len(requirement))
+			for _, scheme := range sortedKeys(requirement) {
+				quoted = append(quoted, strconv.Quote(scheme))
+			}
+			alternatives = append(alternatives, "{"+strings.Join(quoted, ", ")+"}")
+		}
+		fmt.Fprintf(b, "\tif auth := c.auth([][]string{%s}); auth != nil {\n", strings.Join(alternatives, ", "))
+		b.WriteString("\t\topts = append(opts, clientmanager.WithAuth(auth))\n\t}\n")
+	}
+
+	fmt.Fprintf(b, "\n\tres, err := c.call(ctx, %q, append(opts, options...))\n", path)
+	b.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n\n")
+	success := fmt.Sprintf("\t\treturn clientmanager.DecodeResponse[%s](res)\n", resultType)
+	if resultType == "[]byte" {
+		success = "\t\treturn res, nil\n"
+	}
+	if len(errorCases) == 0 {
+		b.WriteString("\tif res.IsSuccess() {\n" + success)
+	} else {
+		b.WriteString("\tswitch {\n" + strings.Join(errorCases, "") + "\tcase res.IsSuccess():\n" + success)
+	}
+	fmt.Fprintf(b, "\t}\n\treturn nil, clientmanager.NewResponseError[%s](res)\n}\n\n", errorDefault)
+	return nil
+}
+
+func methodConstant(method string) string {
+	return "http.Method" + method[:1] + strings.ToLower(method[1:])
+}
+
+func lowerFirst(s string) string {
+	words := strings.SplitN(s, " ", 2)
+	if len(words[0]) > 1 && strings.ToUpper(words[0]) == words[0] {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
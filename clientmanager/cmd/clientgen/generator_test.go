@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/SALT-Indonesia/salt-pkg/clientmanager"
+	"github.com/SALT-Indonesia/salt-pkg/clientmanager/cmd/clientgen/internal/petstore"
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the generated petstore package")
+
+const goldenFile = "internal/petstore/client.gen.go"
+
+func generateFile(t *testing.T, spec, pkg string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(spec)
+	require.NoError(t, err)
+	doc, err := parseDocument(data)
+	require.NoError(t, err)
+	source, err := generate(doc, pkg)
+	require.NoError(t, err)
+	return source
+}
+
+func TestGenerateGolden(t *testing.T) {
+	source := generateFile(t, "testdata/petstore.yaml", "petstore")
+	if *update {
+		require.NoError(t, os.WriteFile(goldenFile, source, 0o600))
+	}
+
+	golden, err := os.ReadFile(goldenFile)
+	require.NoError(t, err)
+	assert.Equal(t, string(golden), string(source), "run go generate ./... to update %s", goldenFile)
+
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, source, generateFile(t, "testdata/petstore.yaml", "petstore"))
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr string
+	}{
+		{
+			name:    "swagger 2",
+			spec:    `{"swagger": "2.0"}`,
+			wantErr: "unsupported OpenAPI version",
+		},
+		{
+			name: "remote reference",
+			spec: `
+openapi: 3.0.0
+components:
+  schemas:
+    Pet:
+      $ref: 'https://example.com/pet.yaml'
+`,
+			wantErr: "unsupported reference",
+		},
+		{
+			name: "duplicate method name",
+			spec: `
+openapi: 3.0.0
+paths:
+  /a:
+    get:
+      operationId: fetch
+      responses: {}
+  /b:
+    get:
+      operationId: Fetch
+      responses: {}
+`,
+			wantErr: "method name Fetch already used by GET /a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseDocument([]byte(tt.spec))
+			if err == nil {
+				_, err = generate(doc, "api")
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"petId":          "PetID",
+		"X-Request-ID":   "XRequestID",
+		"list_pets":      "ListPets",
+		"HTTPServer":     "HTTPServer",
+		"get /pets/{id}": "GetPetsID",
+		"2fa":            "N2fa",
+		"":               "Value",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, goName(in), in)
+	}
+	assert.Equal(t, "petID", goParamName("pet_id"))
+	assert.Equal(t, "typeValue", goParamName("type"))
+}
+
+func TestGeneratedClient(t *testing.T) {
+	app := logmanager.NewApplication()
+	txn := app.Start("test", "cli", logmanager.TxnTypeOther)
+	ctx := txn.ToContext(context.Background())
+	defer txn.End()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "GET /pets":
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			assert.Equal(t, "req-1", r.Header.Get("X-Request-ID"))
+			assert.Equal(t, "available,pending", r.URL.Query().Get("status"))
+			assert.Equal(t, "10", r.URL.Query().Get("limit"))
+			_, _ = w.Write([]byte(`{"items":[{"id":1,"name":"Rex","kind":"dog","status":"available"}]}`))
+		case "POST /pets":
+			var pet petstore.NewPet
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&pet))
+			if pet.Name == "taken" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				_, _ = w.Write([]byte(`{"fields":{"name":["already taken"]}}`))
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":2,"name":"` + pet.Name + `","kind":"cat","status":"pending"}`))
+		case "GET /pets/404":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":"not_found","message":"pet 404 not found"}`))
+		case "DELETE /pets/1":
+			user, pass, ok := r.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "admin:secret", user+":"+pass)
+			w.WriteHeader(http.StatusNoContent)
+		case "PUT /pets/1/photo":
+			assert.Empty(t, r.Header.Get("Authorization"))
+			assert.Equal(t, "image/png", r.Header.Get("Content-Type"))
+			w.WriteHeader(http.StatusTeapot)
+			_, _ = w.Write([]byte(`short and stout`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer ts.Close()
+
+	client := petstore.NewClient(ts.URL, petstore.Security{
+		BearerAuth:        "token",
+		BasicAuthUsername: "admin",
+		BasicAuthPassword: "secret",
+	})
+
+	t.Run("query, header and bearer auth", func(t *testing.T) {
+		limit := int32(10)
+		res, err := client.ListPets(ctx, petstore.ListPetsParams{
+			Status:     []petstore.PetStatus{petstore.PetStatusAvailable, petstore.PetStatusPending},
+			Limit:      &limit,
+			XRequestID: "req-1",
+		})
+
+		require.NoError(t, err)
+		require.Len(t, res.Body.Items, 1)
+		assert.Equal(t, int64(1), res.Body.Items[0].ID)
+		assert.Equal(t, "Rex", res.Body.Items[0].Name)
+	})
+
+	t.Run("request body", func(t *testing.T) {
+		res, err := client.CreatePet(ctx, petstore.NewPet{Name: "Tom", Kind: petstore.NewPetKindCat})
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, petstore.PetStatusPending, res.Body.Status)
+	})
+
+	t.Run("validation runs before sending", func(t *testing.T) {
+		_, err := client.CreatePet(ctx, petstore.NewPet{Name: "Tom", Kind: "bird"})
+
+		assert.ErrorContains(t, err, "oneof")
+	})
+
+	t.Run("typed error per status", func(t *testing.T) {
+		_, err := client.CreatePet(ctx, petstore.NewPet{Name: "taken", Kind: petstore.NewPetKindDog})
+
+		var validationErr *clientmanager.ResponseError[petstore.ValidationError]
+		require.True(t, errors.As(err, &validationErr))
+		assert.Equal(t, []string{"already taken"}, validationErr.Body.Fields["name"])
+
+		_, err = client.GetPetByID(ctx, petstore.GetPetByIDParams{PetID: 404})
+
+		var notFound *clientmanager.ResponseError[petstore.Error]
+		require.True(t, errors.As(err, &notFound))
+		assert.Equal(t, http.StatusNotFound, notFound.StatusCode)
+		assert.Equal(t, "not_found", notFound.Body.Code)
+	})
+
+	t.Run("operation security", func(t *testing.T) {
+		res, err := client.DeletePetsByPetID(ctx, petstore.DeletePetsByPetIDParams{PetID: 1})
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		_, err = client.UploadPhoto(ctx, petstore.UploadPhotoParams{PetID: 1}, strings.NewReader("png"))
+
+		var undeclared *clientmanager.ResponseError[string]
+		require.True(t, errors.As(err, &undeclared))
+		assert.Equal(t, "short and stout", undeclared.Body)
+	})
+}
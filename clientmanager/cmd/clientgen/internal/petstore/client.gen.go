@@ -0,0 +1,299 @@
+// Code generated by clientgen. DO NOT EDIT.
+
+// Package petstore is the client of Petstore API 1.2.0.
+package petstore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/clientmanager"
+)
+
+// DeletePetsByPetIDParams holds the parameters of DeletePetsByPetID.
+type DeletePetsByPetIDParams struct {
+	PetID int64 `path:"petId"`
+}
+
+// Error is the "Error" schema.
+type Error struct {
+	Code    string `json:"code" validate:"required"`
+	Message string `json:"message" validate:"required"`
+}
+
+// GetPetByIDParams holds the parameters of GetPetByID.
+type GetPetByIDParams struct {
+	PetID int64 `path:"petId"`
+}
+
+// ListPetsParams holds the parameters of ListPets.
+type ListPetsParams struct {
+	Status    []PetStatus `query:"status,omitempty,comma"`
+	BornAfter *time.Time  `query:"bornAfter,omitempty" layout:"2006-01-02"`
+	// Maximum number of pets to return.
+	Limit      *int32 `query:"limit,omitempty"`
+	XRequestID string `header:"X-Request-ID"`
+}
+
+// ListPetsResponse is an inline object.
+type ListPetsResponse struct {
+	Items []Pet   `json:"items" validate:"required,dive"`
+	Next  *string `json:"next,omitempty"`
+}
+
+// NewPet is the "NewPet" schema.
+//
+// A pet to add to the store.
+type NewPet struct {
+	Age          *int                     `json:"age,omitempty" validate:"omitempty,gte=0,lte=40"`
+	Attributes   map[string]string        `json:"attributes,omitempty"`
+	Kind         NewPetKind               `json:"kind" validate:"required,oneof=cat dog"`
+	Name         string                   `json:"name" validate:"required,min=1,max=64"`
+	OwnerEmail   *string                  `json:"ownerEmail,omitempty" validate:"omitempty,email"`
+	Tags         []string                 `json:"tags,omitempty" validate:"omitempty,max=5"`
+	Vaccinations []NewPetVaccinationsItem `json:"vaccinations,omitempty" validate:"omitempty,dive"`
+}
+
+// NewPetKind is an enum value.
+type NewPetKind string
+
+const (
+	NewPetKindCat NewPetKind = "cat"
+	NewPetKindDog NewPetKind = "dog"
+)
+
+// NewPetVaccinationsItem is an inline object.
+type NewPetVaccinationsItem struct {
+	Date *time.Time `json:"date,omitempty"`
+	Name string     `json:"name" validate:"required"`
+}
+
+// Pet is the "Pet" schema.
+type Pet struct {
+	NewPet
+	Chipped *bool     `json:"chipped,omitempty"`
+	ID      int64     `json:"id"`
+	Status  PetStatus `json:"status" validate:"required,oneof=available pending sold"`
+}
+
+// PetStatus is the "PetStatus" schema.
+type PetStatus string
+
+const (
+	PetStatusAvailable PetStatus = "available"
+	PetStatusPending   PetStatus = "pending"
+	PetStatusSold      PetStatus = "sold"
+)
+
+// UploadPhotoParams holds the parameters of UploadPhoto.
+type UploadPhotoParams struct {
+	PetID int64 `path:"petId"`
+}
+
+// ValidationError is the "ValidationError" schema.
+type ValidationError struct {
+	Fields map[string][]string `json:"fields,omitempty"`
+}
+
+// Client calls the operations of the API. Options given to NewClient apply to every call,
+// options given to an operation apply to that call only.
+type Client struct {
+	host     string
+	security Security
+	options  []clientmanager.Option
+}
+
+// Security holds the credentials of the security schemes. Schemes left empty are not sent.
+type Security struct {
+	// APIKey is sent as the "X-API-Key" header of "apiKey".
+	APIKey string
+	// BasicAuthUsername and BasicAuthPassword are the HTTP basic credentials of "basicAuth".
+	BasicAuthUsername string
+	BasicAuthPassword string
+	// BearerAuth is the bearer token of "bearerAuth".
+	BearerAuth string
+}
+
+// NewClient returns a client sending requests to host with the given credentials.
+func NewClient(host string, security Security, options ...clientmanager.Option) *Client {
+	return &Client{host: host, security: security, options: options}
+}
+
+// auth returns the authentication of the first security requirement whose credentials are all set.
+func (c *Client) auth(requirements [][]string) clientmanager.Auth {
+	for _, requirement := range requirements {
+		if len(requirement) == 0 {
+			return nil
+		}
+		auths := make([]clientmanager.Auth, 0, len(requirement))
+		for _, scheme := range requirement {
+			if auth := c.schemeAuth(scheme); auth != nil {
+				auths = append(auths, auth)
+			}
+		}
+		if len(auths) == len(requirement) {
+			return func(r *http.Request) error {
+				for _, auth := range auths {
+					if err := auth(r); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Client) schemeAuth(scheme string) clientmanager.Auth {
+	switch scheme {
+	case "apiKey":
+		if c.security.APIKey != "" {
+			return clientmanager.AuthAPIKey("X-API-Key", c.security.APIKey, false)
+		}
+	case "basicAuth":
+		if c.security.BasicAuthUsername != "" {
+			return clientmanager.AuthBasic(c.security.BasicAuthUsername, c.security.BasicAuthPassword)
+		}
+	case "bearerAuth":
+		if c.security.BearerAuth != "" {
+			return clientmanager.AuthBearer(c.security.BearerAuth)
+		}
+	}
+	return nil
+}
+
+func (c *Client) call(ctx context.Context, endpoint string, options []clientmanager.Option) (*clientmanager.BaseResponse[[]byte], error) {
+	opts := append([]clientmanager.Option{clientmanager.WithHost(c.host)}, c.options...)
+	return clientmanager.CallBytes(ctx, endpoint, append(opts, options...)...)
+}
+
+// ListPets lists the pets of the store.
+//
+//	GET /pets
+//
+// Error responses are returned as *clientmanager.ResponseError with the typed error body.
+func (c *Client) ListPets(ctx context.Context, params ListPetsParams, options ...clientmanager.Option) (*clientmanager.BaseResponse[ListPetsResponse], error) {
+	opts := []clientmanager.Option{
+		clientmanager.WithMethod(http.MethodGet),
+		clientmanager.WithQuery(params),
+	}
+	header := http.Header{}
+	header.Set("X-Request-ID", params.XRequestID)
+	opts = append(opts, clientmanager.WithHeaders(header))
+	if auth := c.auth([][]string{{"bearerAuth"}, {"apiKey"}}); auth != nil {
+		opts = append(opts, clientmanager.WithAuth(auth))
+	}
+
+	res, err := c.call(ctx, "/pets", append(opts, options...))
+	if err != nil {
+		return nil, err
+	}
+
+	if res.IsSuccess() {
+		return clientmanager.DecodeResponse[ListPetsResponse](res)
+	}
+	return nil, clientmanager.NewResponseError[Error](res)
+}
+
+// CreatePet adds a pet to the store.
+//
+//	POST /pets
+//
+// Error responses are returned as *clientmanager.ResponseError with the typed error body.
+func (c *Client) CreatePet(ctx context.Context, body NewPet, options ...clientmanager.Option) (*clientmanager.BaseResponse[Pet], error) {
+	opts := []clientmanager.Option{
+		clientmanager.WithMethod(http.MethodPost),
+		clientmanager.WithRequestBody(body),
+	}
+	if auth := c.auth([][]string{{"bearerAuth"}, {"apiKey"}}); auth != nil {
+		opts = append(opts, clientmanager.WithAuth(auth))
+	}
+
+	res, err := c.call(ctx, "/pets", append(opts, options...))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case res.StatusCode == 422:
+		return nil, clientmanager.NewResponseError[ValidationError](res)
+	case res.StatusCode >= 400 && res.StatusCode < 500:
+		return nil, clientmanager.NewResponseError[Error](res)
+	case res.IsSuccess():
+		return clientmanager.DecodeResponse[Pet](res)
+	}
+	return nil, clientmanager.NewResponseError[string](res)
+}
+
+// GetPetByID sends GET /pets/{petId}.
+//
+// Error responses are returned as *clientmanager.ResponseError with the typed error body.
+func (c *Client) GetPetByID(ctx context.Context, params GetPetByIDParams, options ...clientmanager.Option) (*clientmanager.BaseResponse[Pet], error) {
+	opts := []clientmanager.Option{
+		clientmanager.WithMethod(http.MethodGet),
+		clientmanager.WithPathParams(params),
+	}
+	if auth := c.auth([][]string{{"bearerAuth"}, {"apiKey"}}); auth != nil {
+		opts = append(opts, clientmanager.WithAuth(auth))
+	}
+
+	res, err := c.call(ctx, "/pets/{petId}", append(opts, options...))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case res.StatusCode == 404:
+		return nil, clientmanager.NewResponseError[Error](res)
+	case res.IsSuccess():
+		return clientmanager.DecodeResponse[Pet](res)
+	}
+	return nil, clientmanager.NewResponseError[string](res)
+}
+
+// DeletePetsByPetID removes a pet.
+//
+//	DELETE /pets/{petId}
+//
+// Deprecated: the operation is deprecated by the API.
+func (c *Client) DeletePetsByPetID(ctx context.Context, params DeletePetsByPetIDParams, options ...clientmanager.Option) (*clientmanager.BaseResponse[[]byte], error) {
+	opts := []clientmanager.Option{
+		clientmanager.WithMethod(http.MethodDelete),
+		clientmanager.WithPathParams(params),
+	}
+	if auth := c.auth([][]string{{"basicAuth"}}); auth != nil {
+		opts = append(opts, clientmanager.WithAuth(auth))
+	}
+
+	res, err := c.call(ctx, "/pets/{petId}", append(opts, options...))
+	if err != nil {
+		return nil, err
+	}
+
+	if res.IsSuccess() {
+		return res, nil
+	}
+	return nil, clientmanager.NewResponseError[string](res)
+}
+
+// UploadPhoto sends PUT /pets/{petId}/photo.
+func (c *Client) UploadPhoto(ctx context.Context, params UploadPhotoParams, body io.Reader, options ...clientmanager.Option) (*clientmanager.BaseResponse[[]byte], error) {
+	opts := []clientmanager.Option{
+		clientmanager.WithMethod(http.MethodPut),
+		clientmanager.WithPathParams(params),
+		clientmanager.WithBodyReader(body, "image/png"),
+	}
+
+	res, err := c.call(ctx, "/pets/{petId}/photo", append(opts, options...))
+	if err != nil {
+		return nil, err
+	}
+
+	if res.IsSuccess() {
+		return res, nil
+	}
+	return nil, clientmanager.NewResponseError[string](res)
+}
@@ -0,0 +1,3 @@
+package petstore
+
+//go:generate go run ../.. -spec ../../testdata/petstore.yaml -out client.gen.go
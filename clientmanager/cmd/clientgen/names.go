@@ -0,0 +1,100 @@
+package main
+
+import (
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+var initialisms = map[string]bool{
+	"API": true, "DNS": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "JWT": true, "SQL": true, "TLS": true, "TTL": true, "UI": true, "URI": true,
+	"URL": true, "UUID": true, "XML": true,
+}
+
+// splitWords splits an identifier on separators and camel-case boundaries.
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+	runes := []rune(s)
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+			continue
+		case unicode.IsUpper(r) && len(current) > 0:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+// goName converts an OpenAPI name into an exported Go identifier.
+func goName(s string) string {
+	var b strings.Builder
+	for _, word := range splitWords(s) {
+		upper := strings.ToUpper(word)
+		if initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	name := b.String()
+	if name == "" {
+		return "Value"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		name = "N" + name
+	}
+	return name
+}
+
+// goParamName converts an OpenAPI name into an unexported Go identifier.
+func goParamName(s string) string {
+	name := goName(s)
+	words := splitWords(name)
+	if len(words) > 0 && initialisms[words[0]] {
+		name = strings.ToLower(words[0]) + strings.TrimPrefix(name, words[0])
+	} else {
+		runes := []rune(name)
+		runes[0] = unicode.ToLower(runes[0])
+		name = string(runes)
+	}
+	if token.IsKeyword(name) {
+		name += "Value"
+	}
+	return name
+}
+
+// operationName returns the Go method name of an operation.
+func operationName(method, path, operationID string) string {
+	if operationID != "" {
+		return goName(operationID)
+	}
+	return goName(strings.ToLower(method) + " " + strings.NewReplacer("{", " by ", "}", " ").Replace(path))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
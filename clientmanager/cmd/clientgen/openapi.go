@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const schemaRefPrefix = "#/components/"
+
+// document is the subset of an OpenAPI 3 document the generator understands.
+type document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       info                  `json:"info"`
+	Paths      map[string]*pathItem  `json:"paths"`
+	Components components            `json:"components"`
+	Security   []securityRequirement `json:"security"`
+}
+
+type info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type components struct {
+	Schemas         map[string]*schema         `json:"schemas"`
+	Parameters      map[string]*parameter      `json:"parameters"`
+	RequestBodies   map[string]*requestBody    `json:"requestBodies"`
+	Responses       map[string]*response       `json:"responses"`
+	SecuritySchemes map[string]*securityScheme `json:"securitySchemes"`
+}
+
+type pathItem struct {
+	Parameters []*parameter `json:"parameters"`
+	Get        *operation   `json:"get"`
+	Put        *operation   `json:"put"`
+	Post       *operation   `json:"post"`
+	Delete     *operation   `json:"delete"`
+	Options    *operation   `json:"options"`
+	Head       *operation   `json:"head"`
+	Patch      *operation   `json:"patch"`
+}
+
+// operations returns the operations of the path in a fixed method order.
+func (p *pathItem) operations() []struct {
+	method string
+	op     *operation
+} {
+	all := []struct {
+		method string
+		op     *operation
+	}{
+		{"GET", p.Get}, {"PUT", p.Put}, {"POST", p.Post}, {"DELETE", p.Delete},
+		{"OPTIONS", p.Options}, {"HEAD", p.Head}, {"PATCH", p.Patch},
+	}
+	result := all[:0]
+	for _, entry := range all {
+		if entry.op != nil {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+type operation struct {
+	OperationID string                 `json:"operationId"`
+	Summary     string                 `json:"summary"`
+	Description string                 `json:"description"`
+	Deprecated  bool                   `json:"deprecated"`
+	Parameters  []*parameter           `json:"parameters"`
+	RequestBody *requestBody           `json:"requestBody"`
+	Responses   map[string]*response   `json:"responses"`
+	Security    *[]securityRequirement `json:"security"`
+}
+
+type parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Explode     *bool   `json:"explode"`
+	Schema      *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Required    bool                  `json:"required"`
+	Content     map[string]*mediaType `json:"content"`
+}
+
+type response struct {
+	Ref         string                `json:"$ref"`
+	Description string                `json:"description"`
+	Content     map[string]*mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type securityRequirement map[string][]string
+
+type securityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+	Name   string `json:"name"`
+	In     string `json:"in"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	AllOf                []*schema          `json:"allOf"`
+	OneOf                []*schema          `json:"oneOf"`
+	AnyOf                []*schema          `json:"anyOf"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+}
+
+// additional returns the schema of additionalProperties, or nil when it is absent or false.
+func (s *schema) additional() (*schema, error) {
+	raw := strings.TrimSpace(string(s.AdditionalProperties))
+	switch raw {
+	case "", "false", "null":
+		return nil, nil
+	case "true":
+		return &schema{}, nil
+	}
+	var additional schema
+	if err := json.Unmarshal(s.AdditionalProperties, &additional); err != nil {
+		return nil, fmt.Errorf("additionalProperties: %w", err)
+	}
+	return &additional, nil
+}
+
+func (s *schema) isRequired(name string) bool {
+	for _, required := range s.Required {
+		if required == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDocument reads an OpenAPI 3 document in JSON or YAML.
+func parseDocument(data []byte) (*document, error) {
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+	normalized, err := json.Marshal(normalizeYAML(raw))
+	if err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+
+	var doc document
+	if err := json.Unmarshal(normalized, &doc); err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q, want 3.x", doc.OpenAPI)
+	}
+	return &doc, nil
+}
+
+// normalizeYAML turns the non-string map keys YAML allows (such as response codes) into strings.
+func normalizeYAML(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for key, item := range val {
+			val[key] = normalizeYAML(item)
+		}
+		return val
+	case map[any]any:
+		result := make(map[string]any, len(val))
+		for key, item := range val {
+			result[fmt.Sprint(key)] = normalizeYAML(item)
+		}
+		return result
+	case []any:
+		for i, item := range val {
+			val[i] = normalizeYAML(item)
+		}
+		return val
+	}
+	return v
+}
+
+// refName returns the component name of a local reference of the given kind.
+func refName(ref, kind string) (string, error) {
+	prefix := schemaRefPrefix + kind + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return "", fmt.Errorf("unsupported reference %q, want %s<name>", ref, prefix)
+	}
+	return strings.TrimPrefix(ref, prefix), nil
+}
+
+func (d *document) parameter(p *parameter) (*parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, err := refName(p.Ref, "parameters")
+	if err != nil {
+		return nil, err
+	}
+	resolved, ok := d.Components.Parameters[name]
+	if !ok {
+		return nil, fmt.Errorf("parameter %q not found", p.Ref)
+	}
+	return resolved, nil
+}
+
+func (d *document) requestBody(b *requestBody) (*requestBody, error) {
+	if b.Ref == "" {
+		return b, nil
+	}
+	name, err := refName(b.Ref, "requestBodies")
+	if err != nil {
+		return nil, err
+	}
+	resolved, ok := d.Components.RequestBodies[name]
+	if !ok {
+		return nil, fmt.Errorf("request body %q not found", b.Ref)
+	}
+	return resolved, nil
+}
+
+func (d *document) response(r *response) (*response, error) {
+	if r.Ref == "" {
+		return r, nil
+	}
+	name, err := refName(r.Ref, "responses")
+	if err != nil {
+		return nil, err
+	}
+	resolved, ok := d.Components.Responses[name]
+	if !ok {
+		return nil, fmt.Errorf("response %q not found", r.Ref)
+	}
+	return resolved, nil
+}
+
+// jsonSchema returns the schema of the JSON media type of the content, or nil when there is none.
+func jsonSchema(content map[string]*mediaType) *schema {
+	for _, contentType := range sortedKeys(content) {
+		if contentType == "application/json" || strings.HasSuffix(contentType, "+json") {
+			if media := content[contentType]; media != nil {
+				return media.Schema
+			}
+		}
+	}
+	return nil
+}
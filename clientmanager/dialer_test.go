@@ -0,0 +1,118 @@
+package clientmanager_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/clientmanager"
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUnixSocketServer(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	ts := httptest.NewUnstartedServer(handler)
+	_ = ts.Listener.Close()
+	ts.Listener = listener
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	return socket
+}
+
+func TestWithUnixSocket(t *testing.T) {
+	app := logmanager.NewApplication()
+	txn := app.Start("test", "cli", logmanager.TxnTypeOther)
+	ctx := txn.ToContext(context.Background())
+	defer txn.End()
+
+	socket := newUnixSocketServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host + " " + r.URL.Path))
+	}))
+
+	t.Run("default host", func(t *testing.T) {
+		spy := &recorderSpy{}
+		res, err := clientmanager.New[string](
+			clientmanager.WithUnixSocket(socket),
+			clientmanager.WithMetrics(spy),
+		).Call(ctx, "/v1/token")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost /v1/token", res.Body)
+		assert.Equal(t, socket, spy.observations[0].Labels.Upstream)
+	})
+
+	t.Run("custom host", func(t *testing.T) {
+		res, err := clientmanager.New[string](
+			clientmanager.WithHost("http://envoy-admin"),
+			clientmanager.WithUnixSocket(socket),
+		).Call(ctx, "/ready")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "envoy-admin /ready", res.Body)
+	})
+
+	t.Run("behind auth transport wrapper", func(t *testing.T) {
+		res, err := clientmanager.New[string](
+			clientmanager.WithAuthDigest("user", "pass"),
+			clientmanager.WithUnixSocket(socket),
+		).Call(ctx, "/digest")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost /digest", res.Body)
+	})
+
+	t.Run("with dialer control", func(t *testing.T) {
+		res, err := clientmanager.New[string](
+			clientmanager.WithDialerControl(func(network, address string, c syscall.RawConn) error {
+				return errors.New("tcp only")
+			}),
+			clientmanager.WithUnixSocket(socket),
+		).Call(ctx, "/")
+
+		assert.NoError(t, err)
+		assert.True(t, res.IsSuccess())
+	})
+}
+
+func TestWithDialer(t *testing.T) {
+	app := logmanager.NewApplication()
+	txn := app.Start("test", "cli", logmanager.TxnTypeOther)
+	ctx := txn.ToContext(context.Background())
+	defer txn.End()
+
+	socket := newUnixSocketServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	var dials atomic.Int32
+	dialer := func(ctx context.Context, network, address string) (net.Conn, error) {
+		dials.Add(1)
+		assert.Equal(t, "docker:80", address)
+		return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+	}
+
+	res, err := clientmanager.New[string](
+		clientmanager.WithHost("http://docker"),
+		clientmanager.WithDialContext(time.Second, time.Minute),
+		clientmanager.WithDialer(dialer),
+		clientmanager.WithUnixSocket("/does/not/exist.sock"),
+	).Call(ctx, "/containers/json")
+
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.Equal(t, int32(1), dials.Load())
+}
@@ -0,0 +1,230 @@
+package clientmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+)
+
+const persistedQueryNotFound = "PersistedQueryNotFound"
+
+var operationNamePattern = regexp.MustCompile(`\b(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+
+// GraphQLLocation is a position in the query document an error refers to.
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// GraphQLError is an entry of the `errors` array of a GraphQL response.
+type GraphQLError struct {
+	Message    string            `json:"message"`
+	Locations  []GraphQLLocation `json:"locations,omitempty"`
+	Path       []any             `json:"path,omitempty"`
+	Extensions map[string]any    `json:"extensions,omitempty"`
+}
+
+func (e GraphQLError) Error() string {
+	return e.Message
+}
+
+// Code returns the `extensions.code` of the error, if any.
+func (e GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// GraphQLErrors is returned by CallGraphQL when the response contains errors.
+// The response is returned alongside it, so partial data can still be used.
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+	return "graphql: " + strings.Join(messages, "; ")
+}
+
+// Unwrap lets errors.As reach each GraphQLError.
+func (e GraphQLErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+func (e GraphQLErrors) persistedQueryNotFound() bool {
+	for _, err := range e {
+		if err.Message == persistedQueryNotFound || err.Code() == "PERSISTED_QUERY_NOT_FOUND" {
+			return true
+		}
+	}
+	return false
+}
+
+type graphQLRequest struct {
+	Query         string         `json:"query,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     any            `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+type graphQLResponse[Data any] struct {
+	Data   Data          `json:"data"`
+	Errors GraphQLErrors `json:"errors"`
+}
+
+// operationName returns the name of the first named operation in the query.
+func operationName(query string) string {
+	if match := operationNamePattern.FindStringSubmatch(query); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// rebaseMaskingConfigs makes the JSONPath expressions relative to the given path.
+func rebaseMaskingConfigs(configs []logmanager.MaskingConfig, path string) []logmanager.MaskingConfig {
+	rebased := make([]logmanager.MaskingConfig, len(configs))
+	for i, config := range configs {
+		if strings.HasPrefix(config.JSONPath, "$") {
+			config.JSONPath = path + strings.TrimPrefix(config.JSONPath, "$")
+		}
+		rebased[i] = config
+	}
+	return rebased
+}
+
+func callGraphQL[Vars, Data any](
+	ctx context.Context,
+	endpoint string,
+	query string,
+	vars Vars,
+	cOptions callOptions,
+) (*BaseResponse[Data], error) {
+	payload := graphQLRequest{
+		Query:         query,
+		OperationName: cOptions.operationName,
+		Variables:     vars,
+	}
+	if payload.OperationName == "" {
+		payload.OperationName = operationName(query)
+	}
+	if payload.OperationName != "" {
+		cOptions.segment = payload.OperationName
+	}
+	cOptions.maskingConfigs = rebaseMaskingConfigs(cOptions.maskingConfigs, "$.variables")
+
+	if cOptions.persistedQuery {
+		hash := sha256.Sum256([]byte(query))
+		payload.Extensions = map[string]any{
+			"persistedQuery": map[string]any{
+				"version":    1,
+				"sha256Hash": hex.EncodeToString(hash[:]),
+			},
+		}
+
+		// Send the hash only; the query is sent once the server reports it unknown.
+		hashOnly := payload
+		hashOnly.Query = ""
+		res, err := sendGraphQL[Data](ctx, endpoint, hashOnly, cOptions, true)
+		if res != nil || err != nil {
+			return res, err
+		}
+	}
+
+	return sendGraphQL[Data](ctx, endpoint, payload, cOptions, false)
+}
+
+// sendGraphQL sends one GraphQL request. When persistedLookup is true and the server does
+// not know the persisted query, it returns nil, nil so the caller can send the full query.
+func sendGraphQL[Data any](
+	ctx context.Context,
+	endpoint string,
+	payload graphQLRequest,
+	cOptions callOptions,
+	persistedLookup bool,
+) (*BaseResponse[Data], error) {
+	cOptions.requestBody = payload
+	cOptions.isFormURLEncoded = false
+
+	res, txn, err := dispatch(ctx, endpoint, cOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.End()
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	raw := readBody(res, cOptions.maxResponseBytes)
+
+	var body graphQLResponse[Data]
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &body); err != nil {
+			txn.NoticeError(err)
+
+			return nil, err
+		}
+	}
+
+	if persistedLookup && body.Errors.persistedQueryNotFound() {
+		txn.AddTags("persisted-query:miss")
+
+		return nil, nil
+	}
+
+	response := &BaseResponse[Data]{
+		StatusCode: res.StatusCode,
+		Header:     res.Header.Clone(),
+		Body:       body.Data,
+		Raw:        raw,
+	}
+	if len(body.Errors) > 0 {
+		txn.SetBusinessError(body.Errors)
+
+		return response, body.Errors
+	}
+
+	return response, nil
+}
+
+// CallGraphQL sends a GraphQL operation to endpoint and decodes `data` into Data.
+//
+// When the response contains `errors`, they are returned as GraphQLErrors together with the
+// response, so partial data is kept. The logmanager segment is named after the operation name,
+// and JSONPath expressions of WithMaskingConfig are relative to the variables.
+//
+// Example:
+//
+//	type AccountVars struct {
+//	    Number string `json:"number"`
+//	}
+//	type AccountData struct {
+//	    Account struct {
+//	        Name string `json:"name"`
+//	    } `json:"account"`
+//	}
+//
+//	res, err := clientmanager.CallGraphQL[AccountVars, AccountData](ctx,
+//	    "https://partner.example.com/graphql",
+//	    `query AccountName($number: String!) { account(number: $number) { name } }`,
+//	    AccountVars{Number: "1234567890"},
+//	)
+func CallGraphQL[Vars, Data any](ctx context.Context, endpoint, query string, vars Vars, options ...Option) (*BaseResponse[Data], error) {
+	var cOptions = callOptions{
+		client: client,
+		method: http.MethodPost,
+	}
+
+	cOptions.setOptions(options...)
+
+	return callGraphQL[Vars, Data](ctx, endpoint, query, vars, cOptions)
+}
@@ -0,0 +1,145 @@
+package clientmanager_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SALT-Indonesia/salt-pkg/clientmanager"
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type accountVars struct {
+	Number string `json:"number"`
+	PIN    string `json:"pin"`
+}
+
+type accountData struct {
+	Account *struct {
+		Name string `json:"name"`
+	} `json:"account"`
+	Balance *int `json:"balance"`
+}
+
+type graphQLPayload struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     accountVars    `json:"variables"`
+	Extensions    map[string]any `json:"extensions"`
+}
+
+const accountQuery = `query AccountName($number: String!) { account(number: $number) { name } balance }`
+
+func TestCallGraphQL(t *testing.T) {
+	app := logmanager.NewApplication()
+	txn := app.Start("test", "cli", logmanager.TxnTypeOther)
+	ctx := txn.ToContext(context.Background())
+	defer txn.End()
+
+	t.Run("data", func(t *testing.T) {
+		var got graphQLPayload
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data":{"account":{"name":"Budi"},"balance":100}}`))
+		}))
+		defer ts.Close()
+
+		res, err := clientmanager.CallGraphQL[accountVars, accountData](ctx, ts.URL, accountQuery,
+			accountVars{Number: "123", PIN: "999999"},
+			clientmanager.WithMaskingConfig([]logmanager.MaskingConfig{{JSONPath: "$.pin", Type: logmanager.FullMask}}),
+		)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Budi", res.Body.Account.Name)
+		assert.Equal(t, 100, *res.Body.Balance)
+		assert.Equal(t, accountQuery, got.Query)
+		assert.Equal(t, "AccountName", got.OperationName)
+		assert.Equal(t, accountVars{Number: "123", PIN: "999999"}, got.Variables)
+		assert.Nil(t, got.Extensions)
+	})
+
+	t.Run("errors with partial data", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{
+				"data": {"account": {"name": "Budi"}, "balance": null},
+				"errors": [{
+					"message": "balance unavailable",
+					"locations": [{"line": 1, "column": 70}],
+					"path": ["balance"],
+					"extensions": {"code": "UPSTREAM_DOWN"}
+				}]
+			}`))
+		}))
+		defer ts.Close()
+
+		res, err := clientmanager.CallGraphQL[accountVars, accountData](ctx, ts.URL, accountQuery, accountVars{Number: "123"},
+			clientmanager.WithOperationName("Lookup"),
+		)
+
+		var gqlErrs clientmanager.GraphQLErrors
+		require.ErrorAs(t, err, &gqlErrs)
+		assert.Len(t, gqlErrs, 1)
+		assert.Equal(t, "graphql: balance unavailable", err.Error())
+		assert.Equal(t, []any{"balance"}, gqlErrs[0].Path)
+		assert.Equal(t, clientmanager.GraphQLLocation{Line: 1, Column: 70}, gqlErrs[0].Locations[0])
+
+		var gqlErr clientmanager.GraphQLError
+		require.True(t, errors.As(err, &gqlErr))
+		assert.Equal(t, "UPSTREAM_DOWN", gqlErr.Code())
+
+		require.NotNil(t, res)
+		assert.Equal(t, "Budi", res.Body.Account.Name)
+		assert.Nil(t, res.Body.Balance)
+	})
+
+	t.Run("persisted query", func(t *testing.T) {
+		var calls atomic.Int32
+		var registered string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			var payload graphQLPayload
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			persisted := payload.Extensions["persistedQuery"].(map[string]any)
+			hash := persisted["sha256Hash"].(string)
+			assert.Len(t, hash, 64)
+
+			if payload.Query == "" && registered != hash {
+				_, _ = w.Write([]byte(`{"errors":[{"message":"PersistedQueryNotFound"}]}`))
+				return
+			}
+			registered = hash
+			_, _ = w.Write([]byte(`{"data":{"balance":5}}`))
+		}))
+		defer ts.Close()
+
+		for _, wantCalls := range []int32{2, 3} {
+			res, err := clientmanager.CallGraphQL[map[string]any, accountData](ctx, ts.URL, accountQuery, nil,
+				clientmanager.WithPersistedQuery(),
+			)
+
+			require.NoError(t, err)
+			assert.Equal(t, 5, *res.Body.Balance)
+			assert.Equal(t, wantCalls, calls.Load())
+		}
+	})
+
+	t.Run("invalid response", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`<html>`))
+		}))
+		defer ts.Close()
+
+		res, err := clientmanager.CallGraphQL[map[string]any, accountData](ctx, ts.URL, `{ balance }`, nil)
+
+		assert.Error(t, err)
+		assert.Nil(t, res)
+	})
+}
@@ -0,0 +1,176 @@
+package clientmanager
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+)
+
+// Tags added to the logmanager segment of each hedged attempt.
+const (
+	tagHedge          = "hedge"
+	tagHedgeWon       = "hedge:won"
+	tagHedgeCancelled = "hedge:cancelled"
+	tagHedgeFailed    = "hedge:failed"
+)
+
+// idempotentMethods are the methods defined as idempotent by RFC 9110, section 9.2.2.
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// canHedge reports whether the request may be sent more than once. Requests with a
+// body reader are never hedged because the reader cannot be replayed.
+func (c callOptions) canHedge() bool {
+	return c.maxHedges > 0 && c.hedgeDelay > 0 && c.bodyReader == nil && idempotentMethods[c.method]
+}
+
+type hedgeAttempt struct {
+	number int
+	res    *http.Response
+	txn    *logmanager.TxnRecord
+	err    error
+}
+
+func (a hedgeAttempt) succeeded() bool {
+	return a.err == nil && a.res.StatusCode < http.StatusInternalServerError
+}
+
+// executeHedged sends the request, and another identical one every hedgeDelay while no
+// attempt has succeeded, up to maxHedges extra attempts. The first successful response
+// wins and the other attempts are cancelled. When no attempt succeeds, the first server
+// error response is returned, or the last transport error.
+func executeHedged(ctx context.Context, endpoint string, cOptions callOptions) (*http.Response, *logmanager.TxnRecord, error) {
+	total := 1 + cOptions.maxHedges
+	results := make(chan hedgeAttempt, total)
+	cancels := make([]context.CancelFunc, 0, total)
+
+	launch := func() {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		number := len(cancels)
+		go func() {
+			res, txn, err := execute(attemptCtx, endpoint, cOptions)
+			txn.AddTags(tagHedge, "attempt:"+strconv.Itoa(number))
+			results <- hedgeAttempt{number: number, res: res, txn: txn, err: err}
+		}()
+	}
+
+	timer := time.NewTimer(cOptions.hedgeDelay)
+	defer timer.Stop()
+
+	launch()
+	var (
+		finished []hedgeAttempt
+		winner   *hedgeAttempt
+	)
+	for winner == nil && len(finished) < total {
+		if len(finished) == len(cancels) {
+			// Every attempt so far has failed: hedge right away instead of waiting.
+			launch()
+			timer.Reset(cOptions.hedgeDelay)
+		}
+
+		select {
+		case <-timer.C:
+			if len(cancels) < total {
+				launch()
+				timer.Reset(cOptions.hedgeDelay)
+			}
+		case attempt := <-results:
+			if attempt.succeeded() {
+				winner = &attempt
+				continue
+			}
+			finished = append(finished, attempt)
+		}
+	}
+
+	if winner != nil {
+		// Cancel the attempts still in flight and end their segments once they return.
+		inFlight := len(cancels) - len(finished) - 1
+		for i, cancel := range cancels {
+			if i+1 != winner.number {
+				cancel()
+			}
+		}
+		go func() {
+			for i := 0; i < inFlight; i++ {
+				endHedgeAttempt(<-results, tagHedgeCancelled)
+			}
+		}()
+		for _, attempt := range finished {
+			endHedgeAttempt(attempt, tagHedgeFailed)
+		}
+
+		winner.txn.AddTags(tagHedgeWon)
+		winner.res.Body = &cancelOnClose{ReadCloser: winner.res.Body, cancel: cancels[winner.number-1]}
+
+		return winner.res, winner.txn, nil
+	}
+
+	// Every attempt has returned without success.
+	fallback := pickHedgeFallback(finished)
+	for _, attempt := range finished {
+		if attempt.number != fallback.number {
+			endHedgeAttempt(attempt, tagHedgeFailed)
+		}
+	}
+	if fallback.err != nil {
+		for _, cancel := range cancels {
+			cancel()
+		}
+		fallback.txn.NoticeError(fallback.err)
+
+		return nil, nil, fallback.err
+	}
+	for i, cancel := range cancels {
+		if i+1 != fallback.number {
+			cancel()
+		}
+	}
+	fallback.res.Body = &cancelOnClose{ReadCloser: fallback.res.Body, cancel: cancels[fallback.number-1]}
+
+	return fallback.res, fallback.txn, nil
+}
+
+// pickHedgeFallback returns the first attempt with a response, or the last failed one.
+func pickHedgeFallback(finished []hedgeAttempt) *hedgeAttempt {
+	for i := range finished {
+		if finished[i].err == nil {
+			return &finished[i]
+		}
+	}
+	return &finished[len(finished)-1]
+}
+
+// endHedgeAttempt ends a losing attempt. Its error is not noticed, so a hedged call
+// logs at most one error.
+func endHedgeAttempt(attempt hedgeAttempt, tag string) {
+	if attempt.res != nil {
+		_ = attempt.res.Body.Close()
+	}
+	attempt.txn.AddTags(tag)
+	attempt.txn.End()
+}
+
+// cancelOnClose releases the winning attempt's context once its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,165 @@
+package clientmanager_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/clientmanager"
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithHedging(t *testing.T) {
+	app := logmanager.NewApplication()
+	txn := app.Start("test", "cli", logmanager.TxnTypeOther)
+	ctx := txn.ToContext(context.Background())
+	defer txn.End()
+
+	t.Run("hedge wins over a slow first attempt", func(t *testing.T) {
+		var count atomic.Int32
+		cancelled := make(chan struct{}, 1)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if count.Add(1) == 1 {
+				select {
+				case <-r.Context().Done():
+					cancelled <- struct{}{}
+				case <-time.After(2 * time.Second):
+				}
+				_, _ = w.Write([]byte("slow"))
+				return
+			}
+			_, _ = w.Write([]byte("fast"))
+		}))
+		defer ts.Close()
+
+		start := time.Now()
+		res, err := clientmanager.Call[string](ctx, "/balance",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithHedging(50*time.Millisecond, 2),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "fast", res.Body)
+		assert.Less(t, time.Since(start), time.Second)
+		select {
+		case <-cancelled:
+		case <-time.After(time.Second):
+			t.Error("slow attempt was not cancelled")
+		}
+		assert.Equal(t, int32(2), count.Load())
+	})
+
+	t.Run("fast response is not hedged", func(t *testing.T) {
+		var count atomic.Int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			count.Add(1)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+
+		res, err := clientmanager.Call[string](ctx, "/",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithHedging(200*time.Millisecond, 2),
+		)
+
+		assert.NoError(t, err)
+		assert.True(t, res.IsSuccess())
+		assert.Equal(t, int32(1), count.Load())
+	})
+
+	t.Run("non-idempotent method is not hedged", func(t *testing.T) {
+		var count atomic.Int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			count.Add(1)
+			time.Sleep(100 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+
+		_, err := clientmanager.Call[string](ctx, "/",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithMethod(http.MethodPost),
+			clientmanager.WithHedging(10*time.Millisecond, 2),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), count.Load())
+	})
+
+	t.Run("server errors return the first response", func(t *testing.T) {
+		var count atomic.Int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			count.Add(1)
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("down"))
+		}))
+		defer ts.Close()
+
+		res, err := clientmanager.Call[string](ctx, "/",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithHedging(time.Second, 2),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadGateway, res.StatusCode)
+		assert.Equal(t, "down", res.Body)
+		assert.Equal(t, int32(3), count.Load())
+	})
+
+	t.Run("transport errors return one error", func(t *testing.T) {
+		ts := httptest.NewServer(http.NotFoundHandler())
+		ts.Close()
+
+		_, err := clientmanager.Call[string](ctx, "/",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithHedging(10*time.Millisecond, 1),
+		)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("stream body stays readable", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(strings.Repeat("x", 1024)))
+		}))
+		defer ts.Close()
+
+		res, err := clientmanager.CallBytes(ctx, "/",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithHedging(10*time.Millisecond, 1),
+		)
+
+		assert.NoError(t, err)
+		assert.Len(t, res.Body, 1024)
+	})
+	t.Run("hedged attempts share the headers without mutating them", func(t *testing.T) {
+		var count atomic.Int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if count.Add(1) < 4 {
+				time.Sleep(100 * time.Millisecond)
+			}
+			_, _ = w.Write([]byte(r.Header.Get("X-Tenant") + " " + r.Header.Get("Authorization")))
+		}))
+		defer ts.Close()
+
+		headers := http.Header{"X-Tenant": []string{"acme"}}
+		res, err := clientmanager.Call[string](ctx, "/",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithHeaders(headers),
+			clientmanager.WithAuth(func(r *http.Request) error {
+				r.Header.Set("Authorization", "Bearer token")
+				return nil
+			}),
+			clientmanager.WithHedging(time.Millisecond, 3),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "acme Bearer token", res.Body)
+		assert.Equal(t, http.Header{"X-Tenant": []string{"acme"}}, headers, "the caller's headers are not modified")
+	})
+}
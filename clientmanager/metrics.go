@@ -0,0 +1,209 @@
+package clientmanager
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Error types reported in RequestObservation.ErrorType.
+const (
+	ErrorTypeTimeout    = "timeout"
+	ErrorTypeCanceled   = "canceled"
+	ErrorTypeDNS        = "dns"
+	ErrorTypeConnection = "connection"
+	ErrorTypeTLS        = "tls"
+	ErrorTypeOther      = "other"
+)
+
+// MetricLabels identifies the upstream call a measurement belongs to.
+//
+// Endpoint is the endpoint as passed to Call, so it stays a low-cardinality
+// template when the path is filled through WithPathParams.
+type MetricLabels struct {
+	Upstream string
+	Method   string
+	Endpoint string
+}
+
+// RequestObservation holds the measurements of one finished upstream request.
+type RequestObservation struct {
+	Labels MetricLabels
+
+	// StatusCode is zero when the request failed before a response was received.
+	StatusCode int
+	// ErrorType is empty on success, otherwise one of the ErrorType constants.
+	ErrorType string
+	// Duration is the time until the response headers were received or the request failed.
+	Duration time.Duration
+
+	// ConnObtained reports whether a connection was obtained; ConnReused whether it came from the idle pool.
+	ConnObtained bool
+	ConnReused   bool
+
+	// DNSDuration, ConnectDuration and TLSDuration are zero when the phase did not happen.
+	DNSDuration     time.Duration
+	ConnectDuration time.Duration
+	TLSDuration     time.Duration
+}
+
+// StatusClass returns the status class of the response ("2xx", "4xx", ...) or "error"
+// when no response was received.
+func (o RequestObservation) StatusClass() string {
+	if o.StatusCode == 0 {
+		return "error"
+	}
+	return strconv.Itoa(o.StatusCode/100) + "xx"
+}
+
+// MetricsRecorder receives client-side request measurements.
+// Implementations must be safe for concurrent use.
+type MetricsRecorder interface {
+	// RequestStarted is called right before the request is sent.
+	RequestStarted(labels MetricLabels)
+	// RequestFinished is called once the response headers are received or the request failed.
+	RequestFinished(observation RequestObservation)
+}
+
+// metricsProbe collects the httptrace timings of a single request.
+type metricsProbe struct {
+	recorder MetricsRecorder
+	start    time.Time
+
+	mu                               sync.Mutex
+	observation                      RequestObservation
+	dnsStart, connectStart, tlsStart time.Time
+}
+
+func startMetricsProbe(recorder MetricsRecorder, labels MetricLabels) *metricsProbe {
+	recorder.RequestStarted(labels)
+
+	return &metricsProbe{
+		recorder:    recorder,
+		start:       time.Now(),
+		observation: RequestObservation{Labels: labels},
+	}
+}
+
+func (p *metricsProbe) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.observation.ConnObtained = true
+			p.observation.ConnReused = info.Reused
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.observation.DNSDuration = time.Since(p.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.connectStart = time.Now()
+		},
+		ConnectDone: func(string, string, error) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.observation.ConnectDuration = time.Since(p.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			p.observation.TLSDuration = time.Since(p.tlsStart)
+		},
+	}
+}
+
+// withTrace returns a shallow copy of req whose context carries the probe's client trace.
+func (p *metricsProbe) withTrace(req *http.Request) *http.Request {
+	if p == nil {
+		return req
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), p.clientTrace()))
+}
+
+func (p *metricsProbe) finish(res *http.Response, err error) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	observation := p.observation
+	p.mu.Unlock()
+
+	observation.Duration = time.Since(p.start)
+	if res != nil {
+		observation.StatusCode = res.StatusCode
+	}
+	if err != nil {
+		observation.ErrorType = classifyError(err)
+	}
+
+	p.recorder.RequestFinished(observation)
+}
+
+// classifyError maps a transport error to one of the ErrorType constants.
+func classifyError(err error) string {
+	var (
+		dnsErr         *net.DNSError
+		netErr         net.Error
+		opErr          *net.OpError
+		recordErr      tls.RecordHeaderError
+		certErr        *tls.CertificateVerificationError
+		unknownAuthErr x509.UnknownAuthorityError
+		hostnameErr    x509.HostnameError
+	)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorTypeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTypeTimeout
+	case errors.As(err, &dnsErr):
+		return ErrorTypeDNS
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTypeTimeout
+	case errors.As(err, &recordErr), errors.As(err, &certErr),
+		errors.As(err, &unknownAuthErr), errors.As(err, &hostnameErr):
+		return ErrorTypeTLS
+	case errors.As(err, &opErr):
+		return ErrorTypeConnection
+	}
+	return ErrorTypeOther
+}
+
+// metricLabels builds the labels of the request. The upstream defaults to the request host,
+// or the socket path for requests over a Unix socket.
+func (c callOptions) metricLabels(req *http.Request, endpoint string) MetricLabels {
+	upstream := c.upstreamName
+	if upstream == "" {
+		upstream = req.URL.Host
+		if c.unixSocket != "" {
+			upstream = c.unixSocket
+		}
+	}
+	return MetricLabels{
+		Upstream: upstream,
+		Method:   req.Method,
+		Endpoint: endpoint,
+	}
+}
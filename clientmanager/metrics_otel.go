@@ -0,0 +1,120 @@
+package clientmanager
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// OTelMetrics is a MetricsRecorder that records measurements as OpenTelemetry instruments.
+type OTelMetrics struct {
+	requests        metric.Int64Counter
+	errors          metric.Int64Counter
+	inFlight        metric.Int64UpDownCounter
+	connections     metric.Int64Counter
+	duration        metric.Float64Histogram
+	dnsDuration     metric.Float64Histogram
+	connectDuration metric.Float64Histogram
+	tlsDuration     metric.Float64Histogram
+}
+
+// NewOTelMetrics creates the clientmanager instruments on the given meter.
+//
+// Example:
+//
+//	recorder, err := clientmanager.NewOTelMetrics(otel.Meter("clientmanager"))
+//	if err != nil {
+//	    return err
+//	}
+//	clientmanager.Call[Balance](ctx, "/balance", clientmanager.WithMetrics(recorder))
+func NewOTelMetrics(meter metric.Meter) (*OTelMetrics, error) {
+	var (
+		m    OTelMetrics
+		err  error
+		errs []error
+	)
+
+	m.requests, err = meter.Int64Counter("clientmanager.requests",
+		metric.WithDescription("Total upstream requests by status class."),
+		metric.WithUnit("{request}"))
+	errs = append(errs, err)
+	m.errors, err = meter.Int64Counter("clientmanager.errors",
+		metric.WithDescription("Total upstream requests that failed without a response, by error type."),
+		metric.WithUnit("{request}"))
+	errs = append(errs, err)
+	m.inFlight, err = meter.Int64UpDownCounter("clientmanager.requests.in_flight",
+		metric.WithDescription("Upstream requests currently waiting for a response."),
+		metric.WithUnit("{request}"))
+	errs = append(errs, err)
+	m.connections, err = meter.Int64Counter("clientmanager.connections",
+		metric.WithDescription("Connections obtained for upstream requests, by reuse from the idle pool."),
+		metric.WithUnit("{connection}"))
+	errs = append(errs, err)
+	m.duration, err = meter.Float64Histogram("clientmanager.request.duration",
+		metric.WithDescription("Time until the upstream response headers were received."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(DefaultLatencyBuckets...))
+	errs = append(errs, err)
+	m.dnsDuration, err = meter.Float64Histogram("clientmanager.dns.duration",
+		metric.WithDescription("DNS lookup time for new upstream connections."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(DefaultLatencyBuckets...))
+	errs = append(errs, err)
+	m.connectDuration, err = meter.Float64Histogram("clientmanager.connect.duration",
+		metric.WithDescription("Dial time for new upstream connections."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(DefaultLatencyBuckets...))
+	errs = append(errs, err)
+	m.tlsDuration, err = meter.Float64Histogram("clientmanager.tls_handshake.duration",
+		metric.WithDescription("TLS handshake time for new upstream connections."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(DefaultLatencyBuckets...))
+	errs = append(errs, err)
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func endpointAttributes(l MetricLabels, extra ...attribute.KeyValue) metric.MeasurementOption {
+	return metric.WithAttributes(append([]attribute.KeyValue{
+		attribute.String("upstream", l.Upstream),
+		attribute.String("http.request.method", l.Method),
+		attribute.String("endpoint", l.Endpoint),
+	}, extra...)...)
+}
+
+func (m *OTelMetrics) RequestStarted(labels MetricLabels) {
+	m.inFlight.Add(context.Background(), 1, endpointAttributes(labels))
+}
+
+func (m *OTelMetrics) RequestFinished(o RequestObservation) {
+	ctx := context.Background()
+	l := o.Labels
+	upstream := metric.WithAttributes(attribute.String("upstream", l.Upstream))
+
+	m.inFlight.Add(ctx, -1, endpointAttributes(l))
+	m.requests.Add(ctx, 1, endpointAttributes(l, attribute.String("status_class", o.StatusClass())))
+	m.duration.Record(ctx, o.Duration.Seconds(), endpointAttributes(l))
+	if o.ErrorType != "" {
+		m.errors.Add(ctx, 1, endpointAttributes(l, attribute.String("error.type", o.ErrorType)))
+	}
+	if o.ConnObtained {
+		m.connections.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("upstream", l.Upstream),
+			attribute.Bool("reused", o.ConnReused),
+		))
+	}
+	if o.DNSDuration > 0 {
+		m.dnsDuration.Record(ctx, o.DNSDuration.Seconds(), upstream)
+	}
+	if o.ConnectDuration > 0 {
+		m.connectDuration.Record(ctx, o.ConnectDuration.Seconds(), upstream)
+	}
+	if o.TLSDuration > 0 {
+		m.tlsDuration.Record(ctx, o.TLSDuration.Seconds(), upstream)
+	}
+}
@@ -0,0 +1,260 @@
+package clientmanager
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLatencyBuckets are the histogram buckets, in seconds, used by PrometheusMetrics.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics is a MetricsRecorder that keeps the measurements in memory and
+// serves them in the Prometheus text exposition format.
+//
+// Example:
+//
+//	metrics := clientmanager.NewPrometheusMetrics()
+//	http.Handle("/metrics", metrics)
+//
+//	client := clientmanager.New[Balance](
+//	    clientmanager.WithHost("https://core-banking.internal"),
+//	    clientmanager.WithUpstreamName("core-banking"),
+//	    clientmanager.WithMetrics(metrics),
+//	)
+type PrometheusMetrics struct {
+	mu sync.Mutex
+
+	requests        *promFamily
+	errors          *promFamily
+	inFlight        *promFamily
+	connections     *promFamily
+	duration        *promFamily
+	dnsDuration     *promFamily
+	connectDuration *promFamily
+	tlsDuration     *promFamily
+}
+
+// NewPrometheusMetrics creates a PrometheusMetrics with DefaultLatencyBuckets.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return NewPrometheusMetricsWithBuckets(DefaultLatencyBuckets)
+}
+
+// NewPrometheusMetricsWithBuckets creates a PrometheusMetrics with custom histogram buckets in seconds.
+func NewPrometheusMetricsWithBuckets(buckets []float64) *PrometheusMetrics {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	endpointLabels := []string{"upstream", "method", "endpoint"}
+	upstreamLabels := []string{"upstream"}
+
+	return &PrometheusMetrics{
+		requests: newPromFamily("clientmanager_requests_total", "counter",
+			"Total upstream requests by status class.", append(endpointLabels, "status_class"), nil),
+		errors: newPromFamily("clientmanager_errors_total", "counter",
+			"Total upstream requests that failed without a response, by error type.", append(endpointLabels, "error_type"), nil),
+		inFlight: newPromFamily("clientmanager_requests_in_flight", "gauge",
+			"Upstream requests currently waiting for a response.", endpointLabels, nil),
+		connections: newPromFamily("clientmanager_connections_total", "counter",
+			"Connections obtained for upstream requests, by reuse from the idle pool.", []string{"upstream", "reused"}, nil),
+		duration: newPromFamily("clientmanager_request_duration_seconds", "histogram",
+			"Time until the upstream response headers were received.", endpointLabels, buckets),
+		dnsDuration: newPromFamily("clientmanager_dns_duration_seconds", "histogram",
+			"DNS lookup time for new upstream connections.", upstreamLabels, buckets),
+		connectDuration: newPromFamily("clientmanager_connect_duration_seconds", "histogram",
+			"Dial time for new upstream connections.", upstreamLabels, buckets),
+		tlsDuration: newPromFamily("clientmanager_tls_handshake_duration_seconds", "histogram",
+			"TLS handshake time for new upstream connections.", upstreamLabels, buckets),
+	}
+}
+
+func (m *PrometheusMetrics) RequestStarted(labels MetricLabels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight.add(1, labels.Upstream, labels.Method, labels.Endpoint)
+}
+
+func (m *PrometheusMetrics) RequestFinished(o RequestObservation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l := o.Labels
+	m.inFlight.add(-1, l.Upstream, l.Method, l.Endpoint)
+	m.requests.add(1, l.Upstream, l.Method, l.Endpoint, o.StatusClass())
+	m.duration.observe(o.Duration.Seconds(), l.Upstream, l.Method, l.Endpoint)
+	if o.ErrorType != "" {
+		m.errors.add(1, l.Upstream, l.Method, l.Endpoint, o.ErrorType)
+	}
+	if o.ConnObtained {
+		m.connections.add(1, l.Upstream, strconv.FormatBool(o.ConnReused))
+	}
+	if o.DNSDuration > 0 {
+		m.dnsDuration.observe(o.DNSDuration.Seconds(), l.Upstream)
+	}
+	if o.ConnectDuration > 0 {
+		m.connectDuration.observe(o.ConnectDuration.Seconds(), l.Upstream)
+	}
+	if o.TLSDuration > 0 {
+		m.tlsDuration.observe(o.TLSDuration.Seconds(), l.Upstream)
+	}
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format.
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	for _, family := range []*promFamily{
+		m.requests, m.errors, m.inFlight, m.connections,
+		m.duration, m.dnsDuration, m.connectDuration, m.tlsDuration,
+	} {
+		family.write(cw)
+	}
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP serves the metrics so PrometheusMetrics can be mounted as a scrape endpoint.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = m.WriteTo(w)
+}
+
+type promSeries struct {
+	labelValues []string
+	value       float64
+	buckets     []uint64
+	sum         float64
+	count       uint64
+}
+
+type promFamily struct {
+	name, kind, help string
+	labelNames       []string
+	buckets          []float64
+	series           map[string]*promSeries
+}
+
+func newPromFamily(name, kind, help string, labelNames []string, buckets []float64) *promFamily {
+	return &promFamily{
+		name:       name,
+		kind:       kind,
+		help:       help,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*promSeries),
+	}
+}
+
+func (f *promFamily) get(labelValues []string) *promSeries {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &promSeries{labelValues: labelValues}
+		if f.buckets != nil {
+			s.buckets = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *promFamily) add(delta float64, labelValues ...string) {
+	f.get(labelValues).value += delta
+}
+
+func (f *promFamily) observe(value float64, labelValues ...string) {
+	s := f.get(labelValues)
+	for i, bound := range f.buckets {
+		if value <= bound {
+			s.buckets[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+func (f *promFamily) write(w *countingWriter) {
+	if len(f.series) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w.printf("# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+	for _, key := range keys {
+		s := f.series[key]
+		labels := formatPromLabels(f.labelNames, s.labelValues)
+		if f.kind != "histogram" {
+			w.printf("%s%s %s\n", f.name, wrapPromLabels(labels), formatPromFloat(s.value))
+			continue
+		}
+		for i, bound := range f.buckets {
+			w.printf("%s_bucket%s %d\n", f.name, wrapPromLabels(joinPromLabels(labels, `le="`+formatPromFloat(bound)+`"`)), s.buckets[i])
+		}
+		w.printf("%s_bucket%s %d\n", f.name, wrapPromLabels(joinPromLabels(labels, `le="+Inf"`)), s.count)
+		w.printf("%s_sum%s %s\n", f.name, wrapPromLabels(labels), formatPromFloat(s.sum))
+		w.printf("%s_count%s %d\n", f.name, wrapPromLabels(labels), s.count)
+	}
+}
+
+func formatPromLabels(names, values []string) string {
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapePromLabelValue(values[i]) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func joinPromLabels(labels, extra string) string {
+	if labels == "" {
+		return extra
+	}
+	return labels + "," + extra
+}
+
+func wrapPromLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+var promLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapePromLabelValue(value string) string {
+	return promLabelReplacer.Replace(value)
+}
+
+func formatPromFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// countingWriter keeps the first write error and the number of bytes written.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) printf(format string, args ...any) {
+	if c.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(c.w, format, args...)
+	c.n += int64(n)
+	c.err = err
+}
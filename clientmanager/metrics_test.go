@@ -0,0 +1,180 @@
+package clientmanager_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/clientmanager"
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+type recorderSpy struct {
+	mu           sync.Mutex
+	started      []clientmanager.MetricLabels
+	observations []clientmanager.RequestObservation
+}
+
+func (r *recorderSpy) RequestStarted(labels clientmanager.MetricLabels) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started = append(r.started, labels)
+}
+
+func (r *recorderSpy) RequestFinished(o clientmanager.RequestObservation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observations = append(r.observations, o)
+}
+
+func TestWithMetrics(t *testing.T) {
+	app := logmanager.NewApplication()
+	txn := app.Start("test", "cli", logmanager.TxnTypeOther)
+	ctx := txn.ToContext(context.Background())
+	defer txn.End()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	t.Run("labels and connection reuse", func(t *testing.T) {
+		spy := &recorderSpy{}
+		for i := 0; i < 2; i++ {
+			_, err := clientmanager.Call[string](ctx, "/users/{id}",
+				clientmanager.WithHost(ts.URL),
+				clientmanager.WithPathParams(map[string]string{"id": "42"}),
+				clientmanager.WithUpstreamName("users"),
+				clientmanager.WithMetrics(spy),
+			)
+			assert.NoError(t, err)
+		}
+
+		assert.Len(t, spy.started, 2)
+		assert.Len(t, spy.observations, 2)
+		o := spy.observations[1]
+		assert.Equal(t, clientmanager.MetricLabels{Upstream: "users", Method: http.MethodGet, Endpoint: "/users/{id}"}, o.Labels)
+		assert.Equal(t, http.StatusNotFound, o.StatusCode)
+		assert.Equal(t, "4xx", o.StatusClass())
+		assert.Empty(t, o.ErrorType)
+		assert.True(t, o.ConnObtained)
+		assert.True(t, o.ConnReused)
+	})
+
+	t.Run("upstream defaults to host", func(t *testing.T) {
+		spy := &recorderSpy{}
+		_, err := clientmanager.Call[string](ctx, "/", clientmanager.WithHost(ts.URL), clientmanager.WithMetrics(spy))
+
+		assert.NoError(t, err)
+		assert.Equal(t, strings.TrimPrefix(ts.URL, "http://"), spy.observations[0].Labels.Upstream)
+	})
+
+	t.Run("error type", func(t *testing.T) {
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer slow.Close()
+
+		spy := &recorderSpy{}
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		_, err := clientmanager.Call[string](timeoutCtx, "/", clientmanager.WithHost(slow.URL), clientmanager.WithMetrics(spy))
+
+		assert.Error(t, err)
+		assert.Equal(t, clientmanager.ErrorTypeTimeout, spy.observations[0].ErrorType)
+		assert.Equal(t, "error", spy.observations[0].StatusClass())
+	})
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	metrics := clientmanager.NewPrometheusMetricsWithBuckets([]float64{1, 0.1})
+	labels := clientmanager.MetricLabels{Upstream: "core", Method: http.MethodPost, Endpoint: `/a"b`}
+
+	metrics.RequestStarted(labels)
+	metrics.RequestFinished(clientmanager.RequestObservation{
+		Labels:       labels,
+		StatusCode:   http.StatusOK,
+		Duration:     50 * time.Millisecond,
+		ConnObtained: true,
+		DNSDuration:  time.Millisecond,
+	})
+	metrics.RequestStarted(labels)
+	metrics.RequestFinished(clientmanager.RequestObservation{
+		Labels:    labels,
+		ErrorType: clientmanager.ErrorTypeConnection,
+		Duration:  500 * time.Millisecond,
+	})
+
+	var buf bytes.Buffer
+	_, err := metrics.WriteTo(&buf)
+	assert.NoError(t, err)
+
+	out := buf.String()
+	l := `upstream="core",method="POST",endpoint="/a\"b"`
+	assert.Contains(t, out, "# TYPE clientmanager_requests_total counter\n")
+	assert.Contains(t, out, `clientmanager_requests_total{`+l+`,status_class="2xx"} 1`)
+	assert.Contains(t, out, `clientmanager_requests_total{`+l+`,status_class="error"} 1`)
+	assert.Contains(t, out, `clientmanager_errors_total{`+l+`,error_type="connection"} 1`)
+	assert.Contains(t, out, `clientmanager_requests_in_flight{`+l+`} 0`)
+	assert.Contains(t, out, `clientmanager_connections_total{upstream="core",reused="false"} 1`)
+	assert.Contains(t, out, `clientmanager_request_duration_seconds_bucket{`+l+`,le="0.1"} 1`)
+	assert.Contains(t, out, `clientmanager_request_duration_seconds_bucket{`+l+`,le="1"} 2`)
+	assert.Contains(t, out, `clientmanager_request_duration_seconds_bucket{`+l+`,le="+Inf"} 2`)
+	assert.Contains(t, out, `clientmanager_request_duration_seconds_count{`+l+`} 2`)
+	assert.Contains(t, out, `clientmanager_dns_duration_seconds_count{upstream="core"} 1`)
+	assert.NotContains(t, out, "clientmanager_tls_handshake_duration_seconds")
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, out, rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+}
+
+func TestOTelMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	recorder, err := clientmanager.NewOTelMetrics(provider.Meter("clientmanager"))
+	assert.NoError(t, err)
+
+	labels := clientmanager.MetricLabels{Upstream: "core", Method: http.MethodGet, Endpoint: "/balance"}
+	recorder.RequestStarted(labels)
+	recorder.RequestFinished(clientmanager.RequestObservation{
+		Labels:       labels,
+		StatusCode:   http.StatusBadGateway,
+		Duration:     time.Second,
+		ConnObtained: true,
+		ConnReused:   true,
+		TLSDuration:  time.Millisecond,
+	})
+
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+
+	names := map[string]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			names[m.Name] = true
+			if m.Name == "clientmanager.requests" {
+				sum := m.Data.(metricdata.Sum[int64])
+				assert.Equal(t, int64(1), sum.DataPoints[0].Value)
+				class, _ := sum.DataPoints[0].Attributes.Value("status_class")
+				assert.Equal(t, "5xx", class.AsString())
+			}
+		}
+	}
+	assert.True(t, names["clientmanager.request.duration"])
+	assert.True(t, names["clientmanager.requests.in_flight"])
+	assert.True(t, names["clientmanager.connections"])
+	assert.True(t, names["clientmanager.tls_handshake.duration"])
+	assert.False(t, names["clientmanager.errors"])
+}
@@ -1,6 +1,7 @@
 package clientmanager
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"io"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/Azure/go-ntlmssp"
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
 	"github.com/icholy/digest"
 )
 
@@ -210,6 +212,55 @@ func WithURLValues(urlValues url.Values) Option {
 	}
 }
 
+// WithPathParams fills the `{name}` placeholders of the endpoint template, escaping each value.
+// params may be a map[string]string, a map[string]any, or a struct (or a pointer to one) whose
+// fields carry `path:"name"` tags. time.Time fields accept a `layout:"..."` tag.
+//
+// The logmanager segment is named after the unexpanded template.
+//
+// Example:
+//
+//	clientmanager.Call[Order](ctx, "/users/{id}/orders/{orderId}",
+//	    clientmanager.WithPathParams(map[string]string{"id": "42", "orderId": "a/b"}),
+//	)
+func WithPathParams(params any) Option {
+	return func(co *callOptions) {
+		values, err := encodePathParams(params)
+		if err != nil {
+			co.paramsErr = err
+			return
+		}
+		co.pathParams = values
+	}
+}
+
+// WithQuery encodes a struct with `query:"name"` tags into the request query string.
+// It also accepts url.Values and map[string]string. The values are merged with WithURLValues.
+//
+// Supported tag options are `omitempty` and `comma` (joins a slice into one comma-separated
+// value instead of repeating the key). time.Time fields accept a `layout:"..."` tag, which
+// defaults to RFC 3339 and also understands "unix" and "unixmilli". Embedded structs are flattened.
+//
+// Example:
+//
+//	type ListOrders struct {
+//	    Status []string  `query:"status,omitempty"`
+//	    From   time.Time `query:"from,omitempty" layout:"2006-01-02"`
+//	    Page   int       `query:"page"`
+//	}
+//
+//	clientmanager.WithQuery(ListOrders{Status: []string{"paid"}, Page: 1})
+func WithQuery(query any) Option {
+	return func(co *callOptions) {
+		values, err := encodeQuery(query)
+		if err != nil {
+			co.paramsErr = err
+			return
+		}
+		co.query = values
+	}
+}
+
 func WithTimeout(timeout time.Duration) Option {
 	return func(co *callOptions) {
 		co.client.Timeout = timeout
@@ -297,6 +348,68 @@ func WithDialerControl(control func(network, address string, c syscall.RawConn)
 	}
 }
 
+// DialFunc dials the connection for a request, like net.Dialer.DialContext.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// WithDialer replaces the transport dialer, for example to reach a target through an
+// in-process tunnel. It takes precedence over WithUnixSocket, WithDialContext and
+// WithDialerControl, and reaches the underlying transport through the auth wrappers.
+func WithDialer(dial DialFunc) Option {
+	return func(co *callOptions) {
+		co.dialer = dial
+	}
+}
+
+// WithUnixSocket sends the requests over the Unix domain socket at path instead of TCP,
+// for sidecars such as a local auth agent or the Envoy admin API. The host of the URL
+// is then only used for the Host header; it defaults to "localhost" when WithHost is not
+// set and the endpoint is a path. The socket path is logged as the segment host and used
+// as the default metrics upstream. Environment proxies are not used.
+//
+// Like WithProxy, this changes the client transport, so prefer it with New.
+//
+// Example:
+//
+//	agent := clientmanager.New[Token](clientmanager.WithUnixSocket("/var/run/auth-agent.sock"))
+//	res, err := agent.Call(ctx, "/v1/token")
+func WithUnixSocket(path string) Option {
+	return func(co *callOptions) {
+		co.unixSocket = path
+	}
+}
+
+// WithMaskingConfig masks the JSON request body logged for the segment. JSONPath
+// expressions are relative to the request body, or to the variables for CallGraphQL.
+//
+// Example:
+//
+//	clientmanager.WithMaskingConfig([]logmanager.MaskingConfig{
+//	    {JSONPath: "$.pin", Type: logmanager.FullMask},
+//	    {FieldPattern: "card", Type: logmanager.PartialMask, ShowFirst: 4, ShowLast: 4},
+//	})
+func WithMaskingConfig(configs []logmanager.MaskingConfig) Option {
+	return func(co *callOptions) {
+		co.maskingConfigs = configs
+	}
+}
+
+// WithOperationName sets the GraphQL operation name sent by CallGraphQL and used as the
+// segment name. By default, it is the name of the first named operation in the query.
+func WithOperationName(name string) Option {
+	return func(co *callOptions) {
+		co.operationName = name
+	}
+}
+
+// WithPersistedQuery makes CallGraphQL send the SHA-256 hash of the query (automatic
+// persisted queries) instead of the query. When the server reports the hash unknown,
+// the request is repeated with the full query so the server can register it.
+func WithPersistedQuery() Option {
+	return func(co *callOptions) {
+		co.persistedQuery = true
+	}
+}
+
 // WithMaxResponseBytes limits the response body size read by Call and
 // CallBytes. Bodies larger than the limit are truncated at the limit.
 func WithMaxResponseBytes(max int64) Option {
@@ -305,6 +418,38 @@ func WithMaxResponseBytes(max int64) Option {
 	}
 }
 
+// WithMetrics records request count, latency, in-flight requests, status classes, error types
+// and connection-pool statistics on the given recorder. Use NewPrometheusMetrics or
+// NewOTelMetrics, or implement MetricsRecorder for another backend.
+func WithMetrics(recorder MetricsRecorder) Option {
+	return func(co *callOptions) {
+		co.metrics = recorder
+	}
+}
+
+// WithUpstreamName sets the upstream label used by WithMetrics. It defaults to the request host.
+func WithUpstreamName(name string) Option {
+	return func(co *callOptions) {
+		co.upstreamName = name
+	}
+}
+
+// WithHedging sends another identical request when no response has arrived within delay,
+// up to maxHedges extra requests. The first successful (non-5xx) response is used and the
+// other requests are cancelled.
+//
+// Hedging only applies to idempotent methods (GET, HEAD, OPTIONS, TRACE, PUT, DELETE) and
+// is skipped when the body is set through WithBodyReader, because a reader cannot be replayed.
+// Each attempt is logged as its own segment tagged with its attempt number; the winner is
+// tagged "hedge:won", the others "hedge:cancelled" or "hedge:failed", and only the returned
+// error is logged as an error.
+func WithHedging(delay time.Duration, maxHedges int) Option {
+	return func(co *callOptions) {
+		co.hedgeDelay = delay
+		co.maxHedges = maxHedges
+	}
+}
+
 func WithProxy(proxyURL string) (Option, error) {
 	anURL, err := url.Parse(proxyURL)
 	if err != nil {
@@ -332,6 +477,41 @@ func WithCertificates(certificates ...tls.Certificate) Option {
 	}
 }
 
+// WithCertificateFiles loads a client certificate pair, and an optional CA bundle (caPath may be
+// empty), from files. The pair is re-read when the files change, so rotated mTLS certificates
+// are used without a restart. It returns an error when the files cannot be loaded initially.
+//
+// Use CertificateFiles and WithCertificateReloader to tune the check interval and expiry
+// warning, or to expose the certificate expiry to health checks.
+func WithCertificateFiles(certPath, keyPath, caPath string) (Option, error) {
+	reloader, err := CertificateFiles{CertFile: certPath, KeyFile: keyPath, CAFile: caPath}.Reloader()
+	if err != nil {
+		return nil, err
+	}
+	return WithCertificateReloader(reloader), nil
+}
+
+// WithCertificateReloader serves the client certificate through reloader.GetClientCertificate.
+// When the reloader has a CA file, the server is verified against it instead of the system roots.
+func WithCertificateReloader(reloader *CertificateReloader) Option {
+	return func(co *callOptions) {
+		tr, ok := co.client.Transport.(*http.Transport)
+		if !ok {
+			return
+		}
+		if tr.TLSClientConfig == nil {
+			tr.TLSClientConfig = &tls.Config{
+				MinVersion: tls.VersionTLS12, // #nosec G402 - TLS 1.2+ required
+			}
+		}
+		tr.TLSClientConfig.Certificates = nil
+		tr.TLSClientConfig.GetClientCertificate = reloader.GetClientCertificate
+		if reloader.roots != nil {
+			tr.TLSClientConfig.RootCAs = reloader.roots
+		}
+	}
+}
+
 func WithRootCertificate(rootCertificate *x509.CertPool) Option {
 	return func(co *callOptions) {
 		if _, ok := co.client.Transport.(*http.Transport); ok {
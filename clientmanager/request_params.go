@@ -0,0 +1,290 @@
+package clientmanager
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	pathTag   = "path"
+	queryTag  = "query"
+	layoutTag = "layout"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// tagOptions holds the comma-separated options following the name in a `path` or `query` tag.
+type tagOptions struct {
+	omitEmpty bool
+	comma     bool
+}
+
+func parseTag(tag string) (string, tagOptions) {
+	name, rest, _ := strings.Cut(tag, ",")
+	var opts tagOptions
+	for _, opt := range strings.Split(rest, ",") {
+		switch opt {
+		case "omitempty":
+			opts.omitEmpty = true
+		case "comma":
+			opts.comma = true
+		}
+	}
+	return name, opts
+}
+
+// walkTaggedFields calls fn for every exported field of v carrying the given tag.
+// Anonymous embedded structs without a tag are flattened into the parent.
+func walkTaggedFields(v reflect.Value, tagName string, fn func(name string, opts tagOptions, field reflect.StructField, value reflect.Value) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+		tag, hasTag := field.Tag.Lookup(tagName)
+		if tag == "-" {
+			continue
+		}
+		if !hasTag && field.Anonymous {
+			for value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					break
+				}
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct && value.Type() != timeType {
+				if err := walkTaggedFields(value, tagName, fn); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !hasTag || !field.IsExported() {
+			continue
+		}
+		name, opts := parseTag(tag)
+		if name == "" {
+			name = field.Name
+		}
+		if err := fn(name, opts, field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indirectStruct dereferences pointers and reports whether the result is a struct.
+func indirectStruct(params any) (reflect.Value, bool) {
+	v := reflect.ValueOf(params)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.Kind() == reflect.Struct
+}
+
+// formatParamValue converts a scalar value into its string representation.
+// time.Time values use layout (RFC 3339 by default, or "unix" / "unixmilli" for epoch values).
+func formatParamValue(v reflect.Value, layout string) (string, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		switch layout {
+		case "":
+			return t.Format(time.RFC3339), nil
+		case "unix":
+			return strconv.FormatInt(t.Unix(), 10), nil
+		case "unixmilli":
+			return strconv.FormatInt(t.UnixMilli(), 10), nil
+		default:
+			return t.Format(layout), nil
+		}
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return string(text), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+
+	return "", fmt.Errorf("unsupported parameter type %s", v.Type())
+}
+
+// encodePathParams converts a map or a struct with `path` tags into path parameter values.
+func encodePathParams(params any) (map[string]string, error) {
+	switch p := params.(type) {
+	case nil:
+		return nil, nil
+	case map[string]string:
+		return p, nil
+	case map[string]any:
+		values := make(map[string]string, len(p))
+		for name, value := range p {
+			s, err := formatParamValue(reflect.ValueOf(value), "")
+			if err != nil {
+				return nil, fmt.Errorf("path parameter %q: %w", name, err)
+			}
+			values[name] = s
+		}
+		return values, nil
+	}
+
+	v, ok := indirectStruct(params)
+	if !ok {
+		return nil, fmt.Errorf("path parameters must be a map or a struct, got %T", params)
+	}
+
+	values := make(map[string]string)
+	err := walkTaggedFields(v, pathTag, func(name string, _ tagOptions, field reflect.StructField, value reflect.Value) error {
+		s, err := formatParamValue(value, field.Tag.Get(layoutTag))
+		if err != nil {
+			return fmt.Errorf("path parameter %q: %w", name, err)
+		}
+		values[name] = s
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// encodeQuery converts url.Values, a map or a struct with `query` tags into query values.
+//
+// Struct fields support the `omitempty` option, slices (repeated keys, or a single
+// comma-separated value with the `comma` option), time.Time with a `layout` tag,
+// pointers and embedded structs.
+func encodeQuery(query any) (url.Values, error) {
+	switch q := query.(type) {
+	case nil:
+		return nil, nil
+	case url.Values:
+		return q, nil
+	case map[string]string:
+		values := make(url.Values, len(q))
+		for name, value := range q {
+			values.Set(name, value)
+		}
+		return values, nil
+	}
+
+	v, ok := indirectStruct(query)
+	if !ok {
+		return nil, fmt.Errorf("query must be url.Values, a map or a struct, got %T", query)
+	}
+
+	values := make(url.Values)
+	err := walkTaggedFields(v, queryTag, func(name string, opts tagOptions, field reflect.StructField, value reflect.Value) error {
+		if opts.omitEmpty && value.IsZero() {
+			return nil
+		}
+		layout := field.Tag.Get(layoutTag)
+
+		for value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return nil
+			}
+			value = value.Elem()
+		}
+
+		if (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) && value.Type().Elem().Kind() != reflect.Uint8 {
+			items := make([]string, 0, value.Len())
+			for i := 0; i < value.Len(); i++ {
+				s, err := formatParamValue(value.Index(i), layout)
+				if err != nil {
+					return fmt.Errorf("query parameter %q: %w", name, err)
+				}
+				items = append(items, s)
+			}
+			if opts.omitEmpty && len(items) == 0 {
+				return nil
+			}
+			if opts.comma {
+				values.Add(name, strings.Join(items, ","))
+				return nil
+			}
+			for _, item := range items {
+				values.Add(name, item)
+			}
+			return nil
+		}
+
+		s, err := formatParamValue(value, layout)
+		if err != nil {
+			return fmt.Errorf("query parameter %q: %w", name, err)
+		}
+		values.Add(name, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// expandPath replaces every `{name}` placeholder in template with its escaped value.
+func expandPath(template string, params map[string]string) (string, error) {
+	if !strings.Contains(template, "{") {
+		return template, nil
+	}
+
+	var b strings.Builder
+	rest := template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			b.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed path parameter in %q", template)
+		}
+		end += start
+
+		name := rest[start+1 : end]
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("missing path parameter %q in %q", name, template)
+		}
+
+		b.WriteString(rest[:start])
+		b.WriteString(url.PathEscape(value))
+		rest = rest[end+1:]
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,166 @@
+package clientmanager_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/clientmanager"
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/stretchr/testify/assert"
+)
+
+type pagination struct {
+	Page  int `query:"page"`
+	Limit int `query:"limit,omitempty"`
+}
+
+type listOrdersQuery struct {
+	pagination
+	Status  []string   `query:"status,omitempty"`
+	IDs     []int      `query:"ids,comma"`
+	From    time.Time  `query:"from,omitempty" layout:"2006-01-02"`
+	Until   *time.Time `query:"until,omitempty"`
+	Keyword string     `query:"q,omitempty"`
+	Ignored string
+}
+
+type orderPath struct {
+	UserID  int    `path:"id"`
+	OrderID string `path:"orderId"`
+}
+
+func TestWithPathParams(t *testing.T) {
+	app := logmanager.NewApplication()
+	txn := app.Start("test", "cli", logmanager.TxnTypeOther)
+	ctx := txn.ToContext(context.Background())
+	defer txn.End()
+
+	var gotPath string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	t.Run("map", func(t *testing.T) {
+		res, err := clientmanager.Call[string](ctx, "/users/{id}/orders/{orderId}",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithPathParams(map[string]string{"id": "42", "orderId": "a/b c"}),
+		)
+
+		assert.NoError(t, err)
+		assert.True(t, res.IsSuccess())
+		assert.Equal(t, "/users/42/orders/a%2Fb%20c", gotPath)
+	})
+
+	t.Run("struct", func(t *testing.T) {
+		_, err := clientmanager.Call[string](ctx, "/users/{id}/orders/{orderId}",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithPathParams(&orderPath{UserID: 7, OrderID: "ORD-1"}),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "/users/7/orders/ORD-1", gotPath)
+	})
+
+	t.Run("missing parameter", func(t *testing.T) {
+		_, err := clientmanager.Call[string](ctx, "/users/{id}/orders/{orderId}",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithPathParams(map[string]any{"id": 1}),
+		)
+
+		assert.ErrorContains(t, err, `missing path parameter "orderId"`)
+	})
+
+	t.Run("unclosed placeholder", func(t *testing.T) {
+		_, err := clientmanager.Call[string](ctx, "/users/{id",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithPathParams(map[string]string{"id": "1"}),
+		)
+
+		assert.ErrorContains(t, err, "unclosed path parameter")
+	})
+
+	t.Run("invalid type", func(t *testing.T) {
+		_, err := clientmanager.Call[string](ctx, "/users/{id}",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithPathParams("id"),
+		)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestWithQuery(t *testing.T) {
+	app := logmanager.NewApplication()
+	txn := app.Start("test", "cli", logmanager.TxnTypeOther)
+	ctx := txn.ToContext(context.Background())
+	defer txn.End()
+
+	var gotQuery url.Values
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	t.Run("struct", func(t *testing.T) {
+		until := time.Date(2026, 1, 31, 10, 0, 0, 0, time.UTC)
+		_, err := clientmanager.Call[string](ctx, "/orders",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithQuery(listOrdersQuery{
+				pagination: pagination{Page: 2},
+				Status:     []string{"paid", "shipped"},
+				IDs:        []int{1, 2, 3},
+				From:       time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+				Until:      &until,
+				Ignored:    "x",
+			}),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, url.Values{
+			"page":   {"2"},
+			"status": {"paid", "shipped"},
+			"ids":    {"1,2,3"},
+			"from":   {"2026-01-01"},
+			"until":  {"2026-01-31T10:00:00Z"},
+		}, gotQuery)
+	})
+
+	t.Run("omitempty", func(t *testing.T) {
+		_, err := clientmanager.Call[string](ctx, "/orders",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithQuery(&listOrdersQuery{}),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, url.Values{"page": {"0"}, "ids": {""}}, gotQuery)
+	})
+
+	t.Run("merged with URL values", func(t *testing.T) {
+		_, err := clientmanager.Call[string](ctx, "/orders",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithURLValues(url.Values{"limit": {"10"}}),
+			clientmanager.WithQuery(map[string]string{"q": "a&b"}),
+		)
+
+		assert.NoError(t, err)
+		assert.Equal(t, url.Values{"limit": {"10"}, "q": {"a&b"}}, gotQuery)
+	})
+
+	t.Run("unsupported field type", func(t *testing.T) {
+		_, err := clientmanager.Call[string](ctx, "/orders",
+			clientmanager.WithHost(ts.URL),
+			clientmanager.WithQuery(struct {
+				Filter map[string]string `query:"filter"`
+			}{Filter: map[string]string{"a": "b"}}),
+		)
+
+		assert.ErrorContains(t, err, `query parameter "filter"`)
+	})
+}
@@ -0,0 +1,48 @@
+package clientmanager
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ResponseError is returned for an unexpected response status. Body holds the decoded
+// error payload; Raw keeps the original bytes when decoding was not possible.
+type ResponseError[T any] struct {
+	StatusCode int
+	Header     http.Header
+	Body       T
+	Raw        []byte
+}
+
+func (e *ResponseError[T]) Error() string {
+	return fmt.Sprintf("clientmanager: unexpected status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// DecodeResponse decodes the raw body of a CallBytes response into Response,
+// following the Content-Type like Call does.
+func DecodeResponse[Response any](res *BaseResponse[[]byte]) (*BaseResponse[Response], error) {
+	body, err := getResponseBody[Response](res.Body, res.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &BaseResponse[Response]{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+		Raw:        res.Body,
+	}, nil
+}
+
+// NewResponseError wraps a CallBytes response into a ResponseError, decoding the body into T.
+// The body is left empty when it cannot be decoded.
+func NewResponseError[T any](res *BaseResponse[[]byte]) *ResponseError[T] {
+	body, _ := getResponseBody[T](res.Body, res.Header.Get("Content-Type"))
+
+	return &ResponseError[T]{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+		Raw:        res.Body,
+	}
+}
@@ -0,0 +1,35 @@
+package eventmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// connections is the connection state of the subscriptions, by queue name.
+var connections sync.Map
+
+func setConnected(queueName string, connected bool) {
+	connections.Store(queueName, connected)
+}
+
+// HealthCheck returns an error naming the subscriptions that are not connected to RabbitMQ, e.g. while
+// they reconnect. It returns nil without subscriptions. Its signature matches the checks of the
+// httpmanager readiness endpoint.
+func HealthCheck(_ context.Context) error {
+	var disconnected []string
+	connections.Range(func(queueName, connected any) bool {
+		if !connected.(bool) {
+			disconnected = append(disconnected, queueName.(string))
+		}
+		return true
+	})
+	if len(disconnected) == 0 {
+		return nil
+	}
+
+	sort.Strings(disconnected)
+	return fmt.Errorf("rabbitmq subscriptions not connected: %s", strings.Join(disconnected, ", "))
+}
@@ -0,0 +1,26 @@
+package eventmanager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheck(t *testing.T) {
+	t.Cleanup(func() { connections.Clear() })
+
+	assert.NoError(t, HealthCheck(context.Background()), "no subscriptions")
+
+	setConnected("orders", true)
+	setConnected("payments", true)
+	assert.NoError(t, HealthCheck(context.Background()))
+
+	setConnected("payments", false)
+	setConnected("invoices", false)
+	assert.EqualError(t, HealthCheck(context.Background()), "rabbitmq subscriptions not connected: invoices, payments")
+
+	setConnected("payments", true)
+	setConnected("invoices", true)
+	assert.NoError(t, HealthCheck(context.Background()), "reconnected")
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/SALT-Indonesia/salt-pkg/logmanager"
@@ -80,24 +82,27 @@ func reconnect[Message any](ctx context.Context, queueName, exchange string, han
 
 func resubscribe[Message any](notify chan *amqp.Error, ctx context.Context, queueName, exchange string, handlers []Handler[Message]) {
 	if err := <-notify; err != nil {
+		setConnected(queueName, false)
 		logmanager.ErrorWithContext(ctx, err)
 
 		reconnect(ctx, queueName, exchange, handlers)
 	}
 }
 
-func channelling[Message any](ctx context.Context, connection *amqp.Connection, queueName, exchange string, handlers []Handler[Message]) {
+// channelling consumes the queue on a new channel of the connection. It returns the error of the setup,
+// already logged, so the subscription is only reported connected once it consumes.
+func channelling[Message any](ctx context.Context, connection *amqp.Connection, queueName, exchange string, handlers []Handler[Message]) error {
 	channel, err := connection.Channel()
 	if err != nil {
 		logmanager.ErrorWithContext(ctx, err)
 
-		return
+		return err
 	}
 
 	if err := channel.Qos(prefetchCount, 0, false); err != nil { // 10 is prefetch count
 		logmanager.ErrorWithContext(ctx, err)
 
-		return
+		return err
 	}
 
 	if err = channel.ExchangeDeclare(
@@ -111,7 +116,7 @@ func channelling[Message any](ctx context.Context, connection *amqp.Connection,
 	); err != nil {
 		logmanager.ErrorWithContext(ctx, err)
 
-		return
+		return err
 	}
 
 	queue, err := channel.QueueDeclare(
@@ -125,31 +130,34 @@ func channelling[Message any](ctx context.Context, connection *amqp.Connection,
 	if err != nil {
 		logmanager.ErrorWithContext(ctx, err)
 
-		return
+		return err
 	}
 
 	if err := channel.QueueBind(queue.Name, exchange, exchange, false, nil); err != nil {
 		logmanager.ErrorWithContext(ctx, err)
 
-		return
+		return err
 	}
 
 	msgs, err := channel.Consume(queue.Name, "", false, false, false, false, nil)
 	if err != nil {
 		logmanager.ErrorWithContext(ctx, err)
 
-		return
+		return err
 	}
 
 	go handleMessages(ctx, msgs, queueName, handlers)
+
+	return nil
 }
 
 func subscribe[Message any](ctx context.Context, queueName, exchange string, handlers []Handler[Message]) {
+	setConnected(queueName, false)
 	connection, err := amqp.Dial(os.Getenv("RABBITMQ_URL"))
 	if err != nil {
 		logmanager.ErrorWithContext(ctx, err)
 
-		reconnect(ctx, exchange, queueName, handlers)
+		reconnect(ctx, queueName, exchange, handlers)
 
 		return
 	}
@@ -157,9 +165,20 @@ func subscribe[Message any](ctx context.Context, queueName, exchange string, han
 	notify := connection.NotifyClose(make(chan *amqp.Error))
 	go resubscribe(notify, ctx, queueName, exchange, handlers)
 
+	// The subscription is connected once every channel consumes the queue
+	var wg sync.WaitGroup
+	var failed atomic.Bool
 	for range channels {
-		go channelling(ctx, connection, queueName, exchange, handlers)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := channelling(ctx, connection, queueName, exchange, handlers); err != nil {
+				failed.Store(true)
+			}
+		}()
 	}
+	wg.Wait()
+	setConnected(queueName, !failed.Load() && !connection.IsClosed())
 
 	<-ctx.Done()
 }
@@ -67,7 +67,7 @@ func main() {
 
 	// static directory for serving images
 	staticDir := "static"
-	server.Handle("/images/", httpmanager.NewStaticHandler(staticDir))
+	server.Handle("/images/{path:.*}", httpmanager.NewStaticHandler(staticDir))
 
 	log.Println("Health check endpoint: GET http://localhost:8080/health")
 	log.Println("")
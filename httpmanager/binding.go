@@ -0,0 +1,455 @@
+package httpmanager
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Binding sources of the request fields, set with the struct tag of the same name.
+const (
+	bindPath   = "path"
+	bindQuery  = "query"
+	bindHeader = "header"
+	bindCookie = "cookie"
+)
+
+var bindSources = []string{bindPath, bindQuery, bindHeader, bindCookie}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// BindError is returned when a path, query, header or cookie value cannot be converted
+// to the type of its request field.
+type BindError struct {
+	// Source is where the value comes from: "path", "query", "header" or "cookie".
+	Source string `json:"source"`
+	// Name is the name of the parameter, e.g. "page" or "filter.status".
+	Name string `json:"name"`
+	// Value is the value that failed to convert.
+	Value string `json:"value"`
+	Err   error  `json:"-"`
+}
+
+// Error implements the error interface
+func (e *BindError) Error() string {
+	return fmt.Sprintf("invalid %s parameter %q: %v", e.Source, e.Name, e.Err)
+}
+
+// Unwrap returns the conversion error
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// bindField is a request field bound from a path, query, header or cookie value.
+type bindField struct {
+	index        []int
+	source       string
+	name         string
+	layout       string
+	defaultValue *string
+	// nested binds a struct field from the query keys prefixed with the field name, e.g. filter[status].
+	nested *binder
+	// isMap binds a map field from the query keys prefixed with the field name.
+	isMap bool
+}
+
+// binder binds the request parameters to a struct type. Binders are built once per type.
+type binder struct {
+	params []bindField
+	// bodyDefaults are the default values of the fields decoded from the JSON body.
+	bodyDefaults []bindField
+}
+
+var binders sync.Map // map[reflect.Type]*binder
+
+// binderFor returns the cached binder of the type; non-struct types get an empty binder.
+func binderFor(t reflect.Type) *binder {
+	if cached, ok := binders.Load(t); ok {
+		return cached.(*binder)
+	}
+	b := buildBinder(t, map[reflect.Type]*binder{})
+	cached, _ := binders.LoadOrStore(t, b)
+	return cached.(*binder)
+}
+
+// buildBinder builds the binder of t. building holds the binders being built, for recursive types.
+func buildBinder(t reflect.Type, building map[reflect.Type]*binder) *binder {
+	if b, ok := building[t]; ok {
+		return b
+	}
+	b := &binder{}
+	building[t] = b
+	if t != nil && t.Kind() == reflect.Struct {
+		b.collect(t, nil, building)
+	}
+	return b
+}
+
+// parseBindTag returns the binding source and parameter name of the field, and the tag options.
+func parseBindTag(field reflect.StructField) (source, name, options string) {
+	for _, source := range bindSources {
+		name, options, _ := strings.Cut(field.Tag.Get(source), ",")
+		if name != "" && name != "-" {
+			return source, name, options
+		}
+	}
+	return "", "", ""
+}
+
+// collect adds the tagged fields of t, including the fields of embedded structs.
+func (b *binder) collect(t reflect.Type, index []int, building map[reflect.Type]*binder) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			b.collect(field.Type, fieldIndex, building)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		bf := bindField{index: fieldIndex}
+		var options string
+		bf.source, bf.name, options = parseBindTag(field)
+		for _, option := range strings.Split(options, ",") {
+			if layout, ok := strings.CutPrefix(option, "layout="); ok {
+				bf.layout = layout
+			}
+		}
+		if value, ok := field.Tag.Lookup("default"); ok {
+			bf.defaultValue = &value
+		}
+
+		switch {
+		case bindable(field.Type):
+		case bf.source != bindQuery:
+			continue
+		case isNestedStruct(field.Type):
+			bf.nested = buildBinder(indirect(field.Type), building)
+		case field.Type.Kind() == reflect.Map && isScalar(field.Type.Key()) && bindable(field.Type.Elem()):
+			bf.isMap = true
+		default:
+			continue
+		}
+
+		switch {
+		case bf.source != "":
+			b.params = append(b.params, bf)
+		case bf.defaultValue != nil:
+			b.bodyDefaults = append(b.bodyDefaults, bf)
+		}
+	}
+}
+
+// setDefaults sets the default values of the body fields; the decoded body overrides them.
+func (b *binder) setDefaults(v reflect.Value) error {
+	for _, field := range b.bodyDefaults {
+		if _, err := setFieldValue(v.FieldByIndex(field.index), []string{*field.defaultValue}, field.layout); err != nil {
+			return fmt.Errorf("invalid default value of field %s: %w", v.Type().FieldByIndex(field.index).Name, err)
+		}
+	}
+	return nil
+}
+
+// bind sets the path, query, header and cookie fields of v from the request.
+func (b *binder) bind(r *http.Request, v reflect.Value, pathParams PathParams, queryParams QueryParams) error {
+	query := normalizeQuery(queryParams)
+	for _, field := range b.params {
+		if field.source == bindQuery {
+			if err := field.bindQuery(v, query, ""); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := field.set(v.FieldByIndex(field.index), field.lookup(r, pathParams), field.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindQuery sets the query fields of v; prefix is the query key of v when it is a nested struct.
+func (b *binder) bindQuery(v reflect.Value, query QueryParams, prefix string) error {
+	for _, field := range b.params {
+		if field.source != bindQuery {
+			continue
+		}
+		if err := field.bindQuery(v, query, prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindQuery sets the field of v from the query.
+func (f bindField) bindQuery(v reflect.Value, query QueryParams, prefix string) error {
+	key := f.name
+	if prefix != "" {
+		key = prefix + "." + f.name
+	}
+	field := v.FieldByIndex(f.index)
+
+	switch {
+	case f.nested != nil:
+		if !hasQueryPrefix(query, key+".") {
+			return nil
+		}
+		if field.Kind() == reflect.Ptr {
+			if field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			field = field.Elem()
+		}
+		return f.nested.bindQuery(field, query, key)
+	case f.isMap:
+		return f.bindMap(field, query, key)
+	}
+	return f.set(field, query[key], key)
+}
+
+// bindMap sets a map field from the query keys prefixed with key, e.g. filter[status]=active.
+func (f bindField) bindMap(field reflect.Value, query QueryParams, key string) error {
+	var keys []string
+	for queryKey := range query {
+		if mapKey, ok := strings.CutPrefix(queryKey, key+"."); ok && mapKey != "" {
+			keys = append(keys, queryKey)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+
+	mapType := field.Type()
+	m := reflect.MakeMapWithSize(mapType, len(keys))
+	for _, queryKey := range keys {
+		mapKey := reflect.New(mapType.Key()).Elem()
+		if err := setScalarValue(mapKey, strings.TrimPrefix(queryKey, key+"."), ""); err != nil {
+			return &BindError{Source: f.source, Name: queryKey, Value: queryKey, Err: err}
+		}
+		elem := reflect.New(mapType.Elem()).Elem()
+		if failed, err := setFieldValue(elem, query[queryKey], f.layout); err != nil {
+			return &BindError{Source: f.source, Name: queryKey, Value: failed, Err: err}
+		}
+		m.SetMapIndex(mapKey, elem)
+	}
+	field.Set(m)
+	return nil
+}
+
+// set converts the values, or the default value when there are none, and sets the field.
+func (f bindField) set(field reflect.Value, values []string, name string) error {
+	if len(values) == 0 {
+		if f.defaultValue == nil {
+			return nil
+		}
+		values = []string{*f.defaultValue}
+	}
+	if failed, err := setFieldValue(field, values, f.layout); err != nil {
+		return &BindError{Source: f.source, Name: name, Value: failed, Err: err}
+	}
+	return nil
+}
+
+// lookup returns the path, header or cookie values of the field.
+func (f bindField) lookup(r *http.Request, pathParams PathParams) []string {
+	switch f.source {
+	case bindPath:
+		if pathParams.Has(f.name) {
+			return []string{pathParams.Get(f.name)}
+		}
+	case bindHeader:
+		return r.Header.Values(f.name)
+	case bindCookie:
+		if cookie, err := r.Cookie(f.name); err == nil {
+			return []string{cookie.Value}
+		}
+	}
+	return nil
+}
+
+// normalizeQuery rewrites the bracket notation of the query keys to the dot notation,
+// e.g. filter[status] to filter.status, and ids[] to ids.
+func normalizeQuery(query QueryParams) QueryParams {
+	hasBrackets := false
+	for key := range query {
+		if strings.Contains(key, "[") {
+			hasBrackets = true
+			break
+		}
+	}
+	if !hasBrackets {
+		return query
+	}
+
+	normalized := make(QueryParams, len(query))
+	for key, values := range query {
+		key = strings.TrimSuffix(key, "[]")
+		key = strings.ReplaceAll(strings.ReplaceAll(key, "]", ""), "[", ".")
+		normalized[key] = append(normalized[key], values...)
+	}
+	return normalized
+}
+
+func hasQueryPrefix(query QueryParams, prefix string) bool {
+	for key := range query {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// isScalar reports whether setScalarValue supports the type.
+func isScalar(t reflect.Type) bool {
+	if t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// bindable reports whether setFieldValue supports the type.
+func bindable(t reflect.Type) bool {
+	t = indirect(t)
+	if t.Kind() == reflect.Slice && !isScalar(t) {
+		t = indirect(t.Elem())
+	}
+	return isScalar(t)
+}
+
+// splitsOnCommas reports whether slice items of the type are given as comma-separated lists. Strings are
+// not, a comma is part of their value.
+func splitsOnCommas(t reflect.Type) bool {
+	t = indirect(t)
+	return t.Kind() != reflect.String || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// isNestedStruct reports whether the type is a struct, or a pointer to one, bound field by field.
+func isNestedStruct(t reflect.Type) bool {
+	t = indirect(t)
+	return t.Kind() == reflect.Struct && !isScalar(t)
+}
+
+// setFieldValue converts the values to the type of the field and sets it. Slices get every
+// value, split on commas unless the items are strings, without the empty items; other types get
+// the first value. It returns the value that failed.
+func setFieldValue(field reflect.Value, values []string, layout string) (string, error) {
+	switch {
+	case field.Kind() == reflect.Ptr:
+		value := reflect.New(field.Type().Elem())
+		if failed, err := setFieldValue(value.Elem(), values, layout); err != nil {
+			return failed, err
+		}
+		field.Set(value)
+		return "", nil
+	case field.Kind() == reflect.Slice && !isScalar(field.Type()):
+		items := values
+		if splitsOnCommas(field.Type().Elem()) {
+			items = nil
+			for _, value := range values {
+				for _, item := range strings.Split(value, ",") {
+					if item != "" {
+						items = append(items, item)
+					}
+				}
+			}
+		}
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, item := range items {
+			if _, err := setFieldValue(slice.Index(i), []string{item}, layout); err != nil {
+				return item, err
+			}
+		}
+		field.Set(slice)
+		return "", nil
+	}
+	if err := setScalarValue(field, values[0], layout); err != nil {
+		return values[0], err
+	}
+	return "", nil
+}
+
+// setScalarValue converts a single value to the type of the field and sets it. Times are
+// parsed with layout, RFC 3339 by default.
+func setScalarValue(field reflect.Value, value, layout string) error {
+	switch field.Type() {
+	case timeType:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return fmt.Errorf("%q is not a time in the %q layout", value, layout)
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", value)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid %s", value, field.Type())
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid %s", value, field.Type())
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a valid %s", value, field.Type())
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package httpmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/SALT-Indonesia/salt-pkg/httpmanager/internal/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bindingPage struct {
+	Page    int `query:"page" default:"1"`
+	PerPage int `query:"per_page" default:"20" validate:"lte=100"`
+}
+
+type bindingRequest struct {
+	bindingPage
+	ID       int64    `path:"id"`
+	Tags     []string `query:"tag"`
+	MinPrice *float64 `query:"min_price"`
+	ClientID string   `header:"X-Client-Id"`
+	Session  string   `cookie:"session"`
+	Name     string   `json:"name"`
+	Currency string   `json:"currency" default:"IDR"`
+	Limit    uint8    `json:"limit" default:"10"`
+}
+
+func serveBinding(t *testing.T, target, body string, header http.Header) (*httptest.ResponseRecorder, *bindingRequest) {
+	t.Helper()
+	var got *bindingRequest
+	server := NewServer(testdata.NewApplication())
+	server.POST("/products/{id}", NewHandler(http.MethodPost, func(ctx context.Context, req *bindingRequest) (*Response, error) {
+		got = req
+		return &Response{}, nil
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	return rec, got
+}
+
+func TestHandler_Binding(t *testing.T) {
+	t.Run("binds every source in one pass", func(t *testing.T) {
+		header := http.Header{"X-Client-Id": {"mobile"}, "Cookie": {"session=abc"}}
+		rec, got := serveBinding(t, "/products/42?page=3&tag=a&tag=b&min_price=9.5", `{"name":"shoe","limit":0}`, header)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		minPrice := 9.5
+		assert.Equal(t, &bindingRequest{
+			bindingPage: bindingPage{Page: 3, PerPage: 20},
+			ID:          42,
+			Tags:        []string{"a", "b"},
+			MinPrice:    &minPrice,
+			ClientID:    "mobile",
+			Session:     "abc",
+			Name:        "shoe",
+			Currency:    "IDR",
+			Limit:       0,
+		}, got)
+	})
+
+	t.Run("defaults without parameters or body", func(t *testing.T) {
+		rec, got := serveBinding(t, "/products/1", "", nil)
+
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, 1, got.Page)
+		assert.Equal(t, 20, got.PerPage)
+		assert.Equal(t, "IDR", got.Currency)
+		assert.Equal(t, uint8(10), got.Limit)
+		assert.Nil(t, got.MinPrice)
+		assert.Nil(t, got.Tags)
+	})
+
+	t.Run("conversion error names the parameter", func(t *testing.T) {
+		rec, got := serveBinding(t, "/products/1?page=abc", "", nil)
+
+		assert.Nil(t, got)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		var body struct {
+			Code    string      `json:"code"`
+			Message MessageInfo `json:"message"`
+			Data    BindError   `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "400", body.Code)
+		assert.Equal(t, `invalid query parameter "page": "abc" is not a valid int`, body.Message.Desc)
+		assert.Equal(t, BindError{Source: "query", Name: "page", Value: "abc"}, body.Data)
+	})
+
+	t.Run("path conversion error", func(t *testing.T) {
+		rec, _ := serveBinding(t, "/products/x1", "", nil)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `invalid path parameter \"id\"`)
+	})
+
+	t.Run("bound values are validated", func(t *testing.T) {
+		rec, got := serveBinding(t, "/products/1?per_page=500", "", nil)
+
+		assert.Nil(t, got)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"field":"per_page","rule":"lte"`)
+	})
+}
+
+func TestBinderFor(t *testing.T) {
+	t.Run("cached per type", func(t *testing.T) {
+		typ := reflect.TypeOf(bindingRequest{})
+
+		b := binderFor(typ)
+
+		assert.Same(t, b, binderFor(typ))
+		assert.Len(t, b.params, 7)
+		assert.Len(t, b.bodyDefaults, 2)
+	})
+
+	t.Run("non-struct types", func(t *testing.T) {
+		b := binderFor(reflect.TypeOf(""))
+
+		assert.Empty(t, b.params)
+		assert.NoError(t, b.bind(httptest.NewRequest(http.MethodGet, "/", nil), reflect.ValueOf(new(string)).Elem(), nil, nil))
+	})
+
+	t.Run("unsupported types are skipped", func(t *testing.T) {
+		type request struct {
+			Value  interface{}       `query:"value"`
+			Labels map[string]string `header:"X-Labels"`
+			Name   string            `query:"name"`
+		}
+
+		b := binderFor(reflect.TypeOf(request{}))
+
+		require.Len(t, b.params, 1)
+		assert.Equal(t, "name", b.params[0].name)
+	})
+}
+
+func BenchmarkHandler_Binding(b *testing.B) {
+	handler := NewHandler(http.MethodGet, func(ctx context.Context, req *bindingRequest) (*Response, error) {
+		return &Response{}, nil
+	})
+	req := httptest.NewRequest(http.MethodGet, "/products?page=2&tag=a&tag=b", nil)
+	req.Header.Set("X-Client-Id", "mobile")
+
+	b.ReportAllocs()
+	for b.Loop() {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
@@ -0,0 +1,436 @@
+package httpmanager
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/vmihailenco/msgpack/v5"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Codec decodes request bodies and encodes response bodies of a media type.
+type Codec interface {
+	// ContentType is the media type of the codec, sent as the Content-Type of the responses.
+	ContentType() string
+	// Decode decodes the body into v, a pointer to the request.
+	Decode(r io.Reader, v any) error
+	// Encode writes v as the body.
+	Encode(w io.Writer, v any) error
+}
+
+// JSONCodec encodes and decodes application/json bodies. It is the default codec.
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string { return "application/json" }
+
+func (JSONCodec) Decode(r io.Reader, v any) error { return json.NewDecoder(r).Decode(v) }
+
+func (JSONCodec) Encode(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) }
+
+// XMLCodec encodes and decodes application/xml bodies with the `xml` tags. text/xml bodies are decoded too.
+type XMLCodec struct{}
+
+func (XMLCodec) ContentType() string { return "application/xml" }
+
+func (XMLCodec) Decode(r io.Reader, v any) error { return xml.NewDecoder(r).Decode(v) }
+
+func (XMLCodec) Encode(w io.Writer, v any) error { return xml.NewEncoder(w).Encode(v) }
+
+// MsgpackCodec encodes and decodes application/msgpack bodies. Fields are named after their `json` tags,
+// so a request or response has the same field names in JSON and MessagePack.
+// application/x-msgpack and application/vnd.msgpack bodies are decoded too.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) ContentType() string { return "application/msgpack" }
+
+func (MsgpackCodec) Decode(r io.Reader, v any) error {
+	decoder := msgpack.NewDecoder(r)
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(v)
+}
+
+func (MsgpackCodec) Encode(w io.Writer, v any) error {
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetCustomStructTag("json")
+	return encoder.Encode(v)
+}
+
+// FormCodec encodes and decodes application/x-www-form-urlencoded bodies with the `form` tags.
+// Fields support the types of the query parameters (see BindQueryParams), except nested structs and maps,
+// and the fields of embedded structs are included. Values that do not convert return a *BindError.
+type FormCodec struct{}
+
+func (FormCodec) ContentType() string { return "application/x-www-form-urlencoded" }
+
+func (FormCodec) Decode(r io.Reader, v any) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("form body cannot be decoded into %T", v)
+	}
+	rv = rv.Elem()
+	for _, field := range formFieldsFor(rv.Type()) {
+		if _, ok := values[field.name]; !ok {
+			continue
+		}
+		if failed, err := setFieldValue(rv.FieldByIndex(field.index), values[field.name], field.layout); err != nil {
+			return &BindError{Source: bindForm, Name: field.name, Value: failed, Err: err}
+		}
+	}
+	return nil
+}
+
+func (FormCodec) Encode(w io.Writer, v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("%T cannot be encoded as a form", v)
+	}
+
+	values := url.Values{}
+	for _, field := range formFieldsFor(rv.Type()) {
+		fieldValues, err := formatFieldValue(rv.FieldByIndex(field.index), field.layout)
+		if err != nil {
+			return fmt.Errorf("form field %s: %w", field.name, err)
+		}
+		if len(fieldValues) > 0 {
+			values[field.name] = fieldValues
+		}
+	}
+	_, err := io.WriteString(w, values.Encode())
+	return err
+}
+
+// bindForm is the source of the BindError of a form body field.
+const bindForm = "form"
+
+// formField is a struct field encoded in form bodies.
+type formField struct {
+	index  []int
+	name   string
+	layout string
+}
+
+var formFields sync.Map // map[reflect.Type][]formField
+
+// formFieldsFor returns the cached `form` tagged fields of the struct type.
+func formFieldsFor(t reflect.Type) []formField {
+	if cached, ok := formFields.Load(t); ok {
+		return cached.([]formField)
+	}
+	fields, _ := formFields.LoadOrStore(t, collectFormFields(t, nil))
+	return fields.([]formField)
+}
+
+func collectFormFields(t reflect.Type, index []int) []formField {
+	var fields []formField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, collectFormFields(field.Type, fieldIndex)...)
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get(bindForm), ",")
+		if !field.IsExported() || name == "" || name == "-" || !bindable(field.Type) {
+			continue
+		}
+
+		f := formField{index: fieldIndex, name: name}
+		for _, option := range strings.Split(options, ",") {
+			if layout, ok := strings.CutPrefix(option, "layout="); ok {
+				f.layout = layout
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// formatFieldValue formats the value of a bindable field; nil pointers have no value.
+func formatFieldValue(field reflect.Value, layout string) ([]string, error) {
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		values := make([]string, 0, field.Len())
+		for i := 0; i < field.Len(); i++ {
+			value, err := formatScalarValue(field.Index(i), layout)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil, nil
+		}
+		field = field.Elem()
+	}
+	value, err := formatScalarValue(field, layout)
+	if err != nil {
+		return nil, err
+	}
+	return []string{value}, nil
+}
+
+func formatScalarValue(v reflect.Value, layout string) (string, error) {
+	switch {
+	case v.Type() == timeType:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		return v.Interface().(time.Time).Format(layout), nil
+	case v.Type() == durationType:
+		return v.Interface().(time.Duration).String(), nil
+	}
+	if marshaler, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		return string(text), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported field type %s", v.Type())
+}
+
+// codecRegistry holds the codecs of a server by media type. The first codec is the default one,
+// used for requests without a Content-Type and responses to requests without an Accept header.
+type codecRegistry struct {
+	mu     sync.RWMutex
+	codecs []Codec
+	byType map[string]Codec
+}
+
+func newCodecRegistry() *codecRegistry {
+	c := &codecRegistry{byType: map[string]Codec{}}
+	c.register(JSONCodec{})
+	c.register(XMLCodec{}, "text/xml")
+	c.register(FormCodec{})
+	c.register(MsgpackCodec{}, "application/x-msgpack", "application/vnd.msgpack")
+	return c
+}
+
+// register adds the codec for its content type and the aliases, replacing the codec registered for them.
+func (c *codecRegistry) register(codec Codec, aliases ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	contentType := strings.ToLower(codec.ContentType())
+	if previous, ok := c.byType[contentType]; ok {
+		for i, registered := range c.codecs {
+			if registered.ContentType() == previous.ContentType() {
+				c.codecs[i] = codec
+			}
+		}
+	} else {
+		c.codecs = append(c.codecs, codec)
+	}
+	c.byType[contentType] = codec
+	for _, alias := range aliases {
+		c.byType[strings.ToLower(alias)] = codec
+	}
+}
+
+// contentTypes returns the content types of the codecs, the default first.
+func (c *codecRegistry) contentTypes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	contentTypes := make([]string, len(c.codecs))
+	for i, codec := range c.codecs {
+		contentTypes[i] = codec.ContentType()
+	}
+	return contentTypes
+}
+
+// decoder returns the codec of the request Content-Type, the default codec when it is not set.
+func (c *codecRegistry) decoder(r *http.Request) (Codec, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return c.codecs[0], nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if codec, ok := c.byType[mediaType]; ok {
+			return codec, nil
+		}
+	}
+	return nil, &HTTPError{
+		StatusCode: http.StatusUnsupportedMediaType,
+		Title:      "unsupported media type",
+		Detail:     fmt.Sprintf("content type %q is not supported", contentType),
+	}
+}
+
+// encoder returns the codec of the response. The default codec is used when the request has no Accept
+// header, and whenever the request accepts it, e.g. through */*, unless the media types the request ranks
+// highest name another codec explicitly. So browsers, which rank text/html first and accept application/xml
+// above */*, get the default codec. Otherwise the codec of the media type accepted with the highest quality
+// is used.
+func (c *codecRegistry) encoder(r *http.Request) (Codec, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return c.codecs[0], nil
+	}
+	ranges := parseAccept(accept)
+
+	defaultCodec := c.codecs[0]
+	defaultAccepted := false
+	for _, mediaRange := range ranges {
+		if mediaRange.matches(defaultCodec.ContentType()) {
+			defaultAccepted = true
+			break
+		}
+	}
+	if defaultAccepted {
+		// Ranges are sorted by decreasing quality: the first ones are ranked highest
+		for _, mediaRange := range ranges {
+			if mediaRange.quality < ranges[0].quality || mediaRange.matches(defaultCodec.ContentType()) {
+				break
+			}
+			if codec, ok := c.byType[mediaRange.mediaType]; ok && codec.ContentType() != defaultCodec.ContentType() {
+				return codec, nil
+			}
+		}
+		return defaultCodec, nil
+	}
+
+	for _, mediaRange := range ranges {
+		for _, codec := range c.codecs {
+			if mediaRange.matches(codec.ContentType()) {
+				return codec, nil
+			}
+		}
+		if codec, ok := c.byType[mediaRange.mediaType]; ok {
+			return codec, nil
+		}
+	}
+	return nil, &HTTPError{
+		StatusCode: http.StatusNotAcceptable,
+		Title:      "not acceptable",
+		Detail:     fmt.Sprintf("none of the accepted content types %q is supported", accept),
+	}
+}
+
+// acceptRange is a media range of an Accept header, e.g. application/* with its quality.
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// matches reports whether the content type is in the media range.
+func (a acceptRange) matches(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	switch {
+	case a.mediaType == "*/*" || a.mediaType == contentType:
+		return true
+	case strings.HasSuffix(a.mediaType, "/*"):
+		return strings.HasPrefix(contentType, strings.TrimSuffix(a.mediaType, "*"))
+	}
+	return false
+}
+
+// parseAccept returns the acceptable media ranges of an Accept header, by decreasing quality.
+// Ranges with a zero quality are left out.
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, acceptRange{mediaType: mediaType, quality: quality})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	return ranges
+}
+
+// RegisterCodec registers a codec for the request and response bodies of the handlers, for its
+// content type and the aliases decoded with it. It replaces the codec registered for the same content type.
+func (s *Server) RegisterCodec(codec Codec, aliases ...string) {
+	s.codecs.register(codec, aliases...)
+}
+
+const codecsKey contextKey = "codecs"
+
+var (
+	defaultCodecs     *codecRegistry
+	defaultCodecsOnce sync.Once
+)
+
+// codecsMiddleware makes the codecs of the server available to the handlers.
+func codecsMiddleware(c *codecRegistry) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), codecsKey, c)))
+		})
+	}
+}
+
+// defaultCodec returns the codec of requests without a Content-Type and responses without an Accept header.
+func (c *codecRegistry) defaultCodec() Codec {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.codecs[0]
+}
+
+// codecsFromContext returns the codecs of the server handling the request,
+// or the built-in ones when the handler is used without a Server.
+func codecsFromContext(ctx context.Context) *codecRegistry {
+	if c, ok := ctx.Value(codecsKey).(*codecRegistry); ok {
+		return c
+	}
+	defaultCodecsOnce.Do(func() {
+		defaultCodecs = newCodecRegistry()
+	})
+	return defaultCodecs
+}
@@ -0,0 +1,331 @@
+package httpmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/httpmanager/internal/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type callbackRequest struct {
+	OrderID string    `json:"order_id" xml:"order_id" form:"order_id" validate:"required"`
+	Amount  int64     `json:"amount" xml:"amount" form:"amount"`
+	Tags    []string  `json:"tags" xml:"tag" form:"tag"`
+	PaidAt  time.Time `json:"paid_at" xml:"paid_at" form:"paid_at,layout=2006-01-02"`
+}
+
+type callbackResponse struct {
+	XMLName struct{} `json:"-" xml:"callback" msgpack:"-"`
+	OrderID string   `json:"order_id" xml:"order_id" form:"order_id"`
+	Status  string   `json:"status" xml:"status" form:"status"`
+}
+
+// plainTextCodec writes responses as text/plain for the custom codec tests.
+type plainTextCodec struct{}
+
+func (plainTextCodec) ContentType() string { return "text/plain" }
+
+func (plainTextCodec) Decode(r io.Reader, v any) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	v.(*callbackRequest).OrderID = strings.TrimSpace(string(body))
+	return nil
+}
+
+func (plainTextCodec) Encode(w io.Writer, v any) error {
+	_, err := fmt.Fprintf(w, "%s %s", v.(*callbackResponse).OrderID, v.(*callbackResponse).Status)
+	return err
+}
+
+func newCallbackServer(received *callbackRequest) *Server {
+	server := NewServer(testdata.NewApplication())
+	server.POST("/callback", NewHandler(http.MethodPost, func(ctx context.Context, req *callbackRequest) (*callbackResponse, error) {
+		*received = *req
+		return &callbackResponse{OrderID: req.OrderID, Status: "paid"}, nil
+	}))
+	return server
+}
+
+func serveCallback(server *Server, contentType, accept string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/callback", bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_ContentNegotiation(t *testing.T) {
+	paidAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("json by default", func(t *testing.T) {
+		var received callbackRequest
+		rec := serveCallback(newCallbackServer(&received), "", "", []byte(`{"order_id":"A1","amount":100}`))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"order_id":"A1","status":"paid"}`, rec.Body.String())
+		assert.Equal(t, int64(100), received.Amount)
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		var received callbackRequest
+		body := `<callback><order_id>A1</order_id><amount>100</amount><tag>x</tag><tag>y</tag><paid_at>2026-10-01T00:00:00Z</paid_at></callback>`
+		rec := serveCallback(newCallbackServer(&received), "text/xml; charset=utf-8", "application/xml", []byte(body))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+		assert.Equal(t, `<callback><order_id>A1</order_id><status>paid</status></callback>`, rec.Body.String())
+		assert.Equal(t, callbackRequest{OrderID: "A1", Amount: 100, Tags: []string{"x", "y"}, PaidAt: paidAt}, received)
+	})
+
+	t.Run("form", func(t *testing.T) {
+		var received callbackRequest
+		rec := serveCallback(newCallbackServer(&received), "application/x-www-form-urlencoded", "application/x-www-form-urlencoded",
+			[]byte("order_id=A1&amount=100&tag=x&tag=y&paid_at=2026-10-01"))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/x-www-form-urlencoded", rec.Header().Get("Content-Type"))
+		assert.Equal(t, "order_id=A1&status=paid", rec.Body.String())
+		assert.Equal(t, callbackRequest{OrderID: "A1", Amount: 100, Tags: []string{"x", "y"}, PaidAt: paidAt}, received)
+	})
+
+	t.Run("form value that does not convert", func(t *testing.T) {
+		var received callbackRequest
+		rec := serveCallback(newCallbackServer(&received), "application/x-www-form-urlencoded", "", []byte("order_id=A1&amount=lots"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		var body DetailedErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "invalid request parameter", body.Message.Title)
+		assert.Equal(t, map[string]any{"source": "form", "name": "amount", "value": "lots"}, body.Data)
+	})
+
+	t.Run("form fields are validated by their form name", func(t *testing.T) {
+		var received callbackRequest
+		rec := serveCallback(newCallbackServer(&received), "application/x-www-form-urlencoded", "", []byte("amount=100"))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"field":"order_id"`)
+	})
+
+	t.Run("msgpack", func(t *testing.T) {
+		var received callbackRequest
+		body, err := msgpack.Marshal(map[string]any{"order_id": "A1", "amount": 100})
+		require.NoError(t, err)
+		rec := serveCallback(newCallbackServer(&received), "application/x-msgpack", "application/msgpack", body)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/msgpack", rec.Header().Get("Content-Type"))
+		var resp map[string]any
+		require.NoError(t, msgpack.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(t, map[string]any{"order_id": "A1", "status": "paid"}, resp)
+		assert.Equal(t, int64(100), received.Amount)
+	})
+
+	t.Run("accept quality", func(t *testing.T) {
+		var received callbackRequest
+		rec := serveCallback(newCallbackServer(&received), "", "application/json;q=0.5, application/xml, */*;q=0.1", []byte(`{"order_id":"A1"}`))
+
+		assert.Equal(t, "application/xml", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("accept wildcard", func(t *testing.T) {
+		var received callbackRequest
+		rec := serveCallback(newCallbackServer(&received), "", "text/html, */*;q=0.8", []byte(`{"order_id":"A1"}`))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		received := callbackRequest{OrderID: "untouched"}
+		rec := serveCallback(newCallbackServer(&received), "", "text/html, application/json;q=0", []byte(`{"order_id":"A1"}`))
+
+		assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+		assert.Equal(t, "untouched", received.OrderID)
+	})
+
+	t.Run("unsupported media type", func(t *testing.T) {
+		received := callbackRequest{OrderID: "untouched"}
+		rec := serveCallback(newCallbackServer(&received), "text/csv", "", []byte("A1,100"))
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+		var body DetailedErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "unsupported media type", body.Message.Title)
+		assert.Equal(t, "untouched", received.OrderID)
+	})
+
+	t.Run("success status with a negotiated codec", func(t *testing.T) {
+		handler := NewHandler(http.MethodPost, func(ctx context.Context, req *callbackRequest) (*ResponseSuccess[callbackResponse], error) {
+			return &ResponseSuccess[callbackResponse]{StatusCode: http.StatusAccepted, Body: callbackResponse{OrderID: req.OrderID, Status: "queued"}}, nil
+		})
+		req := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader("order_id=A1"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "application/xml")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Equal(t, `<callback><order_id>A1</order_id><status>queued</status></callback>`, rec.Body.String())
+	})
+
+	t.Run("encoding failure falls back to the default codec", func(t *testing.T) {
+		handler := NewHandler(http.MethodGet, func(ctx context.Context, req *struct{}) (*map[string]string, error) {
+			return &map[string]string{"status": "paid"}, nil
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "application/xml")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"status":"paid"}`, rec.Body.String())
+	})
+
+	t.Run("encoding failure of the default codec", func(t *testing.T) {
+		handler := NewHandler(http.MethodGet, func(ctx context.Context, req *struct{}) (*map[string]any, error) {
+			return &map[string]any{"callback": func() {}}, nil
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "application/xml")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	})
+}
+
+func TestHandler_ContentNegotiation_Browser(t *testing.T) {
+	type order struct {
+		ID    string            `json:"id"`
+		Attrs map[string]string `json:"attrs"`
+	}
+	server := NewServer(testdata.NewApplication())
+	server.GET("/order", NewHandler(http.MethodGet, func(ctx context.Context, req *struct{}) (*order, error) {
+		return &order{ID: "A1", Attrs: map[string]string{"channel": "web"}}, nil
+	}))
+	server.GET("/summary", NewHandler(http.MethodGet, func(ctx context.Context, req *struct{}) (*map[string]any, error) {
+		return &map[string]any{"orders": 2}, nil
+	}))
+
+	tests := []struct {
+		path   string
+		accept string
+		want   string
+	}{
+		{"/order", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "application/json"},
+		{"/summary", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8", "application/json"},
+		{"/order", "application/xml, application/json", "application/json"},
+		{"/order", "application/msgpack, */*;q=0.5", "application/msgpack"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			server.router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Equal(t, tt.want, rec.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestServer_RegisterCodec(t *testing.T) {
+	var received callbackRequest
+	server := newCallbackServer(&received)
+	server.RegisterCodec(plainTextCodec{}, "text/x-order")
+
+	rec := serveCallback(server, "text/x-order", "text/plain", []byte("A1\n"))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "A1 paid", rec.Body.String())
+	assert.Equal(t, "A1", received.OrderID)
+	assert.Equal(t, []string{"application/json", "application/xml", "application/x-www-form-urlencoded", "application/msgpack", "text/plain"},
+		server.codecs.contentTypes())
+
+	// The codecs of other servers are not changed
+	rec = serveCallback(newCallbackServer(&received), "text/x-order", "", []byte("A1"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
+
+// prefixedJSONCodec is a codec whose values cannot be compared with ==, it has a slice field.
+type prefixedJSONCodec struct {
+	JSONCodec
+	prefixes []string
+}
+
+func (c prefixedJSONCodec) Encode(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, strings.Join(c.prefixes, "")); err != nil {
+		return err
+	}
+	return c.JSONCodec.Encode(w, v)
+}
+
+func TestServer_RegisterCodec_NotComparable(t *testing.T) {
+	server := NewServer(testdata.NewApplication())
+	server.RegisterCodec(prefixedJSONCodec{prefixes: []string{"v1"}})
+	server.RegisterCodec(prefixedJSONCodec{prefixes: []string{")]}'", "\n"}}, "text/json")
+	server.GET("/order", NewHandler(http.MethodGet, func(ctx context.Context, req *struct{}) (*callbackResponse, error) {
+		return &callbackResponse{OrderID: "A1", Status: "paid"}, nil
+	}))
+	server.GET("/callback", NewHandler(http.MethodGet, func(ctx context.Context, req *struct{}) (*map[string]any, error) {
+		return &map[string]any{"callback": func() {}}, nil
+	}))
+
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/order", "text/json, */*;q=0.5")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, ")]}'\n{\"order_id\":\"A1\",\"status\":\"paid\"}", strings.TrimSpace(rec.Body.String()))
+
+	rec = serve("/callback", "application/json")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, []string{"application/json", "application/xml", "application/x-www-form-urlencoded", "application/msgpack"},
+		server.codecs.contentTypes())
+}
+
+func TestParseAccept(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   []acceptRange
+	}{
+		{"application/json", []acceptRange{{"application/json", 1}}},
+		{"text/html;q=0.2, application/xml;q=0.9, */*;q=0", []acceptRange{{"application/xml", 0.9}, {"text/html", 0.2}}},
+		{"application/*, text/plain;q=0.5, invalid/", []acceptRange{{"application/*", 1}, {"text/plain", 0.5}}},
+		{"application/json;q=nope, text/plain", []acceptRange{{"text/plain", 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseAccept(tt.accept))
+		})
+	}
+}
@@ -1,65 +1,337 @@
 package httpmanager
 
 import (
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
 	"github.com/gorilla/mux"
-	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// CORSMiddleware creates a middleware that adds CORS headers to the response
-func CORSMiddleware(allowedOrigins, allowedMethods, allowedHeaders []string, allowCredentials bool) mux.MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Set default values if not provided
-			origins := "*"
-			if len(allowedOrigins) > 0 {
-				origins = allowedOrigins[0]
-				for _, origin := range allowedOrigins[1:] {
-					origins += ", " + origin
-				}
-			}
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	defaultCORSHeaders = []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization", "X-CSRF-Token"}
+)
 
-			methods := "GET, POST, PUT, DELETE, OPTIONS"
-			if len(allowedMethods) > 0 {
-				methods = allowedMethods[0]
-				for _, method := range allowedMethods[1:] {
-					methods += ", " + method
-				}
-			}
+// CORSConfig configures the Cross-Origin Resource Sharing policy of the server or of a route.
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests: "*" for any origin,
+	// an exact origin such as "https://example.com", or a subdomain pattern such as "https://*.example.com".
+	// Defaults to "*".
+	AllowedOrigins []string
+	// AllowedMethods are the methods allowed in preflight requests. Defaults to GET, POST, PUT, DELETE and OPTIONS.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in preflight requests, "*" allows any header.
+	// Defaults to Accept, Content-Type, Content-Length, Accept-Encoding, Authorization and X-CSRF-Token.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers readable by the browser, sent as Access-Control-Expose-Headers.
+	ExposedHeaders []string
+	// AllowCredentials allows cookies and authorization headers from the origins listed exactly or by a
+	// subdomain pattern, which are then echoed. Origins only allowed through "*" get "*" without credentials,
+	// so browsers never send them credentialed requests.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight response, sent as Access-Control-Max-Age.
+	// Not sent when zero.
+	MaxAge time.Duration
+}
 
-			headers := "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, X-CSRF-Token"
-			if len(allowedHeaders) > 0 {
-				headers = allowedHeaders[0]
-				for _, header := range allowedHeaders[1:] {
-					headers += ", " + header
-				}
-			}
+// corsPolicy is a CORSConfig prepared to answer requests.
+type corsPolicy struct {
+	anyOrigin   bool
+	origins     map[string]struct{}
+	patterns    []originPattern
+	methods     map[string]struct{}
+	anyHeader   bool
+	headers     map[string]struct{}
+	credentials bool
+
+	allowMethods  string
+	allowHeaders  string
+	exposeHeaders string
+	maxAge        string
+}
 
-			// Set CORS headers
-			w.Header().Set("Access-Control-Allow-Origin", origins)
-			w.Header().Set("Access-Control-Allow-Methods", methods)
-			w.Header().Set("Access-Control-Allow-Headers", headers)
+// originPattern matches the origins of a subdomain pattern, e.g. "https://*.example.com".
+type originPattern struct {
+	prefix string
+	suffix string
+}
 
-			if allowCredentials {
-				w.Header().Set("Access-Control-Allow-Credentials", "true")
-			}
+func newCORSPolicy(config CORSConfig) *corsPolicy {
+	p := &corsPolicy{
+		origins:     map[string]struct{}{},
+		methods:     map[string]struct{}{},
+		headers:     map[string]struct{}{},
+		credentials: config.AllowCredentials,
+	}
 
-			// Handle preflight requests
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusOK)
-				return
+	origins := config.AllowedOrigins
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			p.patterns = append(p.patterns, originPattern{prefix: prefix, suffix: suffix})
+		default:
+			p.origins[origin] = struct{}{}
+		}
+	}
+
+	methods := config.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	for _, method := range methods {
+		p.methods[strings.ToUpper(method)] = struct{}{}
+	}
+	p.allowMethods = strings.Join(methods, ", ")
+
+	headers := config.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	for _, header := range headers {
+		if header == "*" {
+			p.anyHeader = true
+		}
+		p.headers[http.CanonicalHeaderKey(header)] = struct{}{}
+	}
+	p.allowHeaders = strings.Join(headers, ", ")
+
+	p.exposeHeaders = strings.Join(config.ExposedHeaders, ", ")
+	if config.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(config.MaxAge.Seconds()))
+	}
+	return p
+}
+
+// allowOrigin reports whether the origin matches the policy.
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	return p.anyOrigin || p.listedOrigin(origin)
+}
+
+// listedOrigin reports whether the origin matches an exact origin or a subdomain pattern of the policy.
+func (p *corsPolicy) listedOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	if _, ok := p.origins[origin]; ok {
+		return true
+	}
+	for _, pattern := range p.patterns {
+		if len(origin) <= len(pattern.prefix)+len(pattern.suffix) ||
+			!strings.HasPrefix(origin, pattern.prefix) || !strings.HasSuffix(origin, pattern.suffix) {
+			continue
+		}
+		// The wildcard stands for subdomains only, not for a path or a port
+		if subdomain := origin[len(pattern.prefix) : len(origin)-len(pattern.suffix)]; !strings.ContainsAny(subdomain, "/:") {
+			return true
+		}
+	}
+	return false
+}
+
+// allowRequestHeaders reports whether all the comma-separated request headers are allowed.
+func (p *corsPolicy) allowRequestHeaders(headers string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for _, header := range strings.Split(headers, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if _, ok := p.headers[http.CanonicalHeaderKey(header)]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// setAllowOrigin sets the allowed origin, and the credentials header when enabled for a listed origin.
+// Credentials are never allowed through "*": echoing any origin with them would let every website make
+// credentialed requests.
+func (p *corsPolicy) setAllowOrigin(h http.Header, origin string) {
+	switch {
+	case p.credentials && p.listedOrigin(origin):
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Credentials", "true")
+	case p.anyOrigin:
+		h.Set("Access-Control-Allow-Origin", "*")
+	default:
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+}
+
+// serve adds the CORS headers to a request and passes it to next, or answers it when it is a preflight request.
+func (p *corsPolicy) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if isPreflight(r) {
+		p.preflight(w, r)
+		return
+	}
+
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if p.allowOrigin(origin) {
+			p.setAllowOrigin(h, origin)
+			if p.exposeHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", p.exposeHeaders)
 			}
+		} else {
+			rejectCORS(r, "origin not allowed")
+		}
+	}
+	next.ServeHTTP(w, r)
+}
 
-			log.Println("CORS")
+// preflight answers a preflight request with 204. A rejected request is answered without CORS headers,
+// so the browser blocks the actual request.
+func (p *corsPolicy) preflight(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+	_, methodAllowed := p.methods[r.Header.Get("Access-Control-Request-Method")]
+	switch {
+	case !p.allowOrigin(r.Header.Get("Origin")):
+		rejectCORS(r, "origin not allowed")
+	case !methodAllowed:
+		rejectCORS(r, "method not allowed")
+	case !p.allowRequestHeaders(requestHeaders):
+		rejectCORS(r, "headers not allowed")
+	default:
+		p.setAllowOrigin(h, r.Header.Get("Origin"))
+		h.Set("Access-Control-Allow-Methods", p.allowMethods)
+		if p.anyHeader && requestHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", requestHeaders)
+		} else {
+			h.Set("Access-Control-Allow-Headers", p.allowHeaders)
+		}
+		if p.maxAge != "" {
+			h.Set("Access-Control-Max-Age", p.maxAge)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isPreflight reports whether the request is a CORS preflight request.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
 
-			// Call the next handler
-			next.ServeHTTP(w, r)
+// rejectCORS logs a rejected cross-origin request in debug mode.
+func rejectCORS(r *http.Request, reason string) {
+	logmanager.DebugWithContext(r.Context(), "CORS request rejected", map[string]string{
+		"type":           "http",
+		"reason":         reason,
+		"origin":         r.Header.Get("Origin"),
+		"method":         r.Method,
+		"path":           r.URL.Path,
+		"request_method": r.Header.Get("Access-Control-Request-Method"),
+	})
+}
+
+// CORSMiddleware creates a middleware that adds CORS headers to the response
+func CORSMiddleware(allowedOrigins, allowedMethods, allowedHeaders []string, allowCredentials bool) mux.MiddlewareFunc {
+	return NewCORSMiddleware(CORSConfig{
+		AllowedOrigins:   allowedOrigins,
+		AllowedMethods:   allowedMethods,
+		AllowedHeaders:   allowedHeaders,
+		AllowCredentials: allowCredentials,
+	})
+}
+
+// NewCORSMiddleware creates a middleware applying the CORS policy. It answers the preflight requests
+// reaching the handler; routes registered for specific methods should use WithCORS or the CORS route
+// option instead, which also answer their preflight requests.
+func NewCORSMiddleware(config CORSConfig) mux.MiddlewareFunc {
+	policy := newCORSPolicy(config)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy.serve(w, r, next)
 		})
 	}
 }
 
+// CORS sets the CORS policy of the route, replacing the policy of the server for it.
+func CORS(config CORSConfig) RouteOption {
+	policy := newCORSPolicy(config)
+	return func(d *routeDoc) {
+		d.cors = policy
+	}
+}
+
 // EnableCORS adds CORS middleware to an existing server
 func (s *Server) EnableCORS(allowedOrigins, allowedMethods, allowedHeaders []string, allowCredentials bool) {
-	s.Use(CORSMiddleware(allowedOrigins, allowedMethods, allowedHeaders, allowCredentials))
+	s.enableCORS(CORSConfig{
+		AllowedOrigins:   allowedOrigins,
+		AllowedMethods:   allowedMethods,
+		AllowedHeaders:   allowedHeaders,
+		AllowCredentials: allowCredentials,
+	})
+}
+
+// enableCORS sets the CORS policy of the server and adds its middleware.
+func (s *Server) enableCORS(config CORSConfig) {
+	policy := newCORSPolicy(config)
+	s.corsPolicy = policy
+	s.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Routes with their own policy are served by it, and preflight requests for routes
+			// registered for a method by the MethodNotAllowedHandler
+			route := mux.CurrentRoute(r)
+			if _, ok := s.routeCORS[route]; ok || route == nil && isPreflight(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			policy.serve(w, r, next)
+		})
+	})
 	s.hasSetUpCORS = true
 }
+
+// registerMethodNotAllowedHandler answers the requests matching a route path but not its method.
+// Preflight requests are answered with the CORS policy of the route matching the requested method,
+// since routes registered for a method do not match OPTIONS.
+func (s *Server) registerMethodNotAllowedHandler() {
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if policy := s.preflightPolicy(r); policy != nil {
+			policy.preflight(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+
+	// Apply middlewares to the handler so it gets trace ID and logging
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		handler = s.middlewares[i](handler)
+	}
+
+	s.router.MethodNotAllowedHandler = handler
+}
+
+// preflightPolicy returns the CORS policy of the route matching a preflight request, if any.
+func (s *Server) preflightPolicy(r *http.Request) *corsPolicy {
+	if !isPreflight(r) {
+		return nil
+	}
+
+	req := r.Clone(r.Context())
+	req.Method = r.Header.Get("Access-Control-Request-Method")
+	var match mux.RouteMatch
+	if !s.router.Match(req, &match) || match.MatchErr != nil {
+		return nil
+	}
+	if policy, ok := s.routeCORS[match.Route]; ok {
+		return policy
+	}
+	return s.corsPolicy
+}
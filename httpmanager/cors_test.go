@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestCORSMiddleware(t *testing.T) {
@@ -21,6 +22,7 @@ func TestCORSMiddleware(t *testing.T) {
 
 		// Create a test request
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Origin", "https://example.com")
 		rr := httptest.NewRecorder()
 
 		// Serve the request
@@ -29,8 +31,8 @@ func TestCORSMiddleware(t *testing.T) {
 		// Check the response
 		assert.Equal(t, http.StatusOK, rr.Code)
 		assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
-		assert.Equal(t, "GET, POST, PUT, DELETE, OPTIONS", rr.Header().Get("Access-Control-Allow-Methods"))
-		assert.Equal(t, "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, X-CSRF-Token", rr.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "Origin", rr.Header().Get("Vary"))
+		assert.Equal(t, "", rr.Header().Get("Access-Control-Allow-Methods"))
 		assert.Equal(t, "", rr.Header().Get("Access-Control-Allow-Credentials"))
 	})
 
@@ -44,34 +46,177 @@ func TestCORSMiddleware(t *testing.T) {
 
 		// Create a test request
 		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Origin", "https://api.example.com")
 		rr := httptest.NewRecorder()
 
 		// Serve the request
 		handler.ServeHTTP(rr, req)
 
-		// Check the response
+		// Check the response - only the matching origin is echoed
 		assert.Equal(t, http.StatusOK, rr.Code)
-		assert.Equal(t, "https://example.com, https://api.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
-		assert.Equal(t, "GET, POST", rr.Header().Get("Access-Control-Allow-Methods"))
-		assert.Equal(t, "Content-Type, Authorization", rr.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "https://api.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
 		assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
 	})
 
+	t.Run("credentials are not allowed through the wildcard", func(t *testing.T) {
+		for _, origins := range [][]string{nil, {"*"}, {"https://example.com", "*"}} {
+			handler := CORSMiddleware(origins, nil, nil, true)(testHandler)
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Origin", "https://evil.test")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"), origins)
+			assert.Equal(t, "", rr.Header().Get("Access-Control-Allow-Credentials"), origins)
+		}
+
+		handler := CORSMiddleware([]string{"https://example.com", "*"}, nil, nil, true)(testHandler)
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Origin", "https://example.com")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, "https://example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"), "a listed origin keeps its credentials")
+	})
+
 	t.Run("preflight request", func(t *testing.T) {
 		// Create middleware
-		middleware := CORSMiddleware(nil, nil, nil, false)
-		handler := middleware(testHandler)
+		middleware := CORSMiddleware(nil, []string{"GET", "POST"}, []string{"Content-Type", "Authorization"}, false)
+		called := false
+		handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
 
 		// Create a preflight OPTIONS request
 		req := httptest.NewRequest(http.MethodOptions, "/test", nil)
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		req.Header.Set("Access-Control-Request-Headers", "content-type, authorization")
 		rr := httptest.NewRecorder()
 
 		// Serve the request
 		handler.ServeHTTP(rr, req)
 
-		// Check the response - should return 200 OK without calling the next handler
-		assert.Equal(t, http.StatusOK, rr.Code)
+		// Check the response - should return 204 without calling the next handler
+		assert.False(t, called)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, POST", rr.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "Content-Type, Authorization", rr.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, rr.Header().Values("Vary"))
+	})
+
+	t.Run("OPTIONS request that is not a preflight", func(t *testing.T) {
+		middleware := CORSMiddleware(nil, nil, nil, false)
+		called := false
+		handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+
+		req := httptest.NewRequest(http.MethodOptions, "/test", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.True(t, called)
+		assert.Equal(t, "", rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestNewCORSMiddleware(t *testing.T) {
+	config := CORSConfig{
+		AllowedOrigins:   []string{"https://example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "PUT"},
+		AllowedHeaders:   []string{"Content-Type"},
+		ExposedHeaders:   []string{"X-Request-Id", "X-Total-Count"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	handler := NewCORSMiddleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	origins := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://example.com", true},
+		{"HTTPS://EXAMPLE.COM", true},
+		{"http://example.com", false},
+		{"https://evil-example.com", false},
+		{"https://api.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://example.org", false},
+		{"https://.example.org", false},
+		{"https://evil.com/.example.org", false},
+		{"https://evil.com:443.example.org", false},
+		{"https://api.example.org.evil.com", false},
+	}
+	for _, tt := range origins {
+		t.Run(tt.origin, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Origin", tt.origin)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "Origin", rr.Header().Get("Vary"))
+			if tt.allowed {
+				assert.Equal(t, tt.origin, rr.Header().Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
+				assert.Equal(t, "X-Request-Id, X-Total-Count", rr.Header().Get("Access-Control-Expose-Headers"))
+			} else {
+				assert.Equal(t, "", rr.Header().Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "", rr.Header().Get("Access-Control-Allow-Credentials"))
+			}
+		})
+	}
+
+	preflights := []struct {
+		name    string
+		origin  string
+		method  string
+		headers string
+		allowed bool
+	}{
+		{"allowed", "https://api.example.org", "PUT", "Content-Type", true},
+		{"origin not allowed", "https://evil.com", "PUT", "", false},
+		{"method not allowed", "https://example.com", "DELETE", "", false},
+		{"header not allowed", "https://example.com", "PUT", "Content-Type, X-Custom", false},
+	}
+	for _, tt := range preflights {
+		t.Run("preflight "+tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/test", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			req.Header.Set("Access-Control-Request-Headers", tt.headers)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusNoContent, rr.Code)
+			if tt.allowed {
+				assert.Equal(t, tt.origin, rr.Header().Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "GET, PUT", rr.Header().Get("Access-Control-Allow-Methods"))
+				assert.Equal(t, "600", rr.Header().Get("Access-Control-Max-Age"))
+			} else {
+				assert.Equal(t, "", rr.Header().Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "", rr.Header().Get("Access-Control-Allow-Methods"))
+			}
+		})
+	}
+
+	t.Run("any header", func(t *testing.T) {
+		handler := NewCORSMiddleware(CORSConfig{AllowedHeaders: []string{"*"}})(http.NotFoundHandler())
+		req := httptest.NewRequest(http.MethodOptions, "/test", nil)
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
 		assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "X-Custom", rr.Header().Get("Access-Control-Allow-Headers"))
 	})
 }
 
@@ -90,6 +235,7 @@ func TestWithCORS(t *testing.T) {
 
 	// Create a test request
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Origin", "https://example.com")
 	rr := httptest.NewRecorder()
 
 	// Serve the request
@@ -116,15 +262,75 @@ func TestServer_EnableCORS(t *testing.T) {
 	// Register a handler
 	server.Handle("/test", testHandler)
 
-	// Create a test request
-	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	// Create a preflight request
+	req := httptest.NewRequest(http.MethodOptions, "/test", nil)
+	req.Header.Set("Origin", "https://api.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
 	rr := httptest.NewRecorder()
 
 	// Serve the request
 	server.router.ServeHTTP(rr, req)
 
 	// Check the response
-	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
 	assert.Equal(t, "https://api.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
 	assert.Equal(t, "GET, POST", rr.Header().Get("Access-Control-Allow-Methods"))
 }
+
+func TestServer_CORSPolicies(t *testing.T) {
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := NewServer(testdata.NewApplication(), WithCORS(CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "POST", "DELETE"},
+		MaxAge:         time.Hour,
+	}))
+	server.GET("/users", testHandler)
+	server.DELETE("/users", testHandler)
+	server.GET("/public", testHandler, CORS(CORSConfig{AllowedMethods: []string{"GET"}}))
+	server.Group("/api").POST("/widgets", testHandler, CORS(CORSConfig{AllowedOrigins: []string{"https://*.partner.com"}, AllowedMethods: []string{"POST"}}))
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		origin  string
+		request string
+		status  int
+		allow   string
+		methods string
+	}{
+		{"server policy", http.MethodGet, "/users", "https://app.example.com", "", http.StatusOK, "https://app.example.com", ""},
+		{"server policy rejects origin", http.MethodGet, "/users", "https://evil.com", "", http.StatusOK, "", ""},
+		{"server policy preflight for method route", http.MethodOptions, "/users", "https://app.example.com", "DELETE", http.StatusNoContent, "https://app.example.com", "GET, POST, DELETE"},
+		{"route policy", http.MethodGet, "/public", "https://anyone.com", "", http.StatusOK, "*", ""},
+		{"route policy preflight", http.MethodOptions, "/public", "https://anyone.com", "GET", http.StatusNoContent, "*", "GET"},
+		{"group route policy preflight", http.MethodOptions, "/api/widgets", "https://eu.partner.com", "POST", http.StatusNoContent, "https://eu.partner.com", "POST"},
+		{"group route policy rejects server origin", http.MethodPost, "/api/widgets", "https://app.example.com", "", http.StatusOK, "", ""},
+		{"preflight for unregistered method", http.MethodOptions, "/users", "https://app.example.com", "PUT", http.StatusMethodNotAllowed, "", ""},
+		{"method not allowed", http.MethodPut, "/users", "", "", http.StatusMethodNotAllowed, "", ""},
+		{"preflight for unknown path", http.MethodOptions, "/unknown", "https://app.example.com", "GET", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.request != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.request)
+			}
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Equal(t, tt.allow, rr.Header().Get("Access-Control-Allow-Origin"))
+			assert.Equal(t, tt.methods, rr.Header().Get("Access-Control-Allow-Methods"))
+			if tt.status == http.StatusNoContent && tt.path == "/users" {
+				assert.Equal(t, "3600", rr.Header().Get("Access-Control-Max-Age"))
+			}
+		})
+	}
+}
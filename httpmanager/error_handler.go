@@ -0,0 +1,292 @@
+package httpmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// HTTPError is the response of an error: its status code and the content of the error body.
+// Handlers can return it directly, and the error mappings of the server build it from other errors.
+type HTTPError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Code is the application error code. Defaults to the status code.
+	Code string
+	// Title is a short summary of the error.
+	Title string
+	// Detail explains the error to the client.
+	Detail string
+	// Data carries additional details, e.g. the failed fields of a validation error.
+	Data any
+	// Body, when set, is encoded as JSON instead of the error format of the server.
+	Body any
+	// Err is the original error, kept for logging; it is not sent to the client.
+	Err error
+}
+
+// Error implements the error interface
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	if e.Detail != "" {
+		return e.Detail
+	}
+	return http.StatusText(e.StatusCode)
+}
+
+// Unwrap returns the original error
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorHandler writes the response of an error returned by a handler, or raised while binding
+// (*BindError) or validating (*ValidationError) its request.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+// ErrorEncoder writes an HTTPError to the response.
+type ErrorEncoder func(w http.ResponseWriter, r *http.Request, err *HTTPError)
+
+// JSONErrorEncoder writes errors as DetailedErrorResponse. It is the default encoder.
+func JSONErrorEncoder(w http.ResponseWriter, r *http.Request, err *HTTPError) {
+	if err.Body != nil {
+		writeJSON(w, "application/json", err.StatusCode, err.Body)
+		return
+	}
+	writeJSON(w, "application/json", err.StatusCode, DetailedErrorResponse{
+		Status: false,
+		Code:   errorCode(err),
+		Message: MessageInfo{
+			Title: err.Title,
+			Desc:  err.Detail,
+		},
+		Data: err.Data,
+	})
+}
+
+// ProblemDetails is the RFC 9457 problem details object written by ProblemJSONEncoder.
+type ProblemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is the application error code, an extension member.
+	Code string `json:"code,omitempty"`
+	// Errors carries the Data of the error, an extension member.
+	Errors any `json:"errors,omitempty"`
+}
+
+// ProblemJSONEncoder writes errors as RFC 9457 application/problem+json documents.
+// Errors with a custom Body, e.g. ResponseError, are still written as application/json.
+func ProblemJSONEncoder(w http.ResponseWriter, r *http.Request, err *HTTPError) {
+	if err.Body != nil {
+		writeJSON(w, "application/json", err.StatusCode, err.Body)
+		return
+	}
+
+	title := err.Title
+	if title == "" {
+		title = http.StatusText(err.StatusCode)
+	}
+	problem := ProblemDetails{
+		Type:     "about:blank",
+		Title:    title,
+		Status:   err.StatusCode,
+		Detail:   err.Detail,
+		Instance: r.URL.Path,
+		Errors:   err.Data,
+	}
+	if err.Code != "" && err.Code != fmt.Sprint(err.StatusCode) {
+		problem.Code = err.Code
+	}
+	writeJSON(w, "application/problem+json", err.StatusCode, problem)
+}
+
+// errorCode returns the application code of the error, the status code by default.
+func errorCode(err *HTTPError) string {
+	if err.Code != "" {
+		return err.Code
+	}
+	return fmt.Sprint(err.StatusCode)
+}
+
+// writeJSON writes body as JSON with the status code.
+func writeJSON(w http.ResponseWriter, contentType string, statusCode int, body any) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// errorMapping converts the errors it handles to an HTTPError, and returns nil for the others.
+type errorMapping func(err error) *HTTPError
+
+// MapError maps the errors matching target with errors.Is to the status code and title.
+// The message of target is the detail of the response, the message of the error is not sent.
+//
+// Example:
+//
+//	server.MapError(sql.ErrNoRows, http.StatusNotFound, "not found")
+func (s *Server) MapError(target error, statusCode int, title string) {
+	s.errors.mappings = append(s.errors.mappings, func(err error) *HTTPError {
+		if !errors.Is(err, target) {
+			return nil
+		}
+		return &HTTPError{StatusCode: statusCode, Title: title, Detail: target.Error(), Err: err}
+	})
+}
+
+// MapErrorType maps the errors of type E, found with errors.As, to the HTTPError built by fn.
+// Mappings are checked in registration order, before ResponseError and CustomError.
+//
+// Example:
+//
+//	httpmanager.MapErrorType(server, func(err *OutOfStockError) *httpmanager.HTTPError {
+//	    return &httpmanager.HTTPError{StatusCode: http.StatusConflict, Title: "out of stock", Data: err.SKU}
+//	})
+func MapErrorType[E error](s *Server, fn func(err E) *HTTPError) {
+	s.errors.mappings = append(s.errors.mappings, func(err error) *HTTPError {
+		var target E
+		if !errors.As(err, &target) {
+			return nil
+		}
+		httpErr := fn(target)
+		if httpErr != nil && httpErr.Err == nil {
+			httpErr.Err = err
+		}
+		return httpErr
+	})
+}
+
+// errorResponder writes the error responses of a server.
+type errorResponder struct {
+	handler  ErrorHandler
+	encoder  ErrorEncoder
+	mappings []errorMapping
+}
+
+func newErrorResponder(o *Option) *errorResponder {
+	e := &errorResponder{encoder: JSONErrorEncoder}
+	if o != nil {
+		e.handler = o.errorHandler
+		if o.errorEncoder != nil {
+			e.encoder = o.errorEncoder
+		}
+	}
+	return e
+}
+
+// write writes the response of err with the error handler of the server, or resolves and encodes it.
+func (e *errorResponder) write(w http.ResponseWriter, r *http.Request, err error) {
+	if e.handler != nil {
+		e.handler(w, r, err)
+		return
+	}
+	e.encoder(w, r, e.resolve(r, err))
+}
+
+// resolve converts err to its HTTPError: an HTTPError, a registered mapping, a ResponseError,
+// a CustomError, a BindError or a ValidationError, otherwise a 500 internal server error.
+func (e *errorResponder) resolve(r *http.Request, err error) *HTTPError {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+	for _, mapping := range e.mappings {
+		if httpErr := mapping(err); httpErr != nil {
+			return httpErr
+		}
+	}
+
+	if isCustomV2, statusCode, body := checkCustomErrorV2(err); isCustomV2 {
+		return &HTTPError{StatusCode: statusCode, Body: body, Err: err}
+	}
+	if customErr, ok := IsCustomError(err); ok {
+		return &HTTPError{
+			StatusCode: customErr.StatusCode,
+			Code:       customErr.Code,
+			Title:      customErr.Title,
+			Detail:     customErr.Desc,
+			Err:        err,
+		}
+	}
+
+	var bindErr *BindError
+	if errors.As(err, &bindErr) {
+		return &HTTPError{
+			StatusCode: http.StatusBadRequest,
+			Title:      "invalid request parameter",
+			Detail:     bindErr.Error(),
+			Data:       bindErr,
+			Err:        err,
+		}
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return validatorFromContext(r.Context()).httpError(r, validationErr)
+	}
+
+	return internalError(err)
+}
+
+// internalError is the 500 response of an unexpected error.
+func internalError(err error) *HTTPError {
+	return &HTTPError{
+		StatusCode: http.StatusInternalServerError,
+		Title:      "internal server error",
+		Detail:     "internal server error",
+		Err:        err,
+	}
+}
+
+// methodNotAllowedError returns the 405 of a request whose method the handler does not serve.
+func methodNotAllowedError(r *http.Request) *HTTPError {
+	return &HTTPError{
+		StatusCode: http.StatusMethodNotAllowed,
+		Title:      "method not allowed",
+		Detail:     fmt.Sprintf("method %s is not allowed", r.Method),
+	}
+}
+
+// invalidBodyError returns the 400 of a request body that cannot be decoded or validated.
+func invalidBodyError(err error) *HTTPError {
+	return &HTTPError{
+		StatusCode: http.StatusBadRequest,
+		Title:      "invalid request body",
+		Detail:     err.Error(),
+		Err:        err,
+	}
+}
+
+const errorResponderKey contextKey = "errorResponder"
+
+// defaultErrorResponder writes the errors of the handlers used without a Server.
+var defaultErrorResponder = newErrorResponder(nil)
+
+// errorResponderMiddleware makes the error responder of the server available to the handlers.
+func errorResponderMiddleware(e *errorResponder) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), errorResponderKey, e)))
+		})
+	}
+}
+
+// errorResponderFromContext returns the error responder of the server handling the request,
+// or a default one when the handler is used without a Server.
+func errorResponderFromContext(ctx context.Context) *errorResponder {
+	if e, ok := ctx.Value(errorResponderKey).(*errorResponder); ok {
+		return e
+	}
+	return defaultErrorResponder
+}
+
+// WriteError writes the response of err the way the server writes the errors returned by handlers,
+// e.g. from a plain http.Handler or a middleware.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	errorResponderFromContext(r.Context()).write(w, r, err)
+}
@@ -0,0 +1,264 @@
+package httpmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SALT-Indonesia/salt-pkg/httpmanager/internal/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errOrderNotFound = errors.New("order not found")
+
+type outOfStockError struct {
+	SKU string
+}
+
+func (e *outOfStockError) Error() string {
+	return fmt.Sprintf("%s is out of stock", e.SKU)
+}
+
+type errorRequest struct {
+	Limit int    `query:"limit"`
+	Name  string `json:"name" validate:"omitempty,min=3"`
+}
+
+func newErrorServer(t *testing.T, err error, opts ...OptionFunc) *Server {
+	server := NewServer(testdata.NewApplication(), opts...)
+	server.MapError(errOrderNotFound, http.StatusNotFound, "not found")
+	MapErrorType(server, func(err *outOfStockError) *HTTPError {
+		return &HTTPError{StatusCode: http.StatusConflict, Code: "OUT_OF_STOCK", Title: "out of stock", Detail: err.Error(), Data: err.SKU}
+	})
+
+	server.POST("/orders", NewHandler(http.MethodPost, func(ctx context.Context, req *errorRequest) (*Response, error) {
+		return nil, err
+	}))
+	server.POST("/upload", NewUploadHandler(http.MethodPost, t.TempDir(), func(ctx context.Context, files map[string][]*UploadedFile, form map[string][]string) (interface{}, error) {
+		return nil, err
+	}))
+	server.GET("/redirect", NewRedirectHandler(http.MethodGet, func(c *Context) {
+		c.Error(err)
+	}))
+	return server
+}
+
+func uploadRequest() *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField("name", "value")
+	_ = writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestServer_ErrorMapping(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantBody   DetailedErrorResponse
+	}{
+		{
+			name:       "sentinel error",
+			err:        fmt.Errorf("load order 42: %w", errOrderNotFound),
+			wantStatus: http.StatusNotFound,
+			wantBody:   DetailedErrorResponse{Code: "404", Message: MessageInfo{Title: "not found", Desc: "order not found"}},
+		},
+		{
+			name:       "typed error",
+			err:        fmt.Errorf("reserve: %w", &outOfStockError{SKU: "SKU-1"}),
+			wantStatus: http.StatusConflict,
+			wantBody:   DetailedErrorResponse{Code: "OUT_OF_STOCK", Message: MessageInfo{Title: "out of stock", Desc: "SKU-1 is out of stock"}, Data: "SKU-1"},
+		},
+		{
+			name:       "http error",
+			err:        &HTTPError{StatusCode: http.StatusForbidden, Title: "forbidden", Detail: "not your order"},
+			wantStatus: http.StatusForbidden,
+			wantBody:   DetailedErrorResponse{Code: "403", Message: MessageInfo{Title: "forbidden", Desc: "not your order"}},
+		},
+		{
+			name:       "custom error",
+			err:        &CustomError{Err: errors.New("db"), Code: "ORD_001", Title: "order error", Desc: "order is locked", StatusCode: http.StatusLocked},
+			wantStatus: http.StatusLocked,
+			wantBody:   DetailedErrorResponse{Code: "ORD_001", Message: MessageInfo{Title: "order error", Desc: "order is locked"}},
+		},
+		{
+			name:       "unknown error",
+			err:        errors.New("connection refused"),
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   DetailedErrorResponse{Code: "500", Message: MessageInfo{Title: "internal server error", Desc: "internal server error"}},
+		},
+	}
+
+	for _, tt := range tests {
+		server := newErrorServer(t, tt.err)
+		requests := map[string]*http.Request{
+			"handler":          httptest.NewRequest(http.MethodPost, "/orders", nil),
+			"upload handler":   uploadRequest(),
+			"redirect handler": httptest.NewRequest(http.MethodGet, "/redirect", nil),
+		}
+		for handler, req := range requests {
+			t.Run(tt.name+" from "+handler, func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				server.router.ServeHTTP(rec, req)
+
+				assert.Equal(t, tt.wantStatus, rec.Code)
+				assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+				var body DetailedErrorResponse
+				require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+				assert.Equal(t, tt.wantBody, body)
+			})
+		}
+	}
+}
+
+func TestServer_ErrorMapping_ResponseError(t *testing.T) {
+	type body struct {
+		Reason string `json:"reason"`
+	}
+	server := newErrorServer(t, &ResponseError[body]{Err: errors.New("archived"), StatusCode: http.StatusGone, Body: body{Reason: "archived"}})
+
+	for _, req := range []*http.Request{httptest.NewRequest(http.MethodPost, "/orders", nil), uploadRequest()} {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusGone, rec.Code)
+		assert.JSONEq(t, `{"reason":"archived"}`, rec.Body.String())
+	}
+}
+
+func TestServer_ErrorMapping_RequestErrors(t *testing.T) {
+	server := newErrorServer(t, nil, WithErrorEncoder(ProblemJSONEncoder))
+
+	t.Run("bind error", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders?limit=ten", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+		var problem ProblemDetails
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		assert.Equal(t, "invalid request parameter", problem.Title)
+		assert.Equal(t, http.StatusBadRequest, problem.Status)
+		assert.Equal(t, "/orders", problem.Instance)
+		assert.Contains(t, problem.Detail, `"limit"`)
+	})
+
+	t.Run("validation error", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewBufferString(`{"name":"ab"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		var problem struct {
+			ProblemDetails
+			Errors []FieldError `json:"errors"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		assert.Equal(t, "validation failed", problem.Title)
+		require.Len(t, problem.Errors, 1)
+		assert.Equal(t, "name", problem.Errors[0].Field)
+	})
+
+	t.Run("invalid request body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewBufferString(`{"name":`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+		var problem ProblemDetails
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		assert.Equal(t, "invalid request body", problem.Title)
+		assert.Equal(t, http.StatusBadRequest, problem.Status)
+		assert.Equal(t, "/orders", problem.Instance)
+		assert.NotEmpty(t, problem.Detail)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		server.Handle("/post-only", NewHandler(http.MethodPost, func(ctx context.Context, req *errorRequest) (*Response, error) {
+			return nil, nil
+		}))
+		server.Handle("/post-only-redirect", NewRedirectHandler(http.MethodPost, func(c *Context) {}))
+
+		for _, path := range []string{"/post-only", "/post-only-redirect"} {
+			rec := httptest.NewRecorder()
+			server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+			assert.Equal(t, http.StatusMethodNotAllowed, rec.Code, path)
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"), path)
+		}
+	})
+
+	t.Run("invalid multipart form", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/upload", bytes.NewBufferString("not multipart")))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	})
+}
+
+func TestProblemJSONEncoder(t *testing.T) {
+	server := newErrorServer(t, &outOfStockError{SKU: "SKU-1"}, WithErrorEncoder(ProblemJSONEncoder))
+
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", nil))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "out of stock",
+		"status": 409,
+		"detail": "SKU-1 is out of stock",
+		"instance": "/orders",
+		"code": "OUT_OF_STOCK",
+		"errors": "SKU-1"
+	}`, rec.Body.String())
+}
+
+func TestWithErrorHandler(t *testing.T) {
+	var handled []error
+	server := newErrorServer(t, errOrderNotFound, WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+		handled = append(handled, err)
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/orders", nil),
+		httptest.NewRequest(http.MethodPost, "/orders?limit=ten", nil),
+		uploadRequest(),
+		httptest.NewRequest(http.MethodGet, "/redirect", nil),
+	} {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusTeapot, rec.Code)
+	}
+
+	require.Len(t, handled, 4)
+	assert.ErrorIs(t, handled[0], errOrderNotFound)
+	var bindErr *BindError
+	assert.ErrorAs(t, handled[1], &bindErr)
+	assert.ErrorIs(t, handled[2], errOrderNotFound)
+	assert.ErrorIs(t, handled[3], errOrderNotFound)
+}
+
+func TestWriteError_WithoutServer(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, httptest.NewRequest(http.MethodGet, "/", nil), errors.New("boom"))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"status":false,"code":"500","message":{"title":"internal server error","desc":"internal server error"},"data":null}`, rec.Body.String())
+}
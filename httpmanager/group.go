@@ -0,0 +1,96 @@
+package httpmanager
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Group registers routes under a shared path prefix with group-scoped middlewares.
+// It is backed by a mux subrouter and registers its routes with their full path, so the route templates,
+// e.g. in the logs, include the prefix.
+//
+// Example:
+//
+//	api := server.Group("/api/v1", authMiddleware)
+//	api.GET("/users/{id}", getUserHandler)        // GET /api/v1/users/{id}
+//
+//	admin := api.Group("/admin", adminOnlyMiddleware)
+//	admin.DELETE("/users/{id}", deleteUserHandler) // DELETE /api/v1/admin/users/{id}
+type Group struct {
+	server      *Server
+	router      *mux.Router
+	prefix      string
+	middlewares []mux.MiddlewareFunc
+}
+
+// Group creates a route group for the path prefix. The middlewares apply to the routes of the group
+// and of its nested groups, after the server middlewares.
+func (s *Server) Group(prefix string, middleware ...mux.MiddlewareFunc) *Group {
+	return &Group{
+		server:      s,
+		router:      newGroupRouter(s.router),
+		prefix:      prefix,
+		middlewares: append([]mux.MiddlewareFunc(nil), middleware...),
+	}
+}
+
+// Group creates a nested route group for the path prefix, relative to the prefix of g.
+// The middlewares apply after the middlewares of g.
+func (g *Group) Group(prefix string, middleware ...mux.MiddlewareFunc) *Group {
+	middlewares := make([]mux.MiddlewareFunc, 0, len(g.middlewares)+len(middleware))
+	middlewares = append(middlewares, g.middlewares...)
+	return &Group{
+		server:      g.server,
+		router:      newGroupRouter(g.router),
+		prefix:      g.prefix + prefix,
+		middlewares: append(middlewares, middleware...),
+	}
+}
+
+// newGroupRouter creates the subrouter of a group. The subrouter has no PathPrefix matcher: mux copies
+// it into every route of the subrouter, where a matching prefix clears the method mismatch of a
+// previous route and turns 405 responses into 404.
+func newGroupRouter(parent *mux.Router) *mux.Router {
+	return parent.NewRoute().Subrouter()
+}
+
+// Use adds middlewares to the group. Like Server.Use, they apply to the routes registered afterwards.
+func (g *Group) Use(middleware ...mux.MiddlewareFunc) {
+	g.middlewares = append(g.middlewares, middleware...)
+}
+
+// Handle registers the handler with the pattern, relative to the group prefix, for all methods.
+func (g *Group) Handle(pattern string, handler http.Handler, opts ...RouteOption) {
+	g.server.handleRoute(g.router, "", g.prefix+pattern, handler, g.middlewares, opts)
+}
+
+// GET registers a GET handler with the pattern, relative to the group prefix.
+func (g *Group) GET(pattern string, handler http.Handler, opts ...RouteOption) {
+	g.handleMethod(http.MethodGet, pattern, handler, opts)
+}
+
+// POST registers a POST handler with the pattern, relative to the group prefix.
+func (g *Group) POST(pattern string, handler http.Handler, opts ...RouteOption) {
+	g.handleMethod(http.MethodPost, pattern, handler, opts)
+}
+
+// PUT registers a PUT handler with the pattern, relative to the group prefix.
+func (g *Group) PUT(pattern string, handler http.Handler, opts ...RouteOption) {
+	g.handleMethod(http.MethodPut, pattern, handler, opts)
+}
+
+// DELETE registers a DELETE handler with the pattern, relative to the group prefix.
+func (g *Group) DELETE(pattern string, handler http.Handler, opts ...RouteOption) {
+	g.handleMethod(http.MethodDelete, pattern, handler, opts)
+}
+
+// PATCH registers a PATCH handler with the pattern, relative to the group prefix.
+func (g *Group) PATCH(pattern string, handler http.Handler, opts ...RouteOption) {
+	g.handleMethod(http.MethodPatch, pattern, handler, opts)
+}
+
+// handleMethod registers the handler for the HTTP method with the full prefix, after the group middlewares.
+func (g *Group) handleMethod(method, pattern string, handler http.Handler, opts []RouteOption) {
+	g.server.handleRoute(g.router, method, g.prefix+pattern, handler, g.middlewares, opts)
+}
@@ -0,0 +1,99 @@
+package httpmanager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SALT-Indonesia/salt-pkg/httpmanager/internal/testdata"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_Group(t *testing.T) {
+	var calls []string
+	record := func(name string) mux.MiddlewareFunc {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	// The handler writes the route template seen by the lmgorilla middleware
+	templateHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		template, _ := mux.CurrentRoute(r).GetPathTemplate()
+		w.Write([]byte(r.Method + " " + template))
+	})
+
+	server := NewServer(testdata.NewApplication())
+	server.Use(record("server"))
+
+	api := server.Group("/api/v1", record("api"))
+	api.GET("/users", templateHandler)
+	api.POST("/users", templateHandler)
+	api.PUT("/users/{id}", templateHandler)
+	api.PATCH("/users/{id}", templateHandler)
+	api.DELETE("/users/{id}", templateHandler)
+	api.Handle("/any", templateHandler)
+
+	admin := api.Group("/admin", record("admin"))
+	admin.Use(record("admin-use"))
+	admin.GET("/users/{id:[0-9]+}", NewHandler(http.MethodGet, func(ctx context.Context, req *struct {
+		ID int `path:"id"`
+	}) (*map[string]int, error) {
+		return &map[string]int{"id": req.ID}, nil
+	}))
+
+	server.GET("/users", templateHandler)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		body   string
+		calls  []string
+	}{
+		{"GET", http.MethodGet, "/api/v1/users", http.StatusOK, "GET /api/v1/users", []string{"server", "api"}},
+		{"POST", http.MethodPost, "/api/v1/users", http.StatusOK, "POST /api/v1/users", []string{"server", "api"}},
+		{"PUT", http.MethodPut, "/api/v1/users/1", http.StatusOK, "PUT /api/v1/users/{id}", []string{"server", "api"}},
+		{"PATCH", http.MethodPatch, "/api/v1/users/1", http.StatusOK, "PATCH /api/v1/users/{id}", []string{"server", "api"}},
+		{"DELETE", http.MethodDelete, "/api/v1/users/1", http.StatusOK, "DELETE /api/v1/users/{id}", []string{"server", "api"}},
+		{"Handle", http.MethodOptions, "/api/v1/any", http.StatusOK, "OPTIONS /api/v1/any", []string{"server", "api"}},
+		{"nested group", http.MethodGet, "/api/v1/admin/users/7", http.StatusOK, `{"id":7}`, []string{"server", "api", "admin", "admin-use"}},
+		{"outside group", http.MethodGet, "/users", http.StatusOK, "GET /users", []string{"server"}},
+		{"nested route pattern", http.MethodGet, "/api/v1/admin/users/abc", http.StatusNotFound, "", nil},
+		{"unknown path in group", http.MethodGet, "/api/v1/orders", http.StatusNotFound, "", nil},
+		{"method not allowed", http.MethodPost, "/api/v1/users/1", http.StatusMethodNotAllowed, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			rec := httptest.NewRecorder()
+			server.router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.body != "" {
+				assert.Equal(t, tt.body, strings.TrimSpace(rec.Body.String()))
+			}
+			assert.Equal(t, tt.calls, calls)
+		})
+	}
+}
+
+func TestServer_Group_OpenAPI(t *testing.T) {
+	server := NewServer(testdata.NewApplication(), WithOpenAPI(OpenAPIConfig{}))
+	users := server.Group("/api/v1").Group("/users")
+	users.GET("/{id:[0-9]+}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), Tags("users"))
+
+	doc := server.openAPIDocument()
+
+	operation := doc.Paths["/api/v1/users/{id}"]["get"]
+	if assert.NotNil(t, operation) {
+		assert.Equal(t, "getApiV1UsersId", operation.OperationID)
+		assert.Equal(t, []string{"users"}, operation.Tags)
+	}
+}
@@ -1,8 +1,10 @@
 package httpmanager
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
 	"github.com/gorilla/mux"
 	"net/http"
@@ -26,9 +28,10 @@ type MessageInfo struct {
 
 // Handler restricts HTTP requests to a specific method
 type Handler[Req any, Resp any] struct {
-	handlerFunc HandlerFunc[Req, Resp]
-	method      string
-	middlewares []mux.MiddlewareFunc
+	handlerFunc    HandlerFunc[Req, Resp]
+	method         string
+	middlewares    []mux.MiddlewareFunc
+	skipValidation bool
 }
 
 // NewHandler creates and returns a new Handler with the specified handler function and HTTP method.
@@ -50,6 +53,17 @@ func (h *Handler[Req, Resp]) Use(middleware ...mux.MiddlewareFunc) *Handler[Req,
 	return h
 }
 
+// WithoutValidation disables the automatic validation of the request `validate` tags for this handler.
+func (h *Handler[Req, Resp]) WithoutValidation() *Handler[Req, Resp] {
+	h.skipValidation = true
+	return h
+}
+
+// openAPITypes returns the method and the request and response types of the handler for the OpenAPI document.
+func (h *Handler[Req, Resp]) openAPITypes() (string, reflect.Type, reflect.Type) {
+	return h.method, reflect.TypeFor[Req](), reflect.TypeFor[Resp]()
+}
+
 // WithMiddleware returns an http.Handler with the middleware applied
 func (h *Handler[Req, Resp]) WithMiddleware() http.Handler {
 	var handler http.Handler = h
@@ -62,10 +76,11 @@ func (h *Handler[Req, Resp]) WithMiddleware() http.Handler {
 	return handler
 }
 
-// ServeHTTP processes incoming HTTP requests, decodes the request body, executes the handler func, and writes a JSON response.
+// ServeHTTP processes incoming HTTP requests, decodes the request body, executes the handler func, and writes
+// the response. The body codecs are selected from the Content-Type and Accept headers, JSON by default.
 func (h *Handler[Req, Resp]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != h.method {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		WriteError(w, r, methodNotAllowedError(r))
 		return
 	}
 
@@ -81,74 +96,96 @@ func (h *Handler[Req, Resp]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx = context.WithValue(ctx, pathParamsKey, pathParams)
 	ctx = context.WithValue(ctx, RequestKey, r)
 
+	// Select the codecs of the request and response bodies before running the handler
+	codecs := codecsFromContext(ctx)
+	encoder, err := codecs.encoder(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
 	var req Req
+	reqValue := reflect.ValueOf(&req).Elem()
+	b := binderFor(reqValue.Type())
+	if err := b.setDefaults(reqValue); err != nil {
+		WriteError(w, r, internalError(err))
+		return
+	}
+
 	if r.Body != nil && r.ContentLength > 0 {
-		decoder := json.NewDecoder(r.Body)
-		if err := decoder.Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+		decoder, err := codecs.decoder(r)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		if err := decoder.Decode(r.Body, &req); err != nil {
+			var bindErr *BindError
+			if errors.As(err, &bindErr) {
+				WriteError(w, r, bindErr)
+				return
+			}
+			WriteError(w, r, invalidBodyError(err))
 			return
 		}
 	}
 
-	resp, err := h.handlerFunc(ctx, &req)
-	if err != nil {
-		// Return error as JSON
-		statusCode := http.StatusInternalServerError
-		errorResp := DetailedErrorResponse{
-			Status: false,
-			Code:   "500",
-			Message: MessageInfo{
-				Title: "unknow error",
-				Desc:  "unknow error",
-			},
-			Data: nil,
-		}
+	// Bind the path, query, header and cookie parameters to the request fields
+	if err := b.bind(r, reqValue, pathParams, queryParams); err != nil {
+		WriteError(w, r, err)
+		return
+	}
 
-		// Check if the error implements the ResponseError interface
-		// We need to check for specific ResponseError types since Go generics don't work well with runtime type checking
-		if isCustomV2, statusCode, body := checkCustomErrorV2(err); isCustomV2 {
-			// Use client-provided custom body and status code
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(statusCode)
-			encoder := json.NewEncoder(w)
-			_ = encoder.Encode(body)
+	// Validate the request against its `validate` tags
+	if !h.skipValidation {
+		if err := validatorFromContext(ctx).check(r, &req); err != nil {
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				WriteError(w, r, validationErr)
+				return
+			}
+			WriteError(w, r, invalidBodyError(err))
 			return
 		}
+	}
 
-		// Check if the error is a CustomError to use client-provided values
-		if detailedErr, ok := IsCustomError(err); ok {
-			// Use client-provided values
-			errorResp.Code = detailedErr.Code
-			errorResp.Message.Title = detailedErr.Title
-			errorResp.Message.Desc = detailedErr.Desc
-			statusCode = detailedErr.StatusCode
-		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(statusCode)
-		encoder := json.NewEncoder(w)
-		_ = encoder.Encode(errorResp)
+	resp, err := h.handlerFunc(ctx, &req)
+	if err != nil {
+		// Write the error with the error handler of the server
+		WriteError(w, r, err)
 		return
 	}
 
 	// Check if the response implements ResponseSuccess for custom status codes
-	if isCustomSuccess, statusCode, body := checkResponseSuccess(resp); isCustomSuccess {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(statusCode)
-		if body != nil {
-			encoder := json.NewEncoder(w)
-			_ = encoder.Encode(body)
-		}
-		return
+	statusCode := http.StatusOK
+	var body any = resp
+	if isCustomSuccess, customStatusCode, customBody := checkResponseSuccess(resp); isCustomSuccess {
+		statusCode, body = customStatusCode, customBody
+	} else if resp == nil {
+		body = nil
 	}
+	writeBody(w, r, encoder, statusCode, body)
+}
 
-	// Default behavior: return 200 OK
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
-	if resp != nil {
-		encoder := json.NewEncoder(w)
-		_ = encoder.Encode(resp)
+// writeBody encodes the body with the codec and writes it with the status code. The body is encoded
+// before the response is started: when the negotiated codec cannot encode it, e.g. a map in XML, the
+// default codec is used, and a failure of the default codec is answered with 500.
+func writeBody(w http.ResponseWriter, r *http.Request, codec Codec, statusCode int, body any) {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := codec.Encode(&buf, body); err != nil {
+			defaultCodec := codecsFromContext(r.Context()).defaultCodec()
+			buf.Reset()
+			if codec.ContentType() == defaultCodec.ContentType() || defaultCodec.Encode(&buf, body) != nil {
+				WriteError(w, r, internalError(fmt.Errorf("encode %s response: %w", codec.ContentType(), err)))
+				return
+			}
+			codec = defaultCodec
+		}
 	}
+
+	w.Header().Set("Content-Type", codec.ContentType())
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(buf.Bytes())
 }
 
 // checkCustomErrorV2 uses reflection to check if an error is a ResponseError of any type
@@ -102,7 +102,7 @@ func TestHandler_ServeHTTP(t *testing.T) {
 			requestBody:    `{"name":"Test"}`,
 			handlerFunc:    func(ctx context.Context, req *Request) (*Response, error) { return &Response{Message: "Hello"}, nil },
 			expectedStatus: http.StatusMethodNotAllowed,
-			expectedBody:   "Method not allowed\n",
+			expectedBody:   `{"status":false,"code":"405","message":{"title":"method not allowed","desc":"method GET is not allowed"},"data":null}`,
 		},
 		{
 			name:           "invalid request body",
@@ -111,7 +111,7 @@ func TestHandler_ServeHTTP(t *testing.T) {
 			requestBody:    `{"name":Test}`, // Invalid JSON
 			handlerFunc:    func(ctx context.Context, req *Request) (*Response, error) { return &Response{Message: "Hello"}, nil },
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "Invalid request body\n",
+			expectedBody:   `{"status":false,"code":"400","message":{"title":"invalid request body","desc":"invalid character 'T' looking for beginning of value"},"data":null}`,
 		},
 		{
 			name:           "handler returns error",
@@ -120,7 +120,7 @@ func TestHandler_ServeHTTP(t *testing.T) {
 			requestBody:    `{"name":"Test"}`,
 			handlerFunc:    func(ctx context.Context, req *Request) (*Response, error) { return nil, errors.New("handler error") },
 			expectedStatus: http.StatusInternalServerError,
-			expectedBody:   `{"status":false,"code":"500","message":{"title":"unknow error","desc":"unknow error"},"data":null}`,
+			expectedBody:   `{"status":false,"code":"500","message":{"title":"internal server error","desc":"internal server error"},"data":null}`,
 		},
 		{
 			name:           "successful request with nil response",
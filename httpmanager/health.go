@@ -0,0 +1,238 @@
+package httpmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHealthCheckTimeout = 5 * time.Second
+
+	healthStatusOK           = "ok"
+	healthStatusDegraded     = "degraded"
+	healthStatusFail         = "fail"
+	healthStatusShuttingDown = "shutting_down"
+)
+
+// HealthCheckFunc checks a dependency of the server, such as a database or an upstream API. A non-nil
+// error marks it unhealthy. The method value of a (*sql.DB).PingContext is a HealthCheckFunc.
+type HealthCheckFunc func(ctx context.Context) error
+
+// HealthCheck is a named check of the readiness endpoint, and of the liveness endpoint when Liveness is set.
+type HealthCheck struct {
+	// Name identifies the check in the JSON of the endpoints.
+	Name string
+	// Check is run with a context bounded by Timeout, not canceled when the probe request is.
+	Check HealthCheckFunc
+	// Timeout bounds each run of the check. Defaults to 5 seconds.
+	Timeout time.Duration
+	// Critical fails the endpoints with 503 when the check fails. A failing non-critical check is reported,
+	// with the status "degraded", without failing them.
+	Critical bool
+	// Liveness adds the check to the liveness endpoint, for failures only a restart recovers from, such as a
+	// deadlocked worker. Every check is part of the readiness endpoint.
+	Liveness bool
+}
+
+// healthCheckResult is the JSON of the result of a check.
+type healthCheckResult struct {
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	Duration  float64   `json:"duration_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// healthResponse is the JSON of the liveness and readiness endpoints.
+type healthResponse struct {
+	Status string                       `json:"status"`
+	Checks map[string]healthCheckResult `json:"checks,omitempty"`
+}
+
+// healthCheckState is a registered check with its last result, reused while it is fresh.
+type healthCheckState struct {
+	HealthCheck
+	mu     sync.Mutex
+	result healthCheckResult
+}
+
+// healthRegistry keeps the checks of the server.
+type healthRegistry struct {
+	mu       sync.RWMutex
+	checks   []*healthCheckState
+	cacheTTL time.Duration
+}
+
+func newHealthRegistry(cacheTTL time.Duration) *healthRegistry {
+	return &healthRegistry{cacheTTL: cacheTTL}
+}
+
+// AddHealthCheck registers a check of the readiness endpoint, and of the liveness endpoint when
+// check.Liveness is set. It panics when the check has no name or func, or when its name is taken.
+//
+// Example:
+//
+//	server.AddHealthCheck(httpmanager.HealthCheck{Name: "database", Check: db.PingContext, Critical: true})
+//	server.AddHealthCheck(httpmanager.HealthCheck{
+//	    Name:    "payment-api",
+//	    Check:   httpmanager.HTTPHealthCheck(nil, "https://payment.internal/health"),
+//	    Timeout: 2 * time.Second,
+//	})
+func (s *Server) AddHealthCheck(check HealthCheck) {
+	if check.Name == "" {
+		panic("health check name must not be empty")
+	}
+	if check.Check == nil {
+		panic("health check func must not be nil")
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = defaultHealthCheckTimeout
+	}
+
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	for _, registered := range s.health.checks {
+		if registered.Name == check.Name {
+			panic(fmt.Sprintf("health check %q is already registered", check.Name))
+		}
+	}
+	s.health.checks = append(s.health.checks, &healthCheckState{HealthCheck: check})
+}
+
+// HTTPHealthCheck returns a check sending a GET request to url, healthy when the response status is below
+// 400. A nil client uses http.DefaultClient.
+func HTTPHealthCheck(client *http.Client, url string) HealthCheckFunc {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = res.Body.Close()
+		if res.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("%s answered %d", url, res.StatusCode)
+		}
+		return nil
+	}
+}
+
+// run returns the result of the check, running it when the last result is older than ttl. Concurrent
+// probes wait for the same run. The check is not canceled with the probe, so a client going away neither
+// fails the check nor has its failure cached.
+func (c *healthCheckState) run(ctx context.Context, ttl time.Duration) healthCheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.result.CheckedAt.IsZero() && time.Since(c.result.CheckedAt) < ttl {
+		return c.result
+	}
+
+	checkCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.Timeout)
+	start := time.Now()
+
+	// The check runs in its own goroutine so one ignoring its context cannot block the probe. It keeps its
+	// context until it returns or times out, even when the probe is gone. The timeout has its own timer:
+	// the context is canceled once the check returns, which must not read as a timeout.
+	done := make(chan error, 1)
+	go func() {
+		defer cancel()
+		done <- c.Check(checkCtx)
+	}()
+	timeout := time.NewTimer(c.Timeout)
+	defer timeout.Stop()
+	var err error
+	cached := true
+	select {
+	case err = <-done:
+	case <-timeout.C:
+		err = fmt.Errorf("timed out after %s", c.Timeout)
+	case <-ctx.Done():
+		err, cached = ctx.Err(), false
+	}
+
+	result := healthCheckResult{
+		Status:    healthStatusOK,
+		Critical:  c.Critical,
+		Duration:  float64(time.Since(start).Microseconds()) / 1000,
+		CheckedAt: start.UTC(),
+	}
+	if err != nil {
+		result.Status = healthStatusFail
+		result.Error = err.Error()
+	}
+	if cached {
+		c.result = result
+	}
+	return result
+}
+
+// evaluate runs the checks concurrently, only the liveness checks when liveness is set, and returns the
+// response and its status code
+func (h *healthRegistry) evaluate(ctx context.Context, liveness bool) (healthResponse, int) {
+	h.mu.RLock()
+	var checks []*healthCheckState
+	for _, check := range h.checks {
+		if !liveness || check.Liveness {
+			checks = append(checks, check)
+		}
+	}
+	h.mu.RUnlock()
+
+	results := make([]healthCheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = check.run(ctx, h.cacheTTL)
+		}()
+	}
+	wg.Wait()
+
+	response := healthResponse{Status: healthStatusOK}
+	statusCode := http.StatusOK
+	if len(checks) > 0 {
+		response.Checks = make(map[string]healthCheckResult, len(checks))
+	}
+	for i, check := range checks {
+		response.Checks[check.Name] = results[i]
+		switch {
+		case results[i].Status == healthStatusOK:
+		case check.Critical:
+			response.Status, statusCode = healthStatusFail, http.StatusServiceUnavailable
+		case response.Status == healthStatusOK:
+			response.Status = healthStatusDegraded
+		}
+	}
+	return response, statusCode
+}
+
+// registerHealthProbes registers the liveness and readiness endpoints on the server. Readiness fails
+// without running the checks once the server is shutting down; liveness does not, the process is still alive.
+func (s *Server) registerHealthProbes() {
+	probe := func(liveness bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			response, statusCode := healthResponse{Status: healthStatusShuttingDown}, http.StatusServiceUnavailable
+			if liveness || !s.shuttingDown.Load() {
+				response, statusCode = s.health.evaluate(r.Context(), liveness)
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(statusCode)
+			_ = json.NewEncoder(w).Encode(response)
+		}
+	}
+
+	s.router.Handle(s.livenessPath, probe(true)).Methods(http.MethodGet)
+	s.router.Handle(s.readinessPath, probe(false)).Methods(http.MethodGet)
+}
@@ -0,0 +1,208 @@
+package httpmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/httpmanager/internal/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func probeHealth(t *testing.T, server *Server, path string) (int, healthResponse) {
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+	var response healthResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response), rr.Body.String())
+	return rr.Code, response
+}
+
+func TestServer_HealthProbes(t *testing.T) {
+	server := NewServer(testdata.NewApplication(), WithHealthCheckCacheTTL(0))
+
+	code, response := probeHealth(t, server, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, healthResponse{Status: "ok"}, response, "no checks registered")
+
+	var dbErr, cacheErr atomic.Value
+	dbErr.Store(errors.New(""))
+	cacheErr.Store(errors.New(""))
+	check := func(v *atomic.Value) HealthCheckFunc {
+		return func(context.Context) error {
+			if err := v.Load().(error); err.Error() != "" {
+				return err
+			}
+			return nil
+		}
+	}
+	server.AddHealthCheck(HealthCheck{Name: "database", Check: check(&dbErr), Critical: true})
+	server.AddHealthCheck(HealthCheck{Name: "cache", Check: check(&cacheErr)})
+	server.AddHealthCheck(HealthCheck{Name: "worker", Check: func(context.Context) error { return nil }, Critical: true, Liveness: true})
+
+	code, response = probeHealth(t, server, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", response.Status)
+	require.Len(t, response.Checks, 3)
+	assert.Equal(t, "ok", response.Checks["database"].Status)
+	assert.True(t, response.Checks["database"].Critical)
+	assert.False(t, response.Checks["database"].CheckedAt.IsZero())
+
+	cacheErr.Store(errors.New("connection refused"))
+	code, response = probeHealth(t, server, "/readyz")
+	assert.Equal(t, http.StatusOK, code, "a failing non-critical check does not fail readiness")
+	assert.Equal(t, "degraded", response.Status)
+	assert.Equal(t, "fail", response.Checks["cache"].Status)
+	assert.Equal(t, "connection refused", response.Checks["cache"].Error)
+
+	dbErr.Store(errors.New("database is down"))
+	code, response = probeHealth(t, server, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "fail", response.Status)
+	assert.Equal(t, "database is down", response.Checks["database"].Error)
+
+	code, response = probeHealth(t, server, "/livez")
+	assert.Equal(t, http.StatusOK, code, "dependencies do not fail liveness")
+	assert.Equal(t, "ok", response.Status)
+	assert.Len(t, response.Checks, 1)
+	assert.Contains(t, response.Checks, "worker")
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "the health check does not run the checks")
+}
+
+func TestServer_HealthProbes_ShuttingDown(t *testing.T) {
+	server := NewServer(testdata.NewApplication())
+	var runs atomic.Int32
+	server.AddHealthCheck(HealthCheck{Name: "database", Check: func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}, Critical: true, Liveness: true})
+
+	server.shuttingDown.Store(true)
+	code, response := probeHealth(t, server, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthResponse{Status: "shutting_down"}, response)
+	assert.Zero(t, runs.Load(), "the checks do not run during shutdown")
+
+	code, _ = probeHealth(t, server, "/livez")
+	assert.Equal(t, http.StatusOK, code, "the process is alive during shutdown")
+}
+
+func TestServer_HealthProbes_CacheAndTimeout(t *testing.T) {
+	server := NewServer(testdata.NewApplication(), WithHealthCheckCacheTTL(time.Hour),
+		WithLivenessPath("/internal/live"), WithReadinessPath("/internal/ready"))
+
+	var runs atomic.Int32
+	server.AddHealthCheck(HealthCheck{Name: "upstream", Check: func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}, Liveness: true})
+	release := make(chan struct{})
+	defer close(release)
+	server.AddHealthCheck(HealthCheck{Name: "stuck", Timeout: 20 * time.Millisecond, Critical: true, Check: func(context.Context) error {
+		<-release // ignores its context
+		return nil
+	}})
+
+	start := time.Now()
+	code, response := probeHealth(t, server, "/internal/ready")
+	assert.Less(t, time.Since(start), time.Second, "a check ignoring its context does not block the probe")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "timed out after 20ms", response.Checks["stuck"].Error)
+	assert.Positive(t, response.Checks["stuck"].Duration)
+
+	probeHealth(t, server, "/internal/ready")
+	probeHealth(t, server, "/internal/live")
+	assert.Equal(t, int32(1), runs.Load(), "fresh results are reused")
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestHealthCheckState_CallerCanceled(t *testing.T) {
+	var runs atomic.Int32
+	var checkErr atomic.Value
+	release := make(chan struct{})
+	check := &healthCheckState{HealthCheck: HealthCheck{Name: "database", Timeout: time.Second, Check: func(ctx context.Context) error {
+		if runs.Add(1) == 1 {
+			<-release
+		}
+		checkErr.Store(fmt.Sprint(ctx.Err()))
+		return ctx.Err()
+	}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := check.run(ctx, time.Hour)
+	assert.Equal(t, healthStatusFail, result.Status)
+	assert.Equal(t, context.Canceled.Error(), result.Error)
+	close(release)
+	assert.Eventually(t, func() bool { return checkErr.Load() != nil }, time.Second, time.Millisecond)
+	assert.Equal(t, "<nil>", checkErr.Load(), "the check is not canceled with the probe")
+
+	result = check.run(context.Background(), time.Hour)
+	assert.Equal(t, healthStatusOK, result.Status, "the result of a canceled probe is not cached")
+	assert.Equal(t, int32(2), runs.Load())
+}
+
+func TestHealthCheckState_InstantCheck(t *testing.T) {
+	// The check must be able to return before the probe waits for it
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	check := &healthCheckState{HealthCheck: HealthCheck{Name: "broker", Timeout: time.Second, Check: func(context.Context) error {
+		return nil
+	}}}
+
+	for i := 0; i < 2000; i++ {
+		result := check.run(context.Background(), 0)
+		require.Equal(t, healthStatusOK, result.Status, "run %d: %s", i, result.Error)
+	}
+}
+
+func TestServer_HealthProbes_Disabled(t *testing.T) {
+	server := NewServer(testdata.NewApplication(), WithoutHealthCheck())
+	for _, path := range []string{"/livez", "/readyz"} {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code, path)
+	}
+}
+
+func TestServer_AddHealthCheck_Invalid(t *testing.T) {
+	server := NewServer(testdata.NewApplication())
+	ok := func(context.Context) error { return nil }
+	server.AddHealthCheck(HealthCheck{Name: "database", Check: ok})
+
+	assert.PanicsWithValue(t, "health check name must not be empty", func() { server.AddHealthCheck(HealthCheck{Check: ok}) })
+	assert.PanicsWithValue(t, "health check func must not be nil", func() { server.AddHealthCheck(HealthCheck{Name: "cache"}) })
+	assert.PanicsWithValue(t, `health check "database" is already registered`, func() { server.AddHealthCheck(HealthCheck{Name: "database", Check: ok}) })
+}
+
+func TestHTTPHealthCheck(t *testing.T) {
+	status := http.StatusOK
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer upstream.Close()
+
+	check := HTTPHealthCheck(nil, upstream.URL)
+	assert.NoError(t, check(context.Background()))
+
+	status = http.StatusBadGateway
+	assert.EqualError(t, check(context.Background()), upstream.URL+" answered 502")
+
+	upstream.Close()
+	assert.Error(t, check(context.Background()))
+}
@@ -0,0 +1,132 @@
+package httpmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/google/uuid"
+)
+
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// OnShutdown registers a hook that Run calls after the in-flight requests are drained, such as
+// closing a database or flushing exporters. Hooks run in registration order; a failing hook does
+// not stop the following ones. Each hook gets a context bounded by WithShutdownTimeout.
+func (s *Server) OnShutdown(name string, hook func(ctx context.Context) error) {
+	s.shutdownHooks = append(s.shutdownHooks, shutdownHook{name: name, fn: hook})
+}
+
+// Run starts the server and blocks until ctx is canceled or the process receives SIGINT or SIGTERM.
+// It then shuts down gracefully:
+//
+//  1. the health check and the readiness endpoint start failing with 503,
+//  2. the server keeps serving for the WithPreStopDelay duration, so load balancers can deregister it,
+//  3. in-flight requests are drained within WithShutdownTimeout, remaining connections are closed after it,
+//  4. the OnShutdown hooks run in registration order.
+//
+// Every phase is logged through logmanager with its duration. A second signal during the shutdown
+// terminates the process.
+//
+// Example:
+//
+//	server := httpmanager.NewServer(app, httpmanager.WithPreStopDelay(5*time.Second))
+//	server.OnShutdown("database", func(ctx context.Context) error {
+//	    return db.Close()
+//	})
+//	if err := server.Run(context.Background()); err != nil {
+//	    log.Fatal(err)
+//	}
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.checkStart(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Start()
+	}()
+
+	select {
+	case err := <-serveErr:
+		stop()
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		return errors.Join(err, s.shutdown(nil))
+	case <-ctx.Done():
+		// A second signal during the shutdown terminates the process
+		stop()
+		return s.shutdown(serveErr)
+	}
+}
+
+// shutdown runs the shutdown phases. serveErr is nil when the server already stopped serving.
+func (s *Server) shutdown(serveErr <-chan error) error {
+	txn := s.app.Start(uuid.NewString(), "shutdown", logmanager.TxnTypeOther)
+	defer txn.End()
+	ctx := txn.ToContext(context.Background())
+	start := time.Now()
+
+	s.shuttingDown.Store(true)
+	logmanager.InfoWithContext(ctx, "server shutdown started", map[string]string{"phase": "started"})
+
+	var errs []error
+	if serveErr != nil {
+		if s.preStopDelay > 0 {
+			phaseStart := time.Now()
+			time.Sleep(s.preStopDelay)
+			logPhase(ctx, "pre_stop", phaseStart)
+		}
+
+		phaseStart := time.Now()
+		drainCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+		err := s.Stop(drainCtx)
+		cancel()
+		if err != nil {
+			_ = s.server.Close()
+			err = fmt.Errorf("drain connections: %w", err)
+			logmanager.ErrorWithContext(ctx, err)
+			errs = append(errs, err)
+		}
+		if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, err)
+		}
+		logPhase(ctx, "drain", phaseStart)
+	}
+
+	for _, hook := range s.shutdownHooks {
+		phaseStart := time.Now()
+		hookCtx, cancel := context.WithTimeout(ctx, s.shutdownTimeout)
+		err := hook.fn(hookCtx)
+		cancel()
+		if err != nil {
+			err = fmt.Errorf("shutdown hook %q: %w", hook.name, err)
+			logmanager.ErrorWithContext(ctx, err)
+			errs = append(errs, err)
+		}
+		logPhase(ctx, "hook:"+hook.name, phaseStart)
+	}
+
+	logPhase(ctx, "completed", start)
+	return errors.Join(errs...)
+}
+
+func logPhase(ctx context.Context, phase string, start time.Time) {
+	logmanager.InfoWithContext(ctx, "server shutdown phase finished", map[string]string{
+		"phase":    phase,
+		"duration": time.Since(start).String(),
+	})
+}
@@ -0,0 +1,191 @@
+package httpmanager
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/httpmanager/internal/testdata"
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runServer runs the server in the background and returns the channel receiving the Run result.
+func runServer(t *testing.T, ctx context.Context, server *Server) <-chan error {
+	t.Helper()
+	server.EnableCORS(nil, nil, nil, false)
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Run(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", server.server.Addr)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+	return done
+}
+
+func TestServer_Run(t *testing.T) {
+	app := logmanager.NewTestableApplication()
+	addr := freeAddr(t)
+	server := NewServer(app.Application,
+		WithAddr(addr),
+		WithPreStopDelay(200*time.Millisecond),
+		WithShutdownTimeout(2*time.Second),
+	)
+
+	started := make(chan struct{})
+	server.GET("/slow", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		_, _ = w.Write([]byte("done"))
+	}))
+
+	var hooks []string
+	server.OnShutdown("database", func(ctx context.Context) error {
+		hooks = append(hooks, "database")
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+		return nil
+	})
+	server.OnShutdown("exporter", func(context.Context) error {
+		hooks = append(hooks, "exporter")
+		return errors.New("flush failed")
+	})
+	server.OnShutdown("cache", func(context.Context) error {
+		hooks = append(hooks, "cache")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runServer(t, ctx, server)
+
+	inFlight := make(chan string, 1)
+	go func() {
+		res, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			inFlight <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		inFlight <- string(body)
+	}()
+	<-started
+	cancel()
+
+	// The health check fails during the pre-stop delay while requests are still served.
+	require.Eventually(t, func() bool {
+		res, err := http.Get("http://" + addr + "/health")
+		if err != nil {
+			return false
+		}
+		_ = res.Body.Close()
+		return res.StatusCode == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, "done", <-inFlight)
+
+	err := <-done
+	assert.ErrorContains(t, err, `shutdown hook "exporter": flush failed`)
+	assert.Equal(t, []string{"database", "exporter", "cache"}, hooks)
+
+	_, err = net.Dial("tcp", addr)
+	assert.Error(t, err)
+
+	var phases []string
+	for _, entry := range app.GetLoggedEntries() {
+		if phase, ok := entry.Data["phase"].(string); ok {
+			phases = append(phases, phase)
+			if phase != "started" {
+				assert.NotEmpty(t, entry.Data["duration"])
+			}
+		}
+	}
+	assert.Equal(t, []string{"started", "pre_stop", "drain", "hook:database", "hook:exporter", "hook:cache", "completed"}, phases)
+}
+
+func TestServer_Run_DrainTimeout(t *testing.T) {
+	addr := freeAddr(t)
+	server := NewServer(testdata.NewApplication(), WithAddr(addr), WithShutdownTimeout(50*time.Millisecond))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	server.GET("/stuck", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runServer(t, ctx, server)
+
+	go func() {
+		res, err := http.Get("http://" + addr + "/stuck")
+		if err == nil {
+			_ = res.Body.Close()
+		}
+	}()
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after the shutdown timeout")
+	}
+}
+
+func TestServer_Run_Signal(t *testing.T) {
+	server := NewServer(testdata.NewApplication(), WithAddr(freeAddr(t)))
+	done := runServer(t, context.Background(), server)
+
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after SIGTERM")
+	}
+}
+
+func TestServer_Run_Errors(t *testing.T) {
+	t.Run("without CORS setup", func(t *testing.T) {
+		server := NewServer(testdata.NewApplication())
+
+		assert.ErrorContains(t, server.Run(context.Background()), "CORS middleware is not set")
+	})
+
+	t.Run("listen error still runs hooks", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		server := NewServer(testdata.NewApplication(), WithAddr(listener.Addr().String()))
+		server.EnableCORS(nil, nil, nil, false)
+		var closed bool
+		server.OnShutdown("database", func(context.Context) error {
+			closed = true
+			return nil
+		})
+
+		err = server.Run(context.Background())
+
+		assert.ErrorContains(t, err, "address already in use")
+		assert.True(t, closed)
+	})
+}
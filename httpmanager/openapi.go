@@ -0,0 +1,544 @@
+package httpmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIConfig configures the OpenAPI document served by WithOpenAPI.
+type OpenAPIConfig struct {
+	// Title of the API. Defaults to "API".
+	Title string
+	// Version of the API. Defaults to "1.0.0".
+	Version string
+	// Description of the API, Markdown is supported.
+	Description string
+	// Servers are the base URLs of the API, e.g. "https://api.example.com".
+	Servers []string
+	// SecuritySchemes are the authentication schemes referenced by the Security route option.
+	SecuritySchemes map[string]SecurityScheme
+	// JSONPath serves the document as JSON. Defaults to "/openapi.json".
+	JSONPath string
+	// YAMLPath serves the document as YAML. Defaults to "/openapi.yaml".
+	YAMLPath string
+	// DocsPath serves a Swagger UI page for the document, e.g. "/docs". Disabled when empty.
+	DocsPath string
+	// DocsAssets locates the Swagger UI files of the docs page. Defaults to swagger-ui-dist
+	// SwaggerUIVersion on unpkg.
+	DocsAssets SwaggerUIAssets
+}
+
+// SwaggerUIVersion is the swagger-ui-dist version the docs page loads by default.
+const SwaggerUIVersion = "5.17.14"
+
+// SwaggerUIAssets locates swagger-ui.css and swagger-ui-bundle.js, e.g. on a CDN mirror or a path of the
+// server for air-gapped deployments.
+type SwaggerUIAssets struct {
+	// BaseURL serves the files, e.g. "/static/swagger-ui". Defaults to
+	// "https://unpkg.com/swagger-ui-dist@" + SwaggerUIVersion.
+	BaseURL string
+	// CSSIntegrity and BundleIntegrity are the Subresource Integrity hashes of the files, e.g.
+	// "sha384-...". The browser refuses a file that does not match. Not checked when empty.
+	CSSIntegrity    string
+	BundleIntegrity string
+}
+
+// SecurityScheme is an OpenAPI security scheme, e.g. {Type: "http", Scheme: "bearer", BearerFormat: "JWT"}
+// or {Type: "apiKey", In: "header", Name: "X-API-Key"}.
+type SecurityScheme struct {
+	Type             string `json:"type"`
+	Description      string `json:"description,omitempty"`
+	Scheme           string `json:"scheme,omitempty"`
+	BearerFormat     string `json:"bearerFormat,omitempty"`
+	In               string `json:"in,omitempty"`
+	Name             string `json:"name,omitempty"`
+	OpenIDConnectURL string `json:"openIdConnectUrl,omitempty"`
+}
+
+// RouteOption sets the OpenAPI metadata, or the CORS policy, of a route when it is registered.
+//
+// Example:
+//
+//	server.GET("/users/{id}", getUserHandler,
+//	    httpmanager.Summary("Get a user"),
+//	    httpmanager.Tags("users"),
+//	    httpmanager.Security("bearer"),
+//	    httpmanager.ErrorResponse(http.StatusNotFound, NotFoundBody{}),
+//	)
+type RouteOption func(*routeDoc)
+
+type routeDoc struct {
+	summary       string
+	description   string
+	operationID   string
+	tags          []string
+	security      []map[string][]string
+	deprecated    bool
+	hidden        bool
+	successStatus int
+	errors        map[int]reflect.Type
+	cors          *corsPolicy
+}
+
+// Summary sets the short summary of the route.
+func Summary(summary string) RouteOption {
+	return func(d *routeDoc) {
+		d.summary = summary
+	}
+}
+
+// Description sets the description of the route, Markdown is supported.
+func Description(description string) RouteOption {
+	return func(d *routeDoc) {
+		d.description = description
+	}
+}
+
+// OperationID sets the operation ID of the route. Defaults to the method and path, e.g. "getUsersId".
+func OperationID(id string) RouteOption {
+	return func(d *routeDoc) {
+		d.operationID = id
+	}
+}
+
+// Tags groups the route under the given tags in the docs UI.
+func Tags(tags ...string) RouteOption {
+	return func(d *routeDoc) {
+		d.tags = append(d.tags, tags...)
+	}
+}
+
+// Security requires the security scheme, declared in OpenAPIConfig.SecuritySchemes, for the route.
+// Several Security options are alternatives.
+func Security(scheme string, scopes ...string) RouteOption {
+	return func(d *routeDoc) {
+		if scopes == nil {
+			scopes = []string{}
+		}
+		d.security = append(d.security, map[string][]string{scheme: scopes})
+	}
+}
+
+// Deprecated marks the route as deprecated.
+func Deprecated() RouteOption {
+	return func(d *routeDoc) {
+		d.deprecated = true
+	}
+}
+
+// Hidden leaves the route out of the OpenAPI document.
+func Hidden() RouteOption {
+	return func(d *routeDoc) {
+		d.hidden = true
+	}
+}
+
+// SuccessStatus sets the status code of the successful response, e.g. http.StatusCreated for
+// handlers returning ResponseSuccess. Defaults to 200.
+func SuccessStatus(statusCode int) RouteOption {
+	return func(d *routeDoc) {
+		d.successStatus = statusCode
+	}
+}
+
+// ErrorResponse declares an error response of the route with the type of its body, e.g. the T
+// of the ResponseError[T] returned by the handler.
+func ErrorResponse(statusCode int, body any) RouteOption {
+	return func(d *routeDoc) {
+		if d.errors == nil {
+			d.errors = map[int]reflect.Type{}
+		}
+		d.errors[statusCode] = reflect.TypeOf(body)
+	}
+}
+
+// documentedHandler is implemented by Handler to describe its route in the OpenAPI document.
+type documentedHandler interface {
+	openAPITypes() (method string, req, resp reflect.Type)
+}
+
+// route is a registered route recorded for the OpenAPI document.
+type route struct {
+	method  string
+	pattern string
+	handler http.Handler
+	doc     routeDoc
+}
+
+// addRoute records a route. Routes registered without a method are recorded for typed handlers only.
+func (s *Server) addRoute(method, pattern string, handler http.Handler, doc routeDoc) {
+	if method == "" {
+		typed, ok := handler.(documentedHandler)
+		if !ok {
+			return
+		}
+		method, _, _ = typed.openAPITypes()
+	}
+
+	s.routes = append(s.routes, route{method: method, pattern: pattern, handler: handler, doc: doc})
+}
+
+// OpenAPI document types, see https://spec.openapis.org/oas/v3.1.0
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Servers    []openAPIServer                         `json:"servers,omitempty"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIOperation struct {
+	Tags        []string                    `json:"tags,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	OperationID string                      `json:"operationId"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required,omitempty"`
+	Style    string      `json:"style,omitempty"`
+	Explode  *bool       `json:"explode,omitempty"`
+	Schema   *jsonSchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIMediaType struct {
+	Schema *jsonSchema `json:"schema"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*jsonSchema    `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// muxVariable matches the variables of a gorilla/mux path template, e.g. {id} or {id:[0-9]+}.
+var muxVariable = regexp.MustCompile(`\{([^{}:]+)(?::((?:[^{}]|\{[^{}]*\})+))?\}`)
+
+// openAPIDocument builds the OpenAPI document of the registered routes.
+func (s *Server) openAPIDocument() *openAPIDocument {
+	config := OpenAPIConfig{}
+	if s.openAPI != nil {
+		config = *s.openAPI
+	}
+	doc := &openAPIDocument{
+		OpenAPI: "3.1.0",
+		Info: openAPIInfo{
+			Title:       config.Title,
+			Version:     config.Version,
+			Description: config.Description,
+		},
+		Paths: map[string]map[string]*openAPIOperation{},
+		Components: openAPIComponents{
+			SecuritySchemes: config.SecuritySchemes,
+		},
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = "API"
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "1.0.0"
+	}
+	for _, url := range config.Servers {
+		doc.Servers = append(doc.Servers, openAPIServer{URL: url})
+	}
+
+	schemas := newSchemaRegistry()
+	for _, r := range s.routes {
+		if r.doc.hidden {
+			continue
+		}
+		path, patterns := openAPIPath(r.pattern)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*openAPIOperation{}
+		}
+		doc.Paths[path][strings.ToLower(r.method)] = s.openAPIOperation(r, path, patterns, schemas)
+	}
+	doc.Components.Schemas = schemas.schemas
+	return doc
+}
+
+// openAPIOperation describes a route.
+func (s *Server) openAPIOperation(r route, path string, patterns map[string]string, schemas *schemaRegistry) *openAPIOperation {
+	op := &openAPIOperation{
+		Tags:        r.doc.tags,
+		Summary:     r.doc.summary,
+		Description: r.doc.description,
+		OperationID: r.doc.operationID,
+		Security:    r.doc.security,
+		Deprecated:  r.doc.deprecated,
+		Responses:   map[string]*openAPIResponse{},
+	}
+	if op.OperationID == "" {
+		op.OperationID = operationID(r.method, path)
+	}
+
+	successStatus := http.StatusOK
+	if r.doc.successStatus != 0 {
+		successStatus = r.doc.successStatus
+	}
+	success := &openAPIResponse{Description: http.StatusText(successStatus)}
+	op.Responses[strconv.Itoa(successStatus)] = success
+
+	if typed, ok := r.handler.(documentedHandler); ok {
+		_, reqType, respType := typed.openAPITypes()
+
+		var body *jsonSchema
+		op.Parameters, body = schemas.request(reqType)
+		if body != nil && r.method != http.MethodGet && r.method != http.MethodDelete && r.method != http.MethodHead {
+			op.RequestBody = &openAPIRequestBody{
+				Required: true,
+				Content:  s.bodyContent(body),
+			}
+		}
+
+		if respType = responseBodyType(respType); respType != nil {
+			success.Content = s.bodyContent(schemas.schema(respType))
+		}
+
+		errorSchema := schemas.schema(reflect.TypeOf(DetailedErrorResponse{}))
+		if len(op.Parameters) > 0 || op.RequestBody != nil {
+			op.Responses["400"] = &openAPIResponse{
+				Description: "Invalid request parameters, body or validation failure",
+				Content:     map[string]openAPIMediaType{"application/json": {Schema: errorSchema}},
+			}
+		}
+		op.Responses["500"] = &openAPIResponse{
+			Description: http.StatusText(http.StatusInternalServerError),
+			Content:     map[string]openAPIMediaType{"application/json": {Schema: errorSchema}},
+		}
+	}
+
+	// Path variables without a path tagged request field
+	for _, name := range pathVariables(r.pattern) {
+		if hasParameter(op.Parameters, name, bindPath) {
+			continue
+		}
+		op.Parameters = append(op.Parameters, openAPIParameter{Name: name, In: bindPath, Schema: &jsonSchema{Type: "string"}})
+	}
+	for i, param := range op.Parameters {
+		if param.In != bindPath {
+			continue
+		}
+		op.Parameters[i].Required = true
+		if pattern := patterns[param.Name]; pattern != "" && param.Schema.Ref == "" {
+			param.Schema.Pattern = "^" + pattern + "$"
+		}
+	}
+
+	statusCodes := make([]int, 0, len(r.doc.errors))
+	for statusCode := range r.doc.errors {
+		statusCodes = append(statusCodes, statusCode)
+	}
+	sort.Ints(statusCodes)
+	for _, statusCode := range statusCodes {
+		response := &openAPIResponse{Description: http.StatusText(statusCode)}
+		if bodyType := r.doc.errors[statusCode]; bodyType != nil {
+			response.Content = map[string]openAPIMediaType{"application/json": {Schema: schemas.schema(bodyType)}}
+		}
+		op.Responses[strconv.Itoa(statusCode)] = response
+	}
+	return op
+}
+
+// bodyContent describes a request or response body in the content types of the server codecs.
+func (s *Server) bodyContent(schema *jsonSchema) map[string]openAPIMediaType {
+	content := map[string]openAPIMediaType{}
+	for _, contentType := range s.codecs.contentTypes() {
+		content[contentType] = openAPIMediaType{Schema: schema}
+	}
+	return content
+}
+
+// responseBodyType returns the type of the JSON body of a handler response, unwrapping
+// ResponseSuccess, or nil for empty structs.
+func responseBodyType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Struct && t.PkgPath() == reflect.TypeOf(Server{}).PkgPath() && strings.HasPrefix(t.Name(), "ResponseSuccess[") {
+		field, _ := t.FieldByName("Body")
+		t = field.Type
+	}
+	if t.Kind() == reflect.Struct && t.NumField() == 0 {
+		return nil
+	}
+	return t
+}
+
+// openAPIPath converts a gorilla/mux path template to an OpenAPI path, returning the patterns of the variables.
+func openAPIPath(pattern string) (string, map[string]string) {
+	patterns := map[string]string{}
+	path := muxVariable.ReplaceAllStringFunc(pattern, func(variable string) string {
+		match := muxVariable.FindStringSubmatch(variable)
+		if match[2] != "" {
+			patterns[match[1]] = match[2]
+		}
+		return "{" + match[1] + "}"
+	})
+	return path, patterns
+}
+
+func pathVariables(pattern string) []string {
+	var names []string
+	for _, match := range muxVariable.FindAllStringSubmatch(pattern, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+func hasParameter(params []openAPIParameter, name, in string) bool {
+	for _, param := range params {
+		if param.Name == name && param.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+// operationID returns the default operation ID of a route, e.g. "getUsersId" for GET /users/{id}.
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, word := range strings.FieldsFunc(path, func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+// OpenAPIJSON returns the OpenAPI document of the registered routes as JSON.
+func (s *Server) OpenAPIJSON() ([]byte, error) {
+	return json.MarshalIndent(s.openAPIDocument(), "", "  ")
+}
+
+// OpenAPIYAML returns the OpenAPI document of the registered routes as YAML.
+func (s *Server) OpenAPIYAML() ([]byte, error) {
+	data, err := json.Marshal(s.openAPIDocument())
+	if err != nil {
+		return nil, err
+	}
+	// JSON is valid YAML: decoding it to a node keeps the order of the keys
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	resetYAMLStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// resetYAMLStyle switches the nodes decoded from JSON to the block style.
+func resetYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetYAMLStyle(child)
+	}
+}
+
+// registerOpenAPI registers the endpoints serving the OpenAPI document and the docs UI.
+func (s *Server) registerOpenAPI() {
+	if s.openAPI.JSONPath == "" {
+		s.openAPI.JSONPath = "/openapi.json"
+	}
+	if s.openAPI.YAMLPath == "" {
+		s.openAPI.YAMLPath = "/openapi.yaml"
+	}
+
+	s.router.Handle(s.openAPI.JSONPath, openAPIHandler("application/json", s.OpenAPIJSON)).Methods("GET")
+	s.router.Handle(s.openAPI.YAMLPath, openAPIHandler("application/yaml", s.OpenAPIYAML)).Methods("GET")
+
+	if s.openAPI.DocsPath != "" {
+		title := s.openAPI.Title
+		if title == "" {
+			title = "API"
+		}
+		assets := s.openAPI.DocsAssets
+		if assets.BaseURL == "" {
+			assets.BaseURL = "https://unpkg.com/swagger-ui-dist@" + SwaggerUIVersion
+		}
+		assets.BaseURL = strings.TrimSuffix(assets.BaseURL, "/")
+		page := new(strings.Builder)
+		_ = docsTemplate.Execute(page, map[string]any{"Title": title, "SpecURL": s.openAPI.JSONPath, "Assets": assets})
+		s.router.Handle(s.openAPI.DocsPath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte(page.String()))
+		})).Methods("GET")
+	}
+}
+
+func openAPIHandler(contentType string, document func() ([]byte, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := document()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("generate OpenAPI document: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write(data)
+	})
+}
+
+// docsTemplate is the Swagger UI page of the docs UI.
+var docsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="{{.Assets.BaseURL}}/swagger-ui.css"{{with .Assets.CSSIntegrity}} integrity="{{.}}"{{end}} crossorigin="anonymous">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="{{.Assets.BaseURL}}/swagger-ui-bundle.js"{{with .Assets.BundleIntegrity}} integrity="{{.}}"{{end}} crossorigin="anonymous"></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "{{.SpecURL}}", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`))
@@ -0,0 +1,407 @@
+package httpmanager
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// jsonSchema is a JSON Schema (draft 2020-12) as used by OpenAPI 3.1.
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Default              any                    `json:"default,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+}
+
+// schemaRegistry builds the schemas of Go types, registering named structs as components.
+type schemaRegistry struct {
+	schemas map[string]*jsonSchema
+	names   map[reflect.Type]string
+	// queryObjects holds the nested query structs being described, for recursive types.
+	queryObjects map[reflect.Type]bool
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		schemas:      map[string]*jsonSchema{},
+		names:        map[reflect.Type]string{},
+		queryObjects: map[reflect.Type]bool{},
+	}
+}
+
+// genericArguments matches the package paths of the type arguments of a generic type name.
+var genericArguments = regexp.MustCompile(`[\w./-]*\.`)
+
+// componentName returns a unique component name for a named type, e.g. "PageUser" for Page[pkg.User].
+func (r *schemaRegistry) componentName(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+	base := genericArguments.ReplaceAllString(t.Name(), "")
+	base = strings.Map(func(c rune) rune {
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_' {
+			return c
+		}
+		return -1
+	}, base)
+
+	name := base
+	for i := 2; ; i++ {
+		if _, taken := r.schemas[name]; !taken {
+			break
+		}
+		name = base + strconv.Itoa(i)
+	}
+	r.names[t] = name
+	return name
+}
+
+// schema returns the schema of t; named structs are referenced from the components.
+func (r *schemaRegistry) schema(t reflect.Type) *jsonSchema {
+	t = indirect(t)
+
+	switch {
+	case t == timeType:
+		return &jsonSchema{Type: "string", Format: "date-time"}
+	case t.Kind() != reflect.Struct && reflect.PointerTo(t).Implements(textUnmarshalerType) && t.Kind() != reflect.String:
+		return &jsonSchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &jsonSchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &jsonSchema{Type: "integer", Format: "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &jsonSchema{Type: "integer", Format: "int32", Minimum: float(0)}
+	case reflect.Uint, reflect.Uint64:
+		return &jsonSchema{Type: "integer", Format: "int64", Minimum: float(0)}
+	case reflect.Float32:
+		return &jsonSchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &jsonSchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &jsonSchema{Type: "string", Format: "byte"}
+		}
+		return &jsonSchema{Type: "array", Items: r.schema(t.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: r.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.object(t, false)
+		}
+		name := r.componentName(t)
+		if _, ok := r.schemas[name]; !ok {
+			// Register before building the properties, for recursive types
+			r.schemas[name] = &jsonSchema{}
+			*r.schemas[name] = *r.object(t, false)
+		}
+		return &jsonSchema{Ref: "#/components/schemas/" + name}
+	}
+	// interface{} and other types accept any value
+	return &jsonSchema{}
+}
+
+// object returns the object schema of the JSON fields of a struct. Fields bound from the
+// path, query, header or cookie are left out when bodyOnly is set.
+func (r *schemaRegistry) object(t reflect.Type, bodyOnly bool) *jsonSchema {
+	schema := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}}
+	r.addProperties(schema, t, bodyOnly)
+	return schema
+}
+
+func (r *schemaRegistry) addProperties(schema *jsonSchema, t reflect.Type, bodyOnly bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && indirect(field.Type).Kind() == reflect.Struct {
+			r.addProperties(schema, indirect(field.Type), bodyOnly)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if source, _, _ := parseBindTag(field); bodyOnly && source != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property, required := r.fieldSchema(field, r.schema(field.Type))
+		schema.Properties[name] = property
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// request returns the parameters and the body schema of a handler request type.
+func (r *schemaRegistry) request(t reflect.Type) ([]openAPIParameter, *jsonSchema) {
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	var params []openAPIParameter
+	hasBody := false
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				walk(field.Type)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			source, name, _ := parseBindTag(field)
+			if source == "" {
+				if jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ","); jsonName != "-" {
+					hasBody = true
+				}
+				continue
+			}
+
+			param := openAPIParameter{Name: name, In: source}
+			param.Schema, param.Required = r.fieldSchema(field, r.parameterSchema(field.Type))
+			if fieldType := indirect(field.Type); fieldType.Kind() == reflect.Map || isNestedStruct(fieldType) {
+				explode := true
+				param.Style, param.Explode = "deepObject", &explode
+			}
+			params = append(params, param)
+		}
+	}
+	walk(t)
+
+	if !hasBody {
+		return params, nil
+	}
+	if len(params) == 0 {
+		return params, r.schema(t)
+	}
+	return params, r.object(t, true)
+}
+
+// parameterSchema returns the schema of a parameter, where durations and text types are strings.
+func (r *schemaRegistry) parameterSchema(t reflect.Type) *jsonSchema {
+	t = indirect(t)
+	switch {
+	case t == durationType:
+		return &jsonSchema{Type: "string", Format: "duration"}
+	case t.Kind() == reflect.Slice && !isScalar(t):
+		return &jsonSchema{Type: "array", Items: r.parameterSchema(t.Elem())}
+	case isScalar(t) && t != timeType && reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return &jsonSchema{Type: "string"}
+	case isNestedStruct(t):
+		return r.queryObject(t)
+	}
+	return r.schema(t)
+}
+
+// queryObject returns the object schema of a nested struct bound from the query, keyed by the query names.
+func (r *schemaRegistry) queryObject(t reflect.Type) *jsonSchema {
+	schema := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}}
+	if r.queryObjects[t] {
+		return schema
+	}
+	r.queryObjects[t] = true
+	defer delete(r.queryObjects, t)
+
+	for _, field := range binderFor(t).params {
+		if field.source != bindQuery {
+			continue
+		}
+		structField := t.FieldByIndex(field.index)
+		property, required := r.fieldSchema(structField, r.parameterSchema(structField.Type))
+		schema.Properties[field.name] = property
+		if required {
+			schema.Required = append(schema.Required, field.name)
+		}
+	}
+	return schema
+}
+
+// fieldSchema applies the validate and default tags of the field to its schema, and reports
+// whether the field is required.
+func (r *schemaRegistry) fieldSchema(field reflect.StructField, schema *jsonSchema) (*jsonSchema, bool) {
+	if schema.Ref != "" {
+		// Sibling keywords of a reference apply to the reference only, keep the component intact
+		_, required := parseValidateTag(field.Tag.Get("validate"))
+		return schema, required
+	}
+
+	rules, required := parseValidateTag(field.Tag.Get("validate"))
+	target := schema
+	for _, rule := range rules {
+		if rule.name == "dive" {
+			if target.Items == nil {
+				break
+			}
+			target = target.Items
+			if target.Ref != "" {
+				break
+			}
+			continue
+		}
+		applyRule(target, rule)
+	}
+
+	if value, ok := field.Tag.Lookup("default"); ok {
+		schema.Default = convertForSchema(schema, value)
+	}
+	return schema, required
+}
+
+type validateRule struct {
+	name  string
+	param string
+}
+
+// parseValidateTag splits a validate tag into its rules and reports whether the field is required.
+func parseValidateTag(tag string) ([]validateRule, bool) {
+	if tag == "" {
+		return nil, false
+	}
+	var rules []validateRule
+	required, dived := false, false
+	for _, part := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(part, "=")
+		switch name {
+		case "dive":
+			dived = true
+		case "required":
+			// required after dive applies to the items
+			required = required || !dived
+		}
+		rules = append(rules, validateRule{name: name, param: param})
+	}
+	return rules, required
+}
+
+// applyRule sets the schema keywords matching a validate rule.
+func applyRule(schema *jsonSchema, rule validateRule) {
+	switch rule.name {
+	case "email":
+		schema.Format = "email"
+	case "url", "http_url", "uri":
+		schema.Format = "uri"
+	case "uuid", "uuid4":
+		schema.Format = "uuid"
+	case "oneof":
+		for _, value := range strings.Fields(rule.param) {
+			schema.Enum = append(schema.Enum, convertForSchema(schema, value))
+		}
+	case "len":
+		applyBound(schema, rule.param, true, true)
+	case "min", "gte":
+		applyBound(schema, rule.param, true, false)
+	case "max", "lte":
+		applyBound(schema, rule.param, false, true)
+	case "gt":
+		if f, err := strconv.ParseFloat(rule.param, 64); err == nil && isNumber(schema) {
+			schema.ExclusiveMinimum = &f
+		}
+	case "lt":
+		if f, err := strconv.ParseFloat(rule.param, 64); err == nil && isNumber(schema) {
+			schema.ExclusiveMaximum = &f
+		}
+	}
+}
+
+// applyBound sets the length, item count or value bound of the schema.
+func applyBound(schema *jsonSchema, param string, lower, upper bool) {
+	if isNumber(schema) {
+		f, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		if lower {
+			schema.Minimum = &f
+		}
+		if upper {
+			schema.Maximum = &f
+		}
+		return
+	}
+
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "string":
+		if lower {
+			schema.MinLength = &n
+		}
+		if upper {
+			schema.MaxLength = &n
+		}
+	case "array":
+		if lower {
+			schema.MinItems = &n
+		}
+		if upper {
+			schema.MaxItems = &n
+		}
+	}
+}
+
+func isNumber(schema *jsonSchema) bool {
+	return schema.Type == "integer" || schema.Type == "number"
+}
+
+// convertForSchema converts a tag value to the JSON type of the schema.
+func convertForSchema(schema *jsonSchema, value string) any {
+	switch schema.Type {
+	case "integer":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case "array":
+		if schema.Items != nil {
+			var items []any
+			for _, item := range strings.Split(value, ",") {
+				items = append(items, convertForSchema(schema.Items, item))
+			}
+			return items
+		}
+	}
+	return value
+}
+
+func float(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,260 @@
+package httpmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/httpmanager/internal/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+type docAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type docUser struct {
+	ID        int64       `json:"id"`
+	Name      string      `json:"name" validate:"required,min=3,max=50"`
+	Email     string      `json:"email" validate:"required,email"`
+	Role      string      `json:"role" validate:"oneof=admin member" default:"member"`
+	Tags      []string    `json:"tags,omitempty" validate:"max=5,dive,min=2"`
+	Address   *docAddress `json:"address,omitempty"`
+	Manager   *docUser    `json:"manager,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	Secret    string      `json:"-"`
+}
+
+type docListUsers struct {
+	Page    int           `query:"page" default:"1" validate:"gte=1"`
+	Status  []string      `query:"status"`
+	Timeout time.Duration `query:"timeout"`
+	Filter  struct {
+		Role string `query:"role" validate:"required"`
+	} `query:"filter"`
+	ClientID string `header:"X-Client-Id" validate:"required"`
+}
+
+type docUpdateUser struct {
+	ID   int64  `path:"id"`
+	Name string `json:"name" validate:"required"`
+}
+
+type docNotFound struct {
+	Reason string `json:"reason"`
+}
+
+func newDocServer() *Server {
+	server := NewServer(testdata.NewApplication(), WithOpenAPI(OpenAPIConfig{
+		Title:    "Users API",
+		Version:  "2.0.0",
+		Servers:  []string{"https://api.example.com"},
+		DocsPath: "/docs",
+		SecuritySchemes: map[string]SecurityScheme{
+			"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		},
+	}))
+
+	server.GET("/users", NewHandler(http.MethodGet, func(ctx context.Context, req *docListUsers) (*[]docUser, error) {
+		return &[]docUser{}, nil
+	}), Summary("List users"), Tags("users"))
+	server.POST("/users", NewHandler(http.MethodPost, func(ctx context.Context, req *docUser) (*ResponseSuccess[docUser], error) {
+		return &ResponseSuccess[docUser]{StatusCode: http.StatusCreated, Body: *req}, nil
+	}), SuccessStatus(http.StatusCreated), Security("bearer"), Tags("users"))
+	server.PUT("/users/{id:[0-9]+}", NewHandler(http.MethodPut, func(ctx context.Context, req *docUpdateUser) (*docUser, error) {
+		return &docUser{}, nil
+	}), ErrorResponse(http.StatusNotFound, docNotFound{}), OperationID("updateUser"), Deprecated())
+	server.Handle("/users/{id}/avatar", NewHandler(http.MethodDelete, func(ctx context.Context, req *struct{}) (*struct{}, error) {
+		return nil, nil
+	}))
+	server.GET("/ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.GET("/internal", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), Hidden())
+	server.Handle("/files/", NewStaticHandler("."))
+	return server
+}
+
+func TestServer_OpenAPIDocument(t *testing.T) {
+	doc := newDocServer().openAPIDocument()
+
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+	assert.Equal(t, openAPIInfo{Title: "Users API", Version: "2.0.0"}, doc.Info)
+	assert.Equal(t, []openAPIServer{{URL: "https://api.example.com"}}, doc.Servers)
+	assert.Len(t, doc.Components.SecuritySchemes, 1)
+	assert.ElementsMatch(t, []string{"/users", "/users/{id}", "/users/{id}/avatar", "/ping"}, keys(doc.Paths))
+
+	t.Run("parameters from binding tags", func(t *testing.T) {
+		op := doc.Paths["/users"]["get"]
+
+		assert.Equal(t, "getUsers", op.OperationID)
+		assert.Equal(t, "List users", op.Summary)
+		assert.Equal(t, []string{"users"}, op.Tags)
+		assert.Nil(t, op.RequestBody)
+		require.Len(t, op.Parameters, 5)
+
+		page := op.Parameters[0]
+		assert.Equal(t, "page", page.Name)
+		assert.Equal(t, "query", page.In)
+		assert.False(t, page.Required)
+		assert.Equal(t, int64(1), page.Schema.Default)
+		assert.Equal(t, 1.0, *page.Schema.Minimum)
+
+		assert.Equal(t, &jsonSchema{Type: "array", Items: &jsonSchema{Type: "string"}}, op.Parameters[1].Schema)
+		assert.Equal(t, &jsonSchema{Type: "string", Format: "duration"}, op.Parameters[2].Schema)
+
+		filter := op.Parameters[3]
+		assert.Equal(t, "deepObject", filter.Style)
+		assert.Equal(t, []string{"role"}, filter.Schema.Required)
+		assert.Contains(t, filter.Schema.Properties, "role")
+
+		assert.Equal(t, openAPIParameter{Name: "X-Client-Id", In: "header", Required: true, Schema: &jsonSchema{Type: "string"}}, op.Parameters[4])
+
+		success := op.Responses["200"].Content["application/json"].Schema
+		assert.Equal(t, &jsonSchema{Type: "array", Items: &jsonSchema{Ref: "#/components/schemas/docUser"}}, success)
+		assert.Contains(t, op.Responses, "400")
+		assert.Contains(t, op.Responses, "500")
+	})
+
+	t.Run("request body and ResponseSuccess", func(t *testing.T) {
+		op := doc.Paths["/users"]["post"]
+
+		require.NotNil(t, op.RequestBody)
+		assert.Equal(t, "#/components/schemas/docUser", op.RequestBody.Content["application/json"].Schema.Ref)
+		assert.Equal(t, []map[string][]string{{"bearer": {}}}, op.Security)
+		assert.NotContains(t, op.Responses, "200")
+		assert.Equal(t, "#/components/schemas/docUser", op.Responses["201"].Content["application/json"].Schema.Ref)
+	})
+
+	t.Run("path parameters and declared errors", func(t *testing.T) {
+		op := doc.Paths["/users/{id}"]["put"]
+
+		assert.Equal(t, "updateUser", op.OperationID)
+		assert.True(t, op.Deprecated)
+		require.Len(t, op.Parameters, 1)
+		assert.Equal(t, openAPIParameter{
+			Name:     "id",
+			In:       "path",
+			Required: true,
+			Schema:   &jsonSchema{Type: "integer", Format: "int64", Pattern: "^[0-9]+$"},
+		}, op.Parameters[0])
+
+		body := op.RequestBody.Content["application/json"].Schema
+		assert.Equal(t, "object", body.Type)
+		assert.Equal(t, []string{"name"}, body.Required)
+		assert.NotContains(t, body.Properties, "ID")
+
+		assert.Equal(t, "#/components/schemas/docNotFound", op.Responses["404"].Content["application/json"].Schema.Ref)
+	})
+
+	t.Run("routes without types", func(t *testing.T) {
+		avatar := doc.Paths["/users/{id}/avatar"]["delete"]
+		assert.Nil(t, avatar.Responses["200"].Content)
+		assert.Equal(t, []openAPIParameter{{Name: "id", In: "path", Required: true, Schema: &jsonSchema{Type: "string"}}}, avatar.Parameters)
+
+		ping := doc.Paths["/ping"]["get"]
+		assert.Equal(t, map[string]*openAPIResponse{"200": {Description: "OK"}}, ping.Responses)
+	})
+
+	t.Run("component schemas from validate tags", func(t *testing.T) {
+		user := doc.Components.Schemas["docUser"]
+
+		assert.Equal(t, []string{"name", "email"}, user.Required)
+		assert.NotContains(t, user.Properties, "Secret")
+		assert.Equal(t, 3, *user.Properties["name"].MinLength)
+		assert.Equal(t, 50, *user.Properties["name"].MaxLength)
+		assert.Equal(t, "email", user.Properties["email"].Format)
+		assert.Equal(t, []any{"admin", "member"}, user.Properties["role"].Enum)
+		assert.Equal(t, "member", user.Properties["role"].Default)
+		assert.Equal(t, 5, *user.Properties["tags"].MaxItems)
+		assert.Equal(t, 2, *user.Properties["tags"].Items.MinLength)
+		assert.Equal(t, "#/components/schemas/docAddress", user.Properties["address"].Ref)
+		assert.Equal(t, "#/components/schemas/docUser", user.Properties["manager"].Ref)
+		assert.Equal(t, &jsonSchema{Type: "string", Format: "date-time"}, user.Properties["created_at"])
+		assert.Contains(t, doc.Components.Schemas, "DetailedErrorResponse")
+	})
+}
+
+func TestServer_OpenAPIEndpoints(t *testing.T) {
+	server := newDocServer()
+
+	t.Run("JSON", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+		assert.Equal(t, "3.1.0", doc["openapi"])
+		assert.NotContains(t, doc["paths"], "/openapi.json")
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.yaml", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "application/yaml", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), "openapi: 3.1.0\ninfo:\n  title: Users API\n")
+		var doc map[string]any
+		require.NoError(t, yaml.Unmarshal(rec.Body.Bytes(), &doc))
+		assert.Contains(t, doc["paths"], "/users/{id}")
+	})
+
+	t.Run("docs UI", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), `<title>Users API</title>`)
+		assert.Contains(t, rec.Body.String(), `url: "\/openapi.json"`)
+	})
+
+	t.Run("docs UI assets", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+		assert.Contains(t, rec.Body.String(), `href="https://unpkg.com/swagger-ui-dist@`+SwaggerUIVersion+`/swagger-ui.css" crossorigin="anonymous"`)
+
+		server := NewServer(testdata.NewApplication(), WithOpenAPI(OpenAPIConfig{
+			DocsPath: "/docs",
+			DocsAssets: SwaggerUIAssets{
+				BaseURL:         "/static/swagger-ui/",
+				CSSIntegrity:    "sha384-css",
+				BundleIntegrity: "sha384-bundle",
+			},
+		}))
+		rec = httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+		assert.Contains(t, rec.Body.String(), `href="/static/swagger-ui/swagger-ui.css" integrity="sha384-css" crossorigin="anonymous"`)
+		assert.Contains(t, rec.Body.String(), `src="/static/swagger-ui/swagger-ui-bundle.js" integrity="sha384-bundle" crossorigin="anonymous"`)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		server := NewServer(testdata.NewApplication())
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	path, patterns := openAPIPath("/users/{id:[0-9]+}/files/{name}/{code:[a-z]{2}}")
+
+	assert.Equal(t, "/users/{id}/files/{name}/{code}", path)
+	assert.Equal(t, map[string]string{"id": "[0-9]+", "code": "[a-z]{2}"}, patterns)
+}
+
+func keys[V any](m map[string]V) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
@@ -1,6 +1,7 @@
 package httpmanager
 
 import (
+	"crypto/tls"
 	"github.com/gorilla/mux"
 	"os"
 	"time"
@@ -15,9 +16,27 @@ type Option struct {
 	keyFile          string
 	certData         string
 	keyData          string
+	clientCAFile     string
+	clientCAData     string
+	clientAuth       tls.ClientAuthType
+	certReloadInterval time.Duration
+	httpRedirectAddr string
+	preStopDelay     time.Duration
+	shutdownTimeout  time.Duration
+	validationStatusCode   int
+	validationMessageFunc  ValidationMessageFunc
+	validationErrorHandler ValidationErrorHandler
+	openAPI                *OpenAPIConfig
+	cors                   *CORSConfig
+	panicHook              PanicHook
+	errorHandler           ErrorHandler
+	errorEncoder           ErrorEncoder
 	middlewares      []mux.MiddlewareFunc
 	healthCheckPath  string
 	healthCheckEnabled bool
+	livenessPath       string
+	readinessPath      string
+	healthCacheTTL     time.Duration
 }
 
 // newDefaultOption initializes an Option struct with default server configurations and returns a pointer to it.
@@ -33,6 +52,10 @@ func newDefaultOption() *Option {
 		middlewares:        []mux.MiddlewareFunc{},
 		healthCheckPath:    "/health",
 		healthCheckEnabled: true,
+		livenessPath:       "/livez",
+		readinessPath:      "/readyz",
+		healthCacheTTL:     time.Second,
+		shutdownTimeout:    30 * time.Second,
 	}
 }
 
@@ -94,6 +117,91 @@ func WithKeyData(keyData string) OptionFunc {
 	}
 }
 
+// WithClientCAFile sets the CA bundle file used to verify client certificates (mTLS).
+// Clients must present a certificate signed by one of the CAs unless WithClientAuth relaxes it.
+func WithClientCAFile(caFile string) OptionFunc {
+	return func(o *Option) {
+		o.clientCAFile = caFile
+	}
+}
+
+// WithClientCAData sets the PEM CA bundle used to verify client certificates (mTLS).
+func WithClientCAData(caData string) OptionFunc {
+	return func(o *Option) {
+		o.clientCAData = caData
+	}
+}
+
+// WithClientAuth sets the client certificate policy. It defaults to tls.RequireAndVerifyClientCert
+// when a client CA is set, use tls.VerifyClientCertIfGiven to make the certificate optional.
+func WithClientAuth(clientAuth tls.ClientAuthType) OptionFunc {
+	return func(o *Option) {
+		o.clientAuth = clientAuth
+	}
+}
+
+// WithCertReload re-reads the certificate and key files when they change,
+// checking at most once per interval during TLS handshakes.
+func WithCertReload(interval time.Duration) OptionFunc {
+	return func(o *Option) {
+		o.certReloadInterval = interval
+	}
+}
+
+// WithHTTPRedirect starts a plain HTTP listener on addr that redirects every request to HTTPS.
+func WithHTTPRedirect(addr string) OptionFunc {
+	return func(o *Option) {
+		o.httpRedirectAddr = addr
+	}
+}
+
+// WithPreStopDelay sets how long Run keeps serving, with the health check failing, after a
+// shutdown signal, so load balancers stop routing new requests before connections are drained.
+func WithPreStopDelay(delay time.Duration) OptionFunc {
+	return func(o *Option) {
+		o.preStopDelay = delay
+	}
+}
+
+// WithShutdownTimeout sets the deadline for draining in-flight requests in Run, and for each shutdown hook.
+func WithShutdownTimeout(timeout time.Duration) OptionFunc {
+	return func(o *Option) {
+		o.shutdownTimeout = timeout
+	}
+}
+
+// WithValidationStatusCode sets the status code of the response to a request failing its
+// `validate` tags, e.g. http.StatusUnprocessableEntity. Defaults to http.StatusBadRequest.
+func WithValidationStatusCode(statusCode int) OptionFunc {
+	return func(o *Option) {
+		o.validationStatusCode = statusCode
+	}
+}
+
+// WithValidationMessages sets the function building the message of each failed validation rule,
+// e.g. to translate messages based on the Accept-Language header. An empty message falls back to
+// the default English one.
+func WithValidationMessages(fn ValidationMessageFunc) OptionFunc {
+	return func(o *Option) {
+		o.validationMessageFunc = fn
+	}
+}
+
+// WithValidationErrorHandler overrides the status code and body of validation error responses.
+func WithValidationErrorHandler(handler ValidationErrorHandler) OptionFunc {
+	return func(o *Option) {
+		o.validationErrorHandler = handler
+	}
+}
+
+// WithOpenAPI serves an OpenAPI 3.1 document generated from the registered routes, and optionally
+// a docs UI. See OpenAPIConfig for the defaults.
+func WithOpenAPI(config OpenAPIConfig) OptionFunc {
+	return func(o *Option) {
+		o.openAPI = &config
+	}
+}
+
 // WithPort sets the port for the server address
 func WithPort(port string) OptionFunc {
 	return func(o *Option) {
@@ -101,6 +209,36 @@ func WithPort(port string) OptionFunc {
 	}
 }
 
+// WithCORS enables CORS for all routes with the policy. Routes can override it with the CORS route option.
+func WithCORS(config CORSConfig) OptionFunc {
+	return func(o *Option) {
+		o.cors = &config
+	}
+}
+
+// WithPanicHook sets the hook called after a panic in a handler is recovered, e.g. to send an alert.
+func WithPanicHook(hook PanicHook) OptionFunc {
+	return func(o *Option) {
+		o.panicHook = hook
+	}
+}
+
+// WithErrorHandler replaces the error responses of the server: the handler writes every error returned
+// by the handlers, including binding and validation errors, instead of the error mappings and the encoder.
+func WithErrorHandler(handler ErrorHandler) OptionFunc {
+	return func(o *Option) {
+		o.errorHandler = handler
+	}
+}
+
+// WithErrorEncoder sets how error responses are written, e.g. ProblemJSONEncoder for RFC 9457
+// problem details. Defaults to JSONErrorEncoder.
+func WithErrorEncoder(encoder ErrorEncoder) OptionFunc {
+	return func(o *Option) {
+		o.errorEncoder = encoder
+	}
+}
+
 // WithMiddleware adds middleware to the server
 func WithMiddleware(middleware ...mux.MiddlewareFunc) OptionFunc {
 	return func(o *Option) {
@@ -123,9 +261,32 @@ func WithHealthCheckPath(path string) OptionFunc {
 	}
 }
 
-// WithoutHealthCheck disables the health check endpoint
+// WithoutHealthCheck disables the health check endpoint, and the liveness and readiness endpoints
 func WithoutHealthCheck() OptionFunc {
 	return func(o *Option) {
 		o.healthCheckEnabled = false
 	}
 }
+
+// WithLivenessPath sets a custom path for the liveness endpoint, "/livez" by default
+func WithLivenessPath(path string) OptionFunc {
+	return func(o *Option) {
+		o.livenessPath = path
+	}
+}
+
+// WithReadinessPath sets a custom path for the readiness endpoint, "/readyz" by default
+func WithReadinessPath(path string) OptionFunc {
+	return func(o *Option) {
+		o.readinessPath = path
+	}
+}
+
+// WithHealthCheckCacheTTL sets how long the result of a health check is reused by the liveness and
+// readiness endpoints, so frequent probes do not load the dependencies. One second by default, zero runs
+// the checks on every probe.
+func WithHealthCheckCacheTTL(ttl time.Duration) OptionFunc {
+	return func(o *Option) {
+		o.healthCacheTTL = ttl
+	}
+}
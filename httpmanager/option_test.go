@@ -21,6 +21,9 @@ func TestNewDefaultOption(t *testing.T) {
 	assert.Empty(t, opt.keyData, "Default keyData should be empty")
 	assert.Equal(t, "/health", opt.healthCheckPath, "Default health check path should be /health")
 	assert.True(t, opt.healthCheckEnabled, "Default health check should be enabled")
+	assert.Equal(t, "/livez", opt.livenessPath, "Default liveness path should be /livez")
+	assert.Equal(t, "/readyz", opt.readinessPath, "Default readiness path should be /readyz")
+	assert.Equal(t, time.Second, opt.healthCacheTTL, "Default health check cache TTL should be 1s")
 }
 
 func TestWithAddr(t *testing.T) {
@@ -4,7 +4,6 @@ import (
 	"context"
 	"net/http"
 	"reflect"
-	"strconv"
 )
 
 // QueryParams represents a map of query parameters from the URL
@@ -61,14 +60,30 @@ func GetHeaders(ctx context.Context) http.Header {
 
 // BindQueryParams automatically binds query parameters to a struct using reflection.
 // The struct fields should have a "query" tag to specify the query parameter name.
-// Supported types: string, int, int64, bool, []string, []int, []int64, []bool
+//
+// Supported types are strings, booleans, all integer and float kinds, time.Time, time.Duration,
+// encoding.TextUnmarshaler implementations, pointers to them for optional parameters, and slices
+// of them. Slices accept repeated parameters and comma-separated lists (?ids=1,2,3).
+// time.Time is parsed as RFC 3339 unless a layout is set with the layout tag option.
+// Struct and map fields are bound from the bracket or dot notation (?filter[status]=active or
+// ?filter.status=active). A `default:"..."` tag sets the value of absent parameters.
+//
+// A value that cannot be converted returns a *BindError naming the parameter.
 //
 // Example usage:
+//   type Filter struct {
+//       Status string `query:"status"`
+//   }
+//
 //   type QueryRequest struct {
-//       Name     string   `query:"name"`
-//       Age      int      `query:"age"`
-//       Active   bool     `query:"active"`
-//       Tags     []string `query:"tags"`
+//       Name     string            `query:"name"`
+//       Age      int               `query:"age"`
+//       Active   *bool             `query:"active"`
+//       IDs      []int64           `query:"ids"`
+//       From     time.Time         `query:"from,layout=2006-01-02"`
+//       Timeout  time.Duration     `query:"timeout" default:"5s"`
+//       Filter   Filter            `query:"filter"`
+//       Labels   map[string]string `query:"label"`
 //   }
 //
 //   var req QueryRequest
@@ -78,14 +93,9 @@ func BindQueryParams(ctx context.Context, dst interface{}) error {
 		return nil
 	}
 
-	queryParams := GetQueryParams(ctx)
-	if len(queryParams) == 0 {
-		return nil
-	}
-
-	// Get the value and type of the destination
+	// Get the value of the destination
 	dstValue := reflect.ValueOf(dst)
-	if dstValue.Kind() != reflect.Ptr {
+	if dstValue.Kind() != reflect.Ptr || dstValue.IsNil() {
 		return nil // Must be a pointer to struct
 	}
 
@@ -94,91 +104,5 @@ func BindQueryParams(ctx context.Context, dst interface{}) error {
 		return nil // Must point to a struct
 	}
 
-	dstType := dstValue.Type()
-
-	// Iterate through struct fields
-	for i := 0; i < dstType.NumField(); i++ {
-		field := dstType.Field(i)
-		fieldValue := dstValue.Field(i)
-
-		// Skip unexported fields
-		if !fieldValue.CanSet() {
-			continue
-		}
-
-		// Get the query tag
-		queryTag := field.Tag.Get("query")
-		if queryTag == "" {
-			continue
-		}
-
-		// Get query parameter values
-		paramValues := queryParams.GetAll(queryTag)
-		if len(paramValues) == 0 {
-			continue
-		}
-
-		// Bind based on field type
-		bindFieldValue(fieldValue, paramValues)
-	}
-
-	return nil
-}
-
-// bindFieldValue binds query parameter values to a struct field based on its type
-func bindFieldValue(fieldValue reflect.Value, paramValues []string) {
-	fieldType := fieldValue.Type()
-
-	switch fieldType.Kind() {
-	case reflect.String:
-		if len(paramValues) > 0 {
-			fieldValue.SetString(paramValues[0])
-		}
-
-	case reflect.Int, reflect.Int64:
-		if len(paramValues) > 0 {
-			if val, err := strconv.ParseInt(paramValues[0], 10, 64); err == nil {
-				fieldValue.SetInt(val)
-			}
-		}
-
-	case reflect.Bool:
-		if len(paramValues) > 0 {
-			if val, err := strconv.ParseBool(paramValues[0]); err == nil {
-				fieldValue.SetBool(val)
-			}
-		}
-
-	case reflect.Slice:
-		switch fieldType.Elem().Kind() {
-		case reflect.String:
-			fieldValue.Set(reflect.ValueOf(paramValues))
-
-		case reflect.Int, reflect.Int64:
-			intSlice := make([]int64, 0, len(paramValues))
-			for _, paramValue := range paramValues {
-				if val, err := strconv.ParseInt(paramValue, 10, 64); err == nil {
-					intSlice = append(intSlice, val)
-				}
-			}
-			if fieldType.Elem().Kind() == reflect.Int {
-				intSliceInt := make([]int, len(intSlice))
-				for i, v := range intSlice {
-					intSliceInt[i] = int(v)
-				}
-				fieldValue.Set(reflect.ValueOf(intSliceInt))
-			} else {
-				fieldValue.Set(reflect.ValueOf(intSlice))
-			}
-
-		case reflect.Bool:
-			boolSlice := make([]bool, 0, len(paramValues))
-			for _, paramValue := range paramValues {
-				if val, err := strconv.ParseBool(paramValue); err == nil {
-					boolSlice = append(boolSlice, val)
-				}
-			}
-			fieldValue.Set(reflect.ValueOf(boolSlice))
-		}
-	}
+	return binderFor(dstValue.Type()).bindQuery(dstValue, normalizeQuery(GetQueryParams(ctx)), "")
 }
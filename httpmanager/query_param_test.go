@@ -2,10 +2,15 @@ package httpmanager
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetQueryParams(t *testing.T) {
@@ -392,9 +397,10 @@ func TestBindQueryParams(t *testing.T) {
 		var req QueryRequest
 		err := BindQueryParams(ctx, &req)
 
-		assert.NoError(t, err) // Should not error, just skip invalid values
+		var bindErr *BindError
+		assert.ErrorAs(t, err, &bindErr) // Invalid values are reported instead of skipped
+		assert.Equal(t, `invalid query parameter "age": "invalid" is not a valid int`, err.Error())
 		assert.Equal(t, 0, req.Age)      // Should remain zero value
-		assert.Equal(t, false, req.Active) // Should remain zero value
 	})
 
 	t.Run("bind int64 slice", func(t *testing.T) {
@@ -410,8 +416,11 @@ func TestBindQueryParams(t *testing.T) {
 		var req QueryRequest
 		err := BindQueryParams(ctx, &req)
 
-		assert.NoError(t, err)
-		assert.Equal(t, []int64{1, 2, 3, 4}, req.Values) // Should skip invalid value
+		var bindErr *BindError
+		assert.ErrorAs(t, err, &bindErr)
+		assert.Equal(t, "values", bindErr.Name)
+		assert.Equal(t, "invalid", bindErr.Value) // Names the invalid value
+		assert.Nil(t, req.Values)
 	})
 
 	t.Run("with nil destination", func(t *testing.T) {
@@ -485,3 +494,153 @@ func TestBindQueryParams(t *testing.T) {
 		assert.Equal(t, "", req.Name) // Should remain empty
 	})
 }
+
+// orderStatus is an enum bound through encoding.TextUnmarshaler
+type orderStatus int
+
+const (
+	orderPending orderStatus = iota + 1
+	orderPaid
+)
+
+func (s *orderStatus) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "pending":
+		*s = orderPending
+	case "paid":
+		*s = orderPaid
+	default:
+		return fmt.Errorf("unknown order status %q", text)
+	}
+	return nil
+}
+
+type priceRange struct {
+	Min float64 `query:"min"`
+	Max float64 `query:"max"`
+}
+
+type orderFilter struct {
+	Status []orderStatus `query:"status"`
+	Price  *priceRange   `query:"price"`
+}
+
+type richQueryRequest struct {
+	Limit    uint16            `query:"limit"`
+	Ratio    float32           `query:"ratio"`
+	Active   *bool             `query:"active"`
+	Missing  *int              `query:"missing"`
+	IDs      []int64           `query:"ids"`
+	From     time.Time         `query:"from,layout=2006-01-02"`
+	Until    *time.Time        `query:"until"`
+	Timeout  time.Duration     `query:"timeout" default:"5s"`
+	Status   orderStatus       `query:"status"`
+	Filter   orderFilter       `query:"filter"`
+	Labels   map[string]string `query:"label"`
+	Quantity map[string]int    `query:"qty"`
+}
+
+func bindRawQuery(t *testing.T, rawQuery string, dst interface{}) error {
+	t.Helper()
+	values, err := url.ParseQuery(rawQuery)
+	require.NoError(t, err)
+	ctx := context.WithValue(context.Background(), queryParamsKey, QueryParams(values))
+	return BindQueryParams(ctx, dst)
+}
+
+func TestBindQueryParams_RichTypes(t *testing.T) {
+	t.Run("all supported types", func(t *testing.T) {
+		var req richQueryRequest
+		err := bindRawQuery(t, "limit=50&ratio=0.5&active=false&ids=1,2&ids=3&from=2026-01-31&until=2026-02-01T10:00:00Z"+
+			"&status=PAID&filter[status]=pending,paid&filter[price][min]=10.5&filter.price.max=99"+
+			"&label[env]=prod&label.team=core&qty[apple]=3", &req)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint16(50), req.Limit)
+		assert.Equal(t, float32(0.5), req.Ratio)
+		require.NotNil(t, req.Active)
+		assert.False(t, *req.Active)
+		assert.Nil(t, req.Missing)
+		assert.Equal(t, []int64{1, 2, 3}, req.IDs)
+		assert.Equal(t, time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC), req.From)
+		require.NotNil(t, req.Until)
+		assert.Equal(t, time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC), *req.Until)
+		assert.Equal(t, 5*time.Second, req.Timeout)
+		assert.Equal(t, orderPaid, req.Status)
+		assert.Equal(t, orderFilter{
+			Status: []orderStatus{orderPending, orderPaid},
+			Price:  &priceRange{Min: 10.5, Max: 99},
+		}, req.Filter)
+		assert.Equal(t, map[string]string{"env": "prod", "team": "core"}, req.Labels)
+		assert.Equal(t, map[string]int{"apple": 3}, req.Quantity)
+	})
+
+	t.Run("absent nested values stay empty", func(t *testing.T) {
+		var req richQueryRequest
+		err := bindRawQuery(t, "timeout=1m", &req)
+
+		require.NoError(t, err)
+		assert.Equal(t, time.Minute, req.Timeout)
+		assert.Nil(t, req.Filter.Price)
+		assert.Nil(t, req.Labels)
+	})
+
+	t.Run("bracket array notation", func(t *testing.T) {
+		var req richQueryRequest
+		err := bindRawQuery(t, "ids[]=4&ids[]=5", &req)
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{4, 5}, req.IDs)
+	})
+
+	t.Run("string items keep their commas", func(t *testing.T) {
+		var req struct {
+			Names []string  `query:"name"`
+			Notes []*string `query:"note"`
+		}
+		err := bindRawQuery(t, "name=Doe,%20John&name=Roe&note=a,b", &req)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Doe, John", "Roe"}, req.Names)
+		require.Len(t, req.Notes, 1)
+		assert.Equal(t, "a,b", *req.Notes[0])
+	})
+
+	t.Run("empty items are skipped", func(t *testing.T) {
+		var req richQueryRequest
+		require.NoError(t, bindRawQuery(t, "ids=", &req))
+		assert.Empty(t, req.IDs)
+
+		req = richQueryRequest{}
+		require.NoError(t, bindRawQuery(t, "ids=1,,2,&ids=&filter[status]=paid,", &req))
+		assert.Equal(t, []int64{1, 2}, req.IDs)
+		assert.Equal(t, []orderStatus{orderPaid}, req.Filter.Status)
+	})
+
+	errorTests := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"negative unsigned", "limit=-1", `invalid query parameter "limit": "-1" is not a valid uint16`},
+		{"overflow", "limit=70000", `invalid query parameter "limit": "70000" is not a valid uint16`},
+		{"float", "ratio=half", `invalid query parameter "ratio": "half" is not a valid float32`},
+		{"time layout", "from=31-01-2026", `invalid query parameter "from": "31-01-2026" is not a time in the "2006-01-02" layout`},
+		{"duration", "timeout=soon", `invalid query parameter "timeout": "soon" is not a duration`},
+		{"text unmarshaler", "status=lost", `invalid query parameter "status": unknown order status "lost"`},
+		{"comma list item", "ids=1,x,3", `invalid query parameter "ids": "x" is not a valid int64`},
+		{"nested field", "filter[price][min]=cheap", `invalid query parameter "filter.price.min": "cheap" is not a valid float64`},
+		{"map value", "qty[apple]=many", `invalid query parameter "qty.apple": "many" is not a valid int`},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req richQueryRequest
+			err := bindRawQuery(t, tt.query, &req)
+
+			var bindErr *BindError
+			require.ErrorAs(t, err, &bindErr)
+			assert.Equal(t, "query", bindErr.Source)
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
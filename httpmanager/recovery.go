@@ -0,0 +1,124 @@
+package httpmanager
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/gorilla/mux"
+	"net"
+	"net/http"
+	"runtime"
+	"strings"
+)
+
+// maxPanicFrames is the maximum number of stack frames recorded for a panic.
+const maxPanicFrames = 32
+
+// PanicHook is called after a panic in a handler has been recovered and answered with 500,
+// e.g. to send an alert. The stack is trimmed to the frames between the panic and the server middlewares.
+type PanicHook func(r *http.Request, value any, stack string)
+
+// PanicCount returns the number of panics recovered since the server was created.
+func (s *Server) PanicCount() uint64 {
+	return s.panics.Load()
+}
+
+// recoveryMiddleware recovers the panics of the handlers and their middlewares. A panic is answered with 500,
+// recorded on the logmanager transaction with its stack trace, counted, and passed to the panic hook.
+// http.ErrAbortHandler is re-panicked so net/http aborts the response silently.
+func (s *Server) recoveryMiddleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &recoveryWriter{ResponseWriter: w}
+			defer func() {
+				value := recover()
+				if value == nil {
+					return
+				}
+				if value == http.ErrAbortHandler {
+					panic(value)
+				}
+
+				stack := panicStack()
+				s.panics.Add(1)
+				if txn := logmanager.FromContext(r.Context()); txn != nil {
+					txn.NoticePanic(value, stack)
+				}
+				// The response can only be replaced when the handler did not start it
+				if !rw.wroteHeader {
+					s.errors.write(rw, r, internalError(fmt.Errorf("panic: %v", value)))
+				}
+				if s.panicHook != nil {
+					s.panicHook(r, value, stack)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// panicStack formats the stack of the panicking goroutine from the frame that panicked up to
+// the recovery middleware, leaving out the runtime frames raising the panic.
+func panicStack() string {
+	pcs := make([]uintptr, maxPanicFrames+16)
+	// Skip runtime.Callers, panicStack and the deferred function of the recovery middleware
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var b strings.Builder
+	count := 0
+	for more := true; more && count < maxPanicFrames; {
+		var frame runtime.Frame
+		frame, more = frames.Next()
+		if count == 0 && strings.HasPrefix(frame.Function, "runtime.") {
+			continue
+		}
+		if strings.Contains(frame.Function, "recoveryMiddleware") {
+			break
+		}
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		count++
+	}
+	return b.String()
+}
+
+// recoveryWriter records whether the response was started, so a recovered panic is only answered
+// when it was not.
+type recoveryWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *recoveryWriter) WriteHeader(statusCode int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *recoveryWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher when the underlying writer does.
+func (w *recoveryWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer does.
+func (w *recoveryWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("http.Hijacker is not implemented by %T", w.ResponseWriter)
+	}
+	w.wroteHeader = true
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *recoveryWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,105 @@
+package httpmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func panickingFunc() {
+	panic("something went wrong")
+}
+
+func TestServer_Recovery(t *testing.T) {
+	app := logmanager.NewTestableApplication()
+
+	var hookValue any
+	var hookStack string
+	server := NewServer(app.Application, WithPanicHook(func(r *http.Request, value any, stack string) {
+		hookValue, hookStack = value, stack
+	}))
+
+	server.GET("/handler", NewHandler(http.MethodGet, func(ctx context.Context, req *struct{}) (*struct{}, error) {
+		panickingFunc()
+		return nil, nil
+	}))
+	server.POST("/upload", NewUploadHandler(http.MethodPost, t.TempDir(), func(ctx context.Context, files map[string][]*UploadedFile, form map[string][]string) (interface{}, error) {
+		var m map[string]string
+		m["nil"] = "map"
+		return nil, nil
+	}))
+	server.GET("/started", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("after the response started")
+	}))
+	server.GET("/abort", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	t.Run("handler panic", func(t *testing.T) {
+		app.ResetLoggedEntries()
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/handler", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		var body DetailedErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, DetailedErrorResponse{Code: "500", Message: MessageInfo{Title: "internal server error", Desc: "internal server error"}}, body)
+
+		assert.Equal(t, logrus.ErrorLevel, app.GetLoggedLevel())
+		assert.Equal(t, "panic: something went wrong", app.GetLoggedMessage())
+		assert.Equal(t, "something went wrong", app.GetLoggedField("panic"))
+		stack := app.GetLoggedField("stack").(string)
+		assert.Regexp(t, `^github.com/SALT-Indonesia/salt-pkg/httpmanager.panickingFunc\n\t.*recovery_test.go:\d+\n`, stack)
+		assert.NotContains(t, stack, "runtime/panic.go")
+		assert.NotContains(t, stack, "recoveryMiddleware")
+		assert.NotContains(t, stack, "lmgorilla")
+
+		assert.Equal(t, "something went wrong", hookValue)
+		assert.Equal(t, stack, hookStack)
+		assert.Equal(t, uint64(1), server.PanicCount())
+	})
+
+	t.Run("upload handler panic", func(t *testing.T) {
+		app.ResetLoggedEntries()
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		_ = writer.WriteField("name", "value")
+		_ = writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/upload", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "panic: assignment to entry in nil map", app.GetLoggedMessage())
+		assert.Equal(t, uint64(2), server.PanicCount())
+	})
+
+	t.Run("response already started", func(t *testing.T) {
+		app.ResetLoggedEntries()
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/started", nil))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Empty(t, rec.Body.String())
+		assert.Equal(t, "after the response started", app.GetLoggedField("panic"))
+		assert.Equal(t, uint64(3), server.PanicCount())
+	})
+
+	t.Run("abort handler", func(t *testing.T) {
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			server.router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+		})
+		assert.Equal(t, uint64(3), server.PanicCount())
+	})
+}
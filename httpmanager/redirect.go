@@ -48,6 +48,11 @@ func (c *Context) RedirectPermanent(location string) {
 	c.Redirect(http.StatusMovedPermanently, location)
 }
 
+// Error writes the response of err with the error handler of the server.
+func (c *Context) Error(err error) {
+	WriteError(c.Writer, c.Request, err)
+}
+
 // GetQueryParams returns query parameters from the context
 func (c *Context) GetQueryParams() QueryParams {
 	return GetQueryParams(c.Context)
@@ -144,7 +149,7 @@ func (h *RedirectHandler) WithMiddleware() http.Handler {
 // ServeHTTP processes incoming HTTP requests for redirects
 func (h *RedirectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != h.method {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		WriteError(w, r, methodNotAllowedError(r))
 		return
 	}
 
@@ -93,7 +93,7 @@ func TestBindQueryParams_SecurityAnalysis(t *testing.T) {
 		var test TestStruct
 		err := BindQueryParams(ctx, &test)
 
-		assert.NoError(t, err)
+		assert.ErrorContains(t, err, `"9223372036854775808" is not a valid int`)
 		assert.Equal(t, 0, test.Value) // Should remain zero due to parsing error
 	})
 
@@ -195,7 +195,7 @@ func TestBindQueryParams_SecurityAnalysis(t *testing.T) {
 		var test TestStruct
 		err := BindQueryParams(ctx, &test)
 
-		assert.NoError(t, err)
+		assert.Error(t, err)
 		assert.Empty(t, test.Values) // Should result in empty slice due to parsing failures
 	})
 
@@ -212,8 +212,9 @@ func TestBindQueryParams_SecurityAnalysis(t *testing.T) {
 		var test TestStruct
 		err := BindQueryParams(ctx, &test)
 
-		// Should not return error even with invalid input - graceful degradation
-		assert.NoError(t, err)
+		// The error names the parameter and value without internal details
+		assert.EqualError(t, err, `invalid query parameter "value": "invalid" is not a valid int`)
+		assert.NotContains(t, err.Error(), "strconv")
 		assert.Equal(t, 0, test.Value) // Should remain zero value
 	})
 }
\ No newline at end of file
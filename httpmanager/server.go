@@ -2,18 +2,34 @@ package httpmanager
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/SALT-Indonesia/salt-pkg/logmanager"
 	"github.com/SALT-Indonesia/salt-pkg/logmanager/integrations/lmgorilla"
 	"github.com/gorilla/mux"
 	"net/http"
+	"sync"
+	"sync/atomic"
 )
 
 type Server struct {
-	server       *http.Server
-	router       *mux.Router
-	app          *logmanager.Application
-	hasSetUpCORS bool
+	server        *http.Server
+	redirect      *http.Server
+	mu            sync.Mutex
+	router        *mux.Router
+	app           *logmanager.Application
+	hasSetUpCORS  bool
+	shuttingDown  atomic.Bool
+	shutdownHooks []shutdownHook
+	validator     *requestValidator
+	routes        []route
+	corsPolicy    *corsPolicy
+	routeCORS     map[*mux.Route]*corsPolicy
+	panics        atomic.Uint64
+	errors        *errorResponder
+	codecs        *codecRegistry
+	webSockets    *webSocketRegistry
+	health        *healthRegistry
 	*Option
 }
 
@@ -22,8 +38,9 @@ func NewServer(app *logmanager.Application, opts ...OptionFunc) *Server {
 	router := mux.NewRouter()
 
 	s := &Server{
-		router: router,
-		app:    app,
+		router:    router,
+		app:       app,
+		routeCORS: map[*mux.Route]*corsPolicy{},
 	}
 
 	s.Option = newDefaultOption()
@@ -31,15 +48,35 @@ func NewServer(app *logmanager.Application, opts ...OptionFunc) *Server {
 		o(s.Option)
 	}
 
+	s.validator = newRequestValidator(s.Option)
+	s.errors = newErrorResponder(s.Option)
+	s.codecs = newCodecRegistry()
+	s.webSockets = newWebSocketRegistry()
+	s.health = newHealthRegistry(s.healthCacheTTL)
+
 	// Add default middlewares
-	s.middlewares = append(s.middlewares, lmgorilla.Middleware(s.app))
+	s.middlewares = append(s.middlewares, lmgorilla.Middleware(s.app), s.recoveryMiddleware(), validatorMiddleware(s.validator),
+		errorResponderMiddleware(s.errors), codecsMiddleware(s.codecs), webSocketsMiddleware(s.webSockets))
+	if s.ssl {
+		s.middlewares = append(s.middlewares, clientCertMiddleware())
+	}
+	if s.cors != nil {
+		s.enableCORS(*s.cors)
+	}
 
 	// Register custom NotFoundHandler with debug logging
 	s.registerNotFoundHandler()
+	s.registerMethodNotAllowedHandler()
 
-	// Register health check endpoint if enabled
+	// Register health check, liveness and readiness endpoints if enabled
 	if s.healthCheckEnabled {
 		s.registerHealthCheck()
+		s.registerHealthProbes()
+	}
+
+	// Register the OpenAPI document endpoints if enabled
+	if s.openAPI != nil {
+		s.registerOpenAPI()
 	}
 
 	s.server = &http.Server{
@@ -48,6 +85,7 @@ func NewServer(app *logmanager.Application, opts ...OptionFunc) *Server {
 		ReadTimeout:  s.readTimeout,
 		WriteTimeout: s.writeTimeout,
 	}
+	s.server.RegisterOnShutdown(s.webSockets.closeAll)
 
 	return s
 }
@@ -59,30 +97,14 @@ func (s *Server) Use(middleware ...mux.MiddlewareFunc) {
 
 // Handle registers the handler with the given pattern in the Server's router.
 // It applies all server middlewares to the handler.
-func (s *Server) Handle(pattern string, handler http.Handler) {
-	// Apply all middlewares in reverse order
-	finalHandler := handler
-	for i := len(s.middlewares) - 1; i >= 0; i-- {
-		finalHandler = s.middlewares[i](finalHandler)
-	}
-	s.router.Handle(pattern, finalHandler)
+func (s *Server) Handle(pattern string, handler http.Handler, opts ...RouteOption) {
+	s.handleRoute(s.router, "", pattern, handler, nil, opts)
 }
 
 // HandleWithMiddleware registers the handler with the given pattern and applies
 // the specified middlewares to the handler, in addition to the server middlewares.
 func (s *Server) HandleWithMiddleware(pattern string, handler http.Handler, middleware ...mux.MiddlewareFunc) {
-	// Apply handler-specific middlewares first (in reverse order)
-	finalHandler := handler
-	for i := len(middleware) - 1; i >= 0; i-- {
-		finalHandler = middleware[i](finalHandler)
-	}
-
-	// Then apply server middlewares
-	for i := len(s.middlewares) - 1; i >= 0; i-- {
-		finalHandler = s.middlewares[i](finalHandler)
-	}
-
-	s.router.Handle(pattern, finalHandler)
+	s.handleRoute(s.router, "", pattern, handler, middleware, nil)
 }
 
 // HandleFunc registers a handler function with the given pattern in the Server's router.
@@ -93,67 +115,98 @@ func (s *Server) HandleFunc(pattern string, handlerFunc func(http.ResponseWriter
 
 // GET registers a GET handler with path parameter support
 // Pattern can include path parameters like "/user/{id}" or "/user/{id:[0-9]+}"
-func (s *Server) GET(pattern string, handler http.Handler) {
-	// Apply all middlewares in reverse order
-	finalHandler := handler
-	for i := len(s.middlewares) - 1; i >= 0; i-- {
-		finalHandler = s.middlewares[i](finalHandler)
-	}
-	s.router.Handle(pattern, finalHandler).Methods("GET")
+func (s *Server) GET(pattern string, handler http.Handler, opts ...RouteOption) {
+	s.handleMethod(http.MethodGet, pattern, handler, opts)
 }
 
 // POST registers a POST handler with path parameter support
 // Pattern can include path parameters like "/user/{id}" or "/user/{id:[0-9]+}"
-func (s *Server) POST(pattern string, handler http.Handler) {
-	// Apply all middlewares in reverse order
-	finalHandler := handler
-	for i := len(s.middlewares) - 1; i >= 0; i-- {
-		finalHandler = s.middlewares[i](finalHandler)
-	}
-	s.router.Handle(pattern, finalHandler).Methods("POST")
+func (s *Server) POST(pattern string, handler http.Handler, opts ...RouteOption) {
+	s.handleMethod(http.MethodPost, pattern, handler, opts)
 }
 
 // PUT registers a PUT handler with path parameter support
 // Pattern can include path parameters like "/user/{id}" or "/user/{id:[0-9]+}"
-func (s *Server) PUT(pattern string, handler http.Handler) {
-	// Apply all middlewares in reverse order
-	finalHandler := handler
-	for i := len(s.middlewares) - 1; i >= 0; i-- {
-		finalHandler = s.middlewares[i](finalHandler)
-	}
-	s.router.Handle(pattern, finalHandler).Methods("PUT")
+func (s *Server) PUT(pattern string, handler http.Handler, opts ...RouteOption) {
+	s.handleMethod(http.MethodPut, pattern, handler, opts)
 }
 
 // DELETE registers a DELETE handler with path parameter support
 // Pattern can include path parameters like "/user/{id}" or "/user/{id:[0-9]+}"
-func (s *Server) DELETE(pattern string, handler http.Handler) {
-	// Apply all middlewares in reverse order
-	finalHandler := handler
-	for i := len(s.middlewares) - 1; i >= 0; i-- {
-		finalHandler = s.middlewares[i](finalHandler)
-	}
-	s.router.Handle(pattern, finalHandler).Methods("DELETE")
+func (s *Server) DELETE(pattern string, handler http.Handler, opts ...RouteOption) {
+	s.handleMethod(http.MethodDelete, pattern, handler, opts)
 }
 
 // PATCH registers a PATCH handler with path parameter support
 // Pattern can include path parameters like "/user/{id}" or "/user/{id:[0-9]+}"
-func (s *Server) PATCH(pattern string, handler http.Handler) {
-	// Apply all middlewares in reverse order
+func (s *Server) PATCH(pattern string, handler http.Handler, opts ...RouteOption) {
+	s.handleMethod(http.MethodPatch, pattern, handler, opts)
+}
+
+// handleMethod registers the handler for the HTTP method.
+func (s *Server) handleMethod(method, pattern string, handler http.Handler, opts []RouteOption) {
+	s.handleRoute(s.router, method, pattern, handler, nil, opts)
+}
+
+// handleRoute applies the middlewares and the server middlewares to the handler, registers it on the router
+// for the method, or all methods when empty, and records the route for the OpenAPI document.
+// A CORS route option replaces the CORS policy of the server for the route.
+func (s *Server) handleRoute(router *mux.Router, method, pattern string, handler http.Handler, middleware []mux.MiddlewareFunc, opts []RouteOption) {
+	var doc routeDoc
+	for _, opt := range opts {
+		opt(&doc)
+	}
+	if doc.cors != nil {
+		policy := doc.cors
+		middleware = append(middleware[:len(middleware):len(middleware)], func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				policy.serve(w, r, next)
+			})
+		})
+	}
+
+	r := router.Handle(pattern, s.wrap(handler, middleware))
+	if method != "" {
+		r.Methods(method)
+	}
+	if doc.cors != nil {
+		s.routeCORS[r] = doc.cors
+	}
+	s.addRoute(method, pattern, handler, doc)
+}
+
+// wrap applies the given middlewares, then the server middlewares, to the handler.
+// The first middleware of each list is the outermost one.
+func (s *Server) wrap(handler http.Handler, middleware []mux.MiddlewareFunc) http.Handler {
+	// Apply handler-specific middlewares first (in reverse order)
 	finalHandler := handler
+	for i := len(middleware) - 1; i >= 0; i-- {
+		finalHandler = middleware[i](finalHandler)
+	}
+
+	// Then apply server middlewares
 	for i := len(s.middlewares) - 1; i >= 0; i-- {
 		finalHandler = s.middlewares[i](finalHandler)
 	}
-	s.router.Handle(pattern, finalHandler).Methods("PATCH")
+	return finalHandler
 }
 
-// Start initializes the server and begins listening for incoming HTTP requests on the configured address.
-func (s *Server) Start() error {
+// checkStart reports the setup missing to start the server.
+func (s *Server) checkStart() error {
 	if s.app == nil {
 		return fmt.Errorf("logmanager application is not set")
 	}
 	if !s.hasSetUpCORS {
 		return fmt.Errorf("CORS middleware is not set")
 	}
+	return nil
+}
+
+// Start initializes the server and begins listening for incoming HTTP requests on the configured address.
+func (s *Server) Start() error {
+	if err := s.checkStart(); err != nil {
+		return err
+	}
 
 	// Warn if not running in production environment
 	env := s.app.Environment()
@@ -161,19 +214,62 @@ func (s *Server) Start() error {
 		fmt.Printf("[WARNING] Server is running in '%s' environment. Set APP_ENV=production for production deployments.\n", env)
 	}
 
-	fmt.Println("starting server on: ", s.server.Addr)
-	return s.server.ListenAndServe()
+	if !s.ssl {
+		fmt.Println("starting server on: ", s.server.Addr)
+		return s.server.ListenAndServe()
+	}
+
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return err
+	}
+	s.server.TLSConfig = tlsConfig
+
+	if s.httpRedirectAddr != "" {
+		redirect := &http.Server{
+			Addr:              s.httpRedirectAddr,
+			Handler:           httpsRedirectHandler(s.server.Addr),
+			ReadHeaderTimeout: s.readTimeout,
+		}
+		s.mu.Lock()
+		s.redirect = redirect
+		s.mu.Unlock()
+		go func() {
+			fmt.Println("starting HTTPS redirect on: ", redirect.Addr)
+			if err := redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fmt.Println("[WARNING] HTTPS redirect listener stopped:", err)
+			}
+		}()
+	}
+
+	fmt.Println("starting TLS server on: ", s.server.Addr)
+	return s.server.ListenAndServeTLS("", "")
 }
 
 // Stop gracefully shuts down the server without interrupting any active connections.
+// Open WebSocket connections are closed with 1001 going away and waited for.
 func (s *Server) Stop(ctx context.Context) error {
-	return s.server.Shutdown(ctx)
+	s.mu.Lock()
+	redirect := s.redirect
+	s.mu.Unlock()
+	if redirect != nil {
+		_ = redirect.Shutdown(ctx)
+	}
+	if err := s.server.Shutdown(ctx); err != nil {
+		return err
+	}
+	return s.webSockets.wait(ctx)
 }
 
 // registerHealthCheck registers the health check endpoint on the server
 func (s *Server) registerHealthCheck() {
 	healthHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
+		if s.shuttingDown.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"status":"shutting_down"}`))
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte(`{"status":"ok"}`))
 	})
@@ -0,0 +1,373 @@
+package httpmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/gorilla/mux"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSSEHeartbeat    = 15 * time.Second
+	defaultSSEWriteTimeout = 10 * time.Second
+)
+
+// ErrStreamClosed is returned when sending on a stream whose client disconnected or whose write failed.
+var ErrStreamClosed = errors.New("stream closed")
+
+// SSEMessage is a Server-Sent Event with its optional id, event name and reconnection delay.
+type SSEMessage[Event any] struct {
+	// ID is sent as the event id; the browser sends the last one back in the Last-Event-ID header when it reconnects.
+	ID string
+	// Name is the event type, "message" when empty.
+	Name string
+	// Data is the event payload. Strings and byte slices are sent as they are, other values as JSON.
+	Data Event
+	// Retry sets the reconnection delay of the client when positive.
+	Retry time.Duration
+}
+
+// SSEStream sends the events of a Server-Sent Events response. It is safe for concurrent use.
+type SSEStream[Event any] struct {
+	ctx          context.Context
+	cancel       context.CancelFunc
+	w            http.ResponseWriter
+	rc           *http.ResponseController
+	writeTimeout time.Duration
+	lastEventID  string
+
+	mu      sync.Mutex
+	opened  bool
+	closed  bool
+	started time.Time
+	events  int
+}
+
+// LastEventID returns the Last-Event-ID header of a reconnecting client, to resume the stream after
+// the last event it received. It is empty on the first connection.
+func (s *SSEStream[Event]) LastEventID() string {
+	return s.lastEventID
+}
+
+// Send sends an event with the data.
+func (s *SSEStream[Event]) Send(data Event) error {
+	return s.SendMessage(SSEMessage[Event]{Data: data})
+}
+
+// SendMessage sends an event with its id, name and reconnection delay.
+func (s *SSEStream[Event]) SendMessage(msg SSEMessage[Event]) error {
+	data, err := encodeSSEData(msg.Data)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if msg.ID != "" {
+		writeSSEField(&buf, "id", msg.ID)
+	}
+	if msg.Name != "" {
+		writeSSEField(&buf, "event", msg.Name)
+	}
+	if msg.Retry > 0 {
+		writeSSEField(&buf, "retry", strconv.FormatInt(msg.Retry.Milliseconds(), 10))
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		writeSSEField(&buf, "data", line)
+	}
+	buf.WriteByte('\n')
+
+	return s.write(buf.Bytes(), true)
+}
+
+// SendAll sends the events received from the channel until it is closed, which returns nil,
+// or until the stream is closed, which returns ErrStreamClosed.
+func (s *SSEStream[Event]) SendAll(events <-chan Event) error {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return ErrStreamClosed
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := s.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// heartbeat sends a comment line, which keeps the connection open through proxies without
+// dispatching an event on the client.
+func (s *SSEStream[Event]) heartbeat() {
+	_ = s.write([]byte(": heartbeat\n\n"), false)
+}
+
+// write opens the stream if needed and writes the bytes, within the write timeout.
+func (s *SSEStream[Event]) write(b []byte, event bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed || s.ctx.Err() != nil {
+		return ErrStreamClosed
+	}
+	if !s.opened {
+		s.open()
+	}
+
+	if s.writeTimeout > 0 {
+		_ = s.rc.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+	}
+	_, err := s.w.Write(b)
+	if err == nil {
+		err = s.rc.Flush()
+	}
+	if err != nil {
+		// The client is gone or too slow, the handler sees its context canceled
+		s.closed = true
+		s.cancel()
+		return ErrStreamClosed
+	}
+	if event {
+		s.events++
+	}
+	return nil
+}
+
+// open writes the response headers. The write deadline of the server is cleared, each write sets its own.
+func (s *SSEStream[Event]) open() {
+	s.opened = true
+	s.started = time.Now()
+	if txn := logmanager.FromContext(s.ctx); txn != nil {
+		txn.StartStream()
+	}
+
+	h := s.w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	_ = s.rc.SetWriteDeadline(time.Time{})
+	s.w.WriteHeader(http.StatusOK)
+}
+
+// encodeSSEData returns strings and byte slices as they are, and other values as JSON.
+func encodeSSEData(data any) ([]byte, error) {
+	switch v := data.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("encode event data: %w", err)
+	}
+	return b, nil
+}
+
+// writeSSEField writes a field line; line breaks would end the field, so they are removed from the value.
+func writeSSEField(buf *bytes.Buffer, name, value string) {
+	if name != "data" {
+		value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	}
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// SSEHandlerFunc streams the events of a request. ctx is canceled when the client disconnects.
+// An error returned before the first event is written as an error response; after it, it is recorded
+// on the logmanager transaction.
+type SSEHandlerFunc[Req any, Event any] func(ctx context.Context, req *Req, stream *SSEStream[Event]) error
+
+// SSEHandler serves Server-Sent Events. The request is bound and validated like the request of a Handler,
+// from the path, query, header and cookie parameters.
+type SSEHandler[Req any, Event any] struct {
+	method         string
+	handlerFunc    SSEHandlerFunc[Req, Event]
+	middlewares    []mux.MiddlewareFunc
+	heartbeat      time.Duration
+	writeTimeout   time.Duration
+	skipValidation bool
+}
+
+// NewSSEHandler creates a Server-Sent Events handler for the method, usually GET.
+func NewSSEHandler[Req any, Event any](method string, handlerFunc SSEHandlerFunc[Req, Event]) *SSEHandler[Req, Event] {
+	if handlerFunc == nil {
+		panic("handlerFunc cannot be nil")
+	}
+	return &SSEHandler[Req, Event]{
+		method:       method,
+		handlerFunc:  handlerFunc,
+		heartbeat:    defaultSSEHeartbeat,
+		writeTimeout: defaultSSEWriteTimeout,
+	}
+}
+
+// WithHeartbeat sets the interval of the heartbeat comments keeping idle streams open. Zero disables them.
+// Defaults to 15s.
+func (h *SSEHandler[Req, Event]) WithHeartbeat(interval time.Duration) *SSEHandler[Req, Event] {
+	h.heartbeat = interval
+	return h
+}
+
+// WithWriteTimeout sets the deadline of each event write, replacing the WriteTimeout of the server for
+// the stream. A client that does not read within it is disconnected. Zero disables it. Defaults to 10s.
+func (h *SSEHandler[Req, Event]) WithWriteTimeout(timeout time.Duration) *SSEHandler[Req, Event] {
+	h.writeTimeout = timeout
+	return h
+}
+
+// WithoutValidation disables the validation of the request against its `validate` tags.
+func (h *SSEHandler[Req, Event]) WithoutValidation() *SSEHandler[Req, Event] {
+	h.skipValidation = true
+	return h
+}
+
+// Use adds middleware to the handler
+func (h *SSEHandler[Req, Event]) Use(middleware ...mux.MiddlewareFunc) *SSEHandler[Req, Event] {
+	h.middlewares = append(h.middlewares, middleware...)
+	return h
+}
+
+// WithMiddleware returns an http.Handler with the middleware applied
+func (h *SSEHandler[Req, Event]) WithMiddleware() http.Handler {
+	var handler http.Handler = h
+	for i := len(h.middlewares) - 1; i >= 0; i-- {
+		handler = h.middlewares[i](handler)
+	}
+	return handler
+}
+
+// ServeHTTP binds the request and streams the events of the handler func.
+func (h *SSEHandler[Req, Event]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != h.method {
+		WriteError(w, r, methodNotAllowedError(r))
+		return
+	}
+	if !acceptsEventStream(r) {
+		WriteError(w, r, &HTTPError{
+			StatusCode: http.StatusNotAcceptable,
+			Title:      "not acceptable",
+			Detail:     "the response is a text/event-stream",
+		})
+		return
+	}
+
+	queryParams := QueryParams(r.URL.Query())
+	pathParams := extractPathParams(r)
+	ctx := r.Context()
+	ctx = context.WithValue(ctx, queryParamsKey, queryParams)
+	ctx = context.WithValue(ctx, pathParamsKey, pathParams)
+	ctx = context.WithValue(ctx, RequestKey, r)
+
+	var req Req
+	reqValue := reflect.ValueOf(&req).Elem()
+	b := binderFor(reqValue.Type())
+	if err := b.setDefaults(reqValue); err != nil {
+		WriteError(w, r, internalError(err))
+		return
+	}
+	if err := b.bind(r, reqValue, pathParams, queryParams); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if !h.skipValidation {
+		if err := validatorFromContext(ctx).check(r, &req); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream := &SSEStream[Event]{
+		ctx:          ctx,
+		cancel:       cancel,
+		w:            w,
+		rc:           http.NewResponseController(w),
+		writeTimeout: h.writeTimeout,
+		lastEventID:  r.Header.Get("Last-Event-ID"),
+	}
+
+	stopHeartbeat := h.startHeartbeat(ctx, stream)
+	err := h.handlerFunc(ctx, &req, stream)
+	stopHeartbeat()
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	stream.closed = true
+
+	if !stream.opened {
+		switch {
+		case err != nil:
+			WriteError(w, r, err)
+		default:
+			// No Content tells EventSource clients not to reconnect
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+
+	// A disconnected client is the normal end of a stream
+	if errors.Is(err, ErrStreamClosed) || errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	if txn := logmanager.FromContext(r.Context()); txn != nil {
+		txn.NoticeStream(stream.events, time.Since(stream.started), err)
+	}
+}
+
+// startHeartbeat sends heartbeats until the returned function is called.
+func (h *SSEHandler[Req, Event]) startHeartbeat(ctx context.Context, stream *SSEStream[Event]) (stop func()) {
+	if h.heartbeat <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(h.heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				stream.heartbeat()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// acceptsEventStream reports whether the Accept header of the request allows text/event-stream.
+func acceptsEventStream(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return true
+	}
+	for _, mediaRange := range parseAccept(accept) {
+		if mediaRange.matches("text/event-stream") {
+			return true
+		}
+	}
+	return false
+}
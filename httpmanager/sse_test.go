@@ -0,0 +1,244 @@
+package httpmanager
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderEventsRequest struct {
+	OrderID string `path:"id" validate:"required,alphanum"`
+}
+
+type orderEvent struct {
+	OrderID string `json:"order_id"`
+	Status  string `json:"status"`
+}
+
+var orderEventStatuses = []string{"created", "paid", "shipped", "delivered"}
+
+// streamOrder sends the statuses of the order after the Last-Event-ID of the client.
+func streamOrder(ctx context.Context, req *orderEventsRequest, stream *SSEStream[orderEvent]) error {
+	if req.OrderID == "missing" {
+		return errOrderNotFound
+	}
+	next := 0
+	if id := stream.LastEventID(); id != "" {
+		last, err := strconv.Atoi(id)
+		if err != nil {
+			return &HTTPError{StatusCode: http.StatusBadRequest, Title: "invalid Last-Event-ID"}
+		}
+		next = last + 1
+	}
+	for i := next; i < len(orderEventStatuses); i++ {
+		err := stream.SendMessage(SSEMessage[orderEvent]{
+			ID:   strconv.Itoa(i),
+			Name: "status",
+			Data: orderEvent{OrderID: req.OrderID, Status: orderEventStatuses[i]},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getStream(t *testing.T, url string, header ...string) *http.Response {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestSSEHandler(t *testing.T) {
+	app := logmanager.NewTestableApplication()
+	server := NewServer(app.Application)
+	server.MapError(errOrderNotFound, http.StatusNotFound, "not found")
+	server.GET("/orders/{id}/events", NewSSEHandler(http.MethodGet, streamOrder))
+	server.GET("/orders/{id}/empty", NewSSEHandler(http.MethodGet, func(ctx context.Context, req *orderEventsRequest, stream *SSEStream[orderEvent]) error {
+		return nil
+	}))
+	ts := httptest.NewServer(server.router)
+	defer ts.Close()
+
+	t.Run("streams the events", func(t *testing.T) {
+		app.ResetLoggedEntries()
+		resp := getStream(t, ts.URL+"/orders/A1/events")
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+		assert.Equal(t, "id: 0\nevent: status\ndata: {\"order_id\":\"A1\",\"status\":\"created\"}\n\n"+
+			"id: 1\nevent: status\ndata: {\"order_id\":\"A1\",\"status\":\"paid\"}\n\n"+
+			"id: 2\nevent: status\ndata: {\"order_id\":\"A1\",\"status\":\"shipped\"}\n\n"+
+			"id: 3\nevent: status\ndata: {\"order_id\":\"A1\",\"status\":\"delivered\"}\n\n", string(body))
+
+		require.Eventually(t, func() bool { return app.CountLoggedEntries() > 0 }, time.Second, 10*time.Millisecond)
+		assert.Equal(t, 4, app.GetLoggedField("stream_events"))
+		assert.NotNil(t, app.GetLoggedField("stream_duration"))
+		assert.Nil(t, app.GetLoggedField("response"), "the stream body should not be captured")
+	})
+
+	t.Run("resumes after the Last-Event-ID", func(t *testing.T) {
+		resp := getStream(t, ts.URL+"/orders/A1/events", "Last-Event-ID", "2")
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, "id: 3\nevent: status\ndata: {\"order_id\":\"A1\",\"status\":\"delivered\"}\n\n", string(body))
+	})
+
+	t.Run("error before the first event", func(t *testing.T) {
+		resp := getStream(t, ts.URL+"/orders/missing/events")
+
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		resp := getStream(t, ts.URL+"/orders/A-1/events")
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("no events", func(t *testing.T) {
+		resp := getStream(t, ts.URL+"/orders/A1/empty")
+
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		resp := getStream(t, ts.URL+"/orders/A1/events", "Accept", "application/json")
+
+		assert.Equal(t, http.StatusNotAcceptable, resp.StatusCode)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		NewSSEHandler(http.MethodGet, streamOrder).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders/A1/events", nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	})
+}
+
+func TestSSEHandler_Heartbeat(t *testing.T) {
+	handler := NewSSEHandler(http.MethodGet, func(ctx context.Context, req *struct{}, stream *SSEStream[string]) error {
+		select {
+		case <-ctx.Done():
+		case <-time.After(120 * time.Millisecond):
+		}
+		return stream.Send("line 1\nline 2")
+	}).WithHeartbeat(20 * time.Millisecond)
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	resp := getStream(t, ts.URL)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(string(body), ": heartbeat\n\n"), "the first heartbeat opens the stream")
+	assert.Contains(t, string(body), "data: line 1\ndata: line 2\n\n")
+}
+
+func TestSSEHandler_ClientDisconnect(t *testing.T) {
+	app := logmanager.NewTestableApplication()
+	server := NewServer(app.Application)
+
+	done := make(chan error, 1)
+	server.GET("/ticks", NewSSEHandler(http.MethodGet, func(ctx context.Context, req *struct{}, stream *SSEStream[int]) error {
+		ticks := make(chan int)
+		go func() {
+			defer close(ticks)
+			for i := 0; ; i++ {
+				select {
+				case <-ctx.Done():
+					return
+				case ticks <- i:
+					time.Sleep(5 * time.Millisecond)
+				}
+			}
+		}()
+		err := stream.SendAll(ticks)
+		done <- err
+		return err
+	}))
+	ts := httptest.NewServer(server.router)
+	defer ts.Close()
+
+	resp := getStream(t, ts.URL+"/ticks")
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: 0\n", line)
+	require.NoError(t, resp.Body.Close())
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrStreamClosed)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the handler did not see the client disconnect")
+	}
+
+	require.Eventually(t, func() bool { return app.CountLoggedEntries() > 0 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "info", app.GetLoggedLevel().String(), "a disconnected client is not an error")
+	assert.Greater(t, app.GetLoggedField("stream_events"), 0)
+}
+
+func TestSSEHandler_ServerWriteTimeout(t *testing.T) {
+	handler := NewSSEHandler(http.MethodGet, func(ctx context.Context, req *struct{}, stream *SSEStream[string]) error {
+		for i := 0; i < 5; i++ {
+			time.Sleep(30 * time.Millisecond)
+			if err := stream.Send(fmt.Sprint(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	ts := httptest.NewUnstartedServer(handler)
+	ts.Config.WriteTimeout = 50 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	resp := getStream(t, ts.URL)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err, "the stream should outlive the server WriteTimeout")
+
+	assert.Equal(t, "data: 0\n\ndata: 1\n\ndata: 2\n\ndata: 3\n\ndata: 4\n\n", string(body))
+}
+
+func TestSSEHandler_ErrorAfterFirstEvent(t *testing.T) {
+	app := logmanager.NewTestableApplication()
+	server := NewServer(app.Application)
+	server.GET("/feed", NewSSEHandler(http.MethodGet, func(ctx context.Context, req *struct{}, stream *SSEStream[string]) error {
+		if err := stream.Send("first"); err != nil {
+			return err
+		}
+		return errors.New("order feed closed")
+	}))
+
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/feed", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "data: first\n\n", rec.Body.String())
+	assert.Equal(t, "order feed closed", app.GetLoggedMessage())
+	assert.Equal(t, 1, app.GetLoggedField("stream_events"))
+}
@@ -1,18 +1,68 @@
 package httpmanager
 
 import (
-	"github.com/gorilla/mux"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// CacheControlImmutable is the cache policy of fingerprinted assets, e.g. app.3f9a2c.js, whose content
+	// never changes under the same name.
+	CacheControlImmutable = "public, max-age=31536000, immutable"
+	// CacheControlNoCache is the cache policy of files revalidated on each use, e.g. index.html.
+	CacheControlNoCache = "no-cache"
+
+	defaultStaticCacheControl = "public, max-age=86400"
+	staticIndexFile           = "index.html"
 )
 
-// StaticHandler handles serving static files, particularly images
+// staticEncodings are the precompressed siblings of a file, by preference.
+var staticEncodings = []struct {
+	name string
+	ext  string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// StaticHandler handles serving static files from a directory or any fs.FS, such as an embed.FS
 type StaticHandler struct {
-	rootDir     string
-	method      string
-	middlewares []mux.MiddlewareFunc
+	rootDir       string
+	fsys          fs.FS
+	method        string
+	index         bool
+	spaFallback   string
+	cachePolicies []staticCachePolicy
+	etags         sync.Map
+	middlewares   []mux.MiddlewareFunc
+}
+
+// staticCachePolicy is the Cache-Control of the files matching a pattern.
+type staticCachePolicy struct {
+	pattern      string
+	cacheControl string
+}
+
+// staticETag is the ETag of a file, kept while its size and modification time do not change.
+type staticETag struct {
+	size    int64
+	modTime time.Time
+	etag    string
 }
 
 // NewStaticHandler creates a new handler for serving static files
@@ -22,13 +72,55 @@ func NewStaticHandler(rootDir string) *StaticHandler {
 		panic("failed to create static files directory: " + err.Error())
 	}
 
+	handler := NewStaticHandlerFS(os.DirFS(rootDir))
+	handler.rootDir = rootDir
+	return handler
+}
+
+// NewStaticHandlerFS creates a new handler for serving the static files of fsys, e.g. an embed.FS.
+// Use fs.Sub to serve a subdirectory of it.
+func NewStaticHandlerFS(fsys fs.FS) *StaticHandler {
+	if fsys == nil {
+		panic("static file system must not be nil")
+	}
+
 	return &StaticHandler{
-		rootDir:     rootDir,
+		fsys:        fsys,
 		method:      http.MethodGet,
 		middlewares: []mux.MiddlewareFunc{},
 	}
 }
 
+// WithIndex serves the index.html file of a directory for the requests of the directory, instead of
+// rejecting them with 403. A request without the trailing slash is redirected to it.
+func (h *StaticHandler) WithIndex() *StaticHandler {
+	h.index = true
+	return h
+}
+
+// WithSPAFallback serves the file at fallback, e.g. "index.html", for the paths without a file, so the
+// client-side router of a single-page application handles them. Paths with a file extension, such as a
+// missing script, are still answered with 404.
+func (h *StaticHandler) WithSPAFallback(fallback string) *StaticHandler {
+	h.spaFallback = strings.TrimPrefix(path.Clean("/"+fallback), "/")
+	return h
+}
+
+// WithCachePolicy sets the Cache-Control header of the files matching pattern, in the syntax of path.Match.
+// A pattern with a slash is matched against the path of the file in the file system, e.g. "assets/*.js",
+// otherwise against its name, e.g. "*.html". The first matching policy applies; files without one are
+// cached for 24 hours.
+func (h *StaticHandler) WithCachePolicy(pattern, cacheControl string) *StaticHandler {
+	if _, err := path.Match(pattern, ""); err != nil {
+		panic("invalid cache policy pattern " + strconv.Quote(pattern) + ": " + err.Error())
+	}
+	h.cachePolicies = append(h.cachePolicies, staticCachePolicy{
+		pattern:      strings.TrimPrefix(pattern, "/"),
+		cacheControl: cacheControl,
+	})
+	return h
+}
+
 // Use adds middleware to the handler
 func (h *StaticHandler) Use(middleware ...mux.MiddlewareFunc) *StaticHandler {
 	h.middlewares = append(h.middlewares, middleware...)
@@ -49,28 +141,29 @@ func (h *StaticHandler) WithMiddleware() http.Handler {
 
 // ServeHTTP processes incoming HTTP requests for static files
 func (h *StaticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.Method != h.method {
+	if r.Method != h.method && r.Method != http.MethodHead {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Extract the file path from the URL
-	urlPath := r.URL.Path
-
-	// Sanitize the path to prevent directory traversal attacks
-	cleanPath := filepath.Clean(urlPath)
-	if strings.Contains(cleanPath, "..") {
+	// Sanitize the path to prevent directory traversal attacks: the cleaned path of a rooted path has
+	// no ".." element, and fs.FS paths are unrooted
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
 		http.Error(w, "Invalid file path", http.StatusBadRequest)
 		return
 	}
 
-	// Construct the full file path
-	filePath := filepath.Join(h.rootDir, cleanPath)
-
-	// Check if the file exists
-	fileInfo, err := os.Stat(filePath) // #nosec G703 - filePath is sanitized above with filepath.Clean and ".." check
+	info, err := fs.Stat(h.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) && h.spaFallback != "" && path.Ext(name) == "" {
+		name = h.spaFallback
+		info, err = fs.Stat(h.fsys, name)
+	}
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			http.Error(w, "File not found", http.StatusNotFound)
 		} else {
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -78,31 +171,195 @@ func (h *StaticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Don't serve directories
-	if fileInfo.IsDir() {
-		http.Error(w, "Cannot serve directories", http.StatusForbidden)
-		return
+	if info.IsDir() {
+		// Don't serve directories
+		if !h.index {
+			http.Error(w, "Cannot serve directories", http.StatusForbidden)
+			return
+		}
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			target := r.URL.Path + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		name = path.Join(name, staticIndexFile)
+		if info, err = fs.Stat(h.fsys, name); err != nil || info.IsDir() {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
 	}
 
-	// Open the file
-	file, err := os.Open(filepath.Clean(filePath)) // #nosec G304,G703 - filePath is sanitized above with filepath.Clean and ".." check
+	h.serveFile(w, r, name, info)
+}
+
+// serveFile serves the file, or its precompressed sibling when the client accepts its encoding
+func (h *StaticHandler) serveFile(w http.ResponseWriter, r *http.Request, name string, info fs.FileInfo) {
+	contentType := getContentType(name)
+
+	servedName, servedInfo, encoding := name, info, ""
+	precompressed := false
+	for _, enc := range staticEncodings {
+		sibling, err := fs.Stat(h.fsys, name+enc.ext)
+		if err != nil || sibling.IsDir() {
+			continue
+		}
+		precompressed = true
+		if acceptsEncoding(r.Header.Get("Accept-Encoding"), enc.name) {
+			servedName, servedInfo, encoding = name+enc.ext, sibling, enc.name
+			break
+		}
+	}
+	if precompressed {
+		// The response depends on Accept-Encoding as soon as the file has a precompressed sibling
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+
+	content, err := h.open(servedName)
 	if err != nil {
 		http.Error(w, "Failed to open file", http.StatusInternalServerError)
 		return
 	}
 	defer func() {
-		_ = file.Close()
+		_ = content.Close()
 	}()
 
-	// Set the content type based on file extension
-	contentType := getContentType(filePath)
+	if contentType == "application/octet-stream" {
+		// Unknown extension: detect the type from the first bytes of the uncompressed content
+		if contentType, err = h.sniffContentType(name); err != nil {
+			http.Error(w, "Failed to read file", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	etag, err := h.etag(servedName, servedInfo, content)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", contentType)
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", h.cacheControl(name))
+
+	// Serve the file; ServeContent answers the conditional and range requests from the ETag
+	http.ServeContent(w, r, path.Base(name), servedInfo.ModTime(), content)
+}
+
+// staticFile is an open file of the file system that can be served by http.ServeContent.
+type staticFile interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// open opens a file, reading it in memory when the file system does not return seekable files
+func (h *StaticHandler) open(name string) (staticFile, error) {
+	file, err := h.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if seeker, ok := file.(staticFile); ok {
+		return seeker, nil
+	}
 
-	// Set cache control headers for better performance
-	w.Header().Set("Cache-Control", "public, max-age=86400") // Cache for 24 hours
+	data, err := io.ReadAll(file)
+	_ = file.Close()
+	if err != nil {
+		return nil, err
+	}
+	return nopSeekCloser{bytes.NewReader(data)}, nil
+}
 
-	// Serve the file
-	http.ServeContent(w, r, fileInfo.Name(), fileInfo.ModTime(), file)
+type nopSeekCloser struct {
+	*bytes.Reader
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+// sniffContentType detects the content type of a file without a known extension from its content
+func (h *StaticHandler) sniffContentType(name string) (string, error) {
+	file, err := h.fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	return http.DetectContentType(header[:n]), nil
+}
+
+// etag returns the strong ETag of a file, the SHA-256 hash of its content. It is computed once per
+// version of the file and content is rewound after it.
+func (h *StaticHandler) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if cached, ok := h.etags.Load(name); ok {
+		cached := cached.(staticETag)
+		if cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+			return cached.etag, nil
+		}
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	h.etags.Store(name, staticETag{size: info.Size(), modTime: info.ModTime(), etag: etag})
+	return etag, nil
+}
+
+// cacheControl returns the Cache-Control of the first cache policy matching the file
+func (h *StaticHandler) cacheControl(name string) string {
+	for _, policy := range h.cachePolicies {
+		target := path.Base(name)
+		if strings.Contains(policy.pattern, "/") {
+			target = name
+		}
+		if matched, _ := path.Match(policy.pattern, target); matched {
+			return policy.cacheControl
+		}
+	}
+	return defaultStaticCacheControl
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header accepts the content coding with a non-zero
+// quality, explicitly or through "*".
+func acceptsEncoding(header, encoding string) bool {
+	accepted := false
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != encoding && coding != "*" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if coding == encoding {
+			// An explicit coding overrides "*"
+			return quality > 0
+		}
+		accepted = quality > 0
+	}
+	return accepted
 }
 
 // getContentType determines the content type based on file extension
@@ -120,14 +377,52 @@ func getContentType(filePath string) string {
 		return "image/svg+xml"
 	case ".webp":
 		return "image/webp"
+	case ".avif":
+		return "image/avif"
 	case ".ico":
 		return "image/x-icon"
 	case ".bmp":
 		return "image/bmp"
 	case ".tiff", ".tif":
 		return "image/tiff"
-	default:
-		// Default to binary data for unknown types
-		return "application/octet-stream"
+	case ".html", ".htm":
+		return "text/html; charset=utf-8"
+	case ".css":
+		return "text/css; charset=utf-8"
+	case ".js", ".mjs":
+		return "text/javascript; charset=utf-8"
+	case ".json", ".map":
+		return "application/json"
+	case ".webmanifest":
+		return "application/manifest+json"
+	case ".txt":
+		return "text/plain; charset=utf-8"
+	case ".xml":
+		return "application/xml"
+	case ".wasm":
+		return "application/wasm"
+	case ".woff":
+		return "font/woff"
+	case ".woff2":
+		return "font/woff2"
+	case ".ttf":
+		return "font/ttf"
+	case ".otf":
+		return "font/otf"
+	case ".pdf":
+		return "application/pdf"
+	case ".mp4":
+		return "video/mp4"
+	case ".webm":
+		return "video/webm"
+	case ".mp3":
+		return "audio/mpeg"
+	}
+
+	// Fall back to the MIME types registered in the system and with mime.AddExtensionType
+	if contentType := mime.TypeByExtension(ext); ext != "" && contentType != "" {
+		return contentType
 	}
+	// Default to binary data for unknown types
+	return "application/octet-stream"
 }
@@ -1,11 +1,18 @@
 package httpmanager
 
 import (
+	"io/fs"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/httpmanager/internal/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewStaticHandler(t *testing.T) {
@@ -293,7 +300,11 @@ func TestStaticHandler_ServeHTTP_ContentTypes(t *testing.T) {
 		{"test.bmp", "image/bmp"},
 		{"test.tiff", "image/tiff"},
 		{"test.tif", "image/tiff"},
-		{"test.unknown", "application/octet-stream"},
+		{"test.js", "text/javascript; charset=utf-8"},
+		{"test.css", "text/css; charset=utf-8"},
+		{"test.woff2", "font/woff2"},
+		// Unknown extensions are detected from the content
+		{"test.unknown", "text/plain; charset=utf-8"},
 	}
 
 	for _, tc := range testCases {
@@ -353,3 +364,187 @@ func TestGetContentType(t *testing.T) {
 		})
 	}
 }
+
+func newStaticRequest(t *testing.T, handler http.Handler, target string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func testStaticFS() fstest.MapFS {
+	modTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	return fstest.MapFS{
+		"index.html":                 {Data: []byte("<!doctype html><title>app</title>"), ModTime: modTime},
+		"assets/app.3f9a2c.js":       {Data: []byte("console.log('app')"), ModTime: modTime},
+		"assets/app.3f9a2c.js.br":    {Data: []byte("brotli"), ModTime: modTime},
+		"assets/app.3f9a2c.js.gz":    {Data: []byte("gzip"), ModTime: modTime},
+		"assets/style.css":           {Data: []byte("body{}"), ModTime: modTime},
+		"docs/index.html":            {Data: []byte("<p>docs</p>"), ModTime: modTime},
+		"docs/guide/getting-started": {Data: []byte("%PDF-1.7"), ModTime: modTime},
+	}
+}
+
+func TestStaticHandlerFS(t *testing.T) {
+	handler := NewStaticHandlerFS(testStaticFS())
+
+	rr := newStaticRequest(t, handler, "/assets/style.css", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "body{}", rr.Body.String())
+	assert.Equal(t, "text/css; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "Fri, 02 Jan 2026 03:04:05 GMT", rr.Header().Get("Last-Modified"))
+	assert.Empty(t, rr.Header().Get("Vary"), "files without precompressed siblings do not vary")
+
+	rr = newStaticRequest(t, handler, "/docs/guide/getting-started", nil)
+	assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"), "files without extension are sniffed")
+
+	rr = newStaticRequest(t, handler, "/docs/", nil)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "directories are rejected without WithIndex")
+
+	assert.Panics(t, func() { NewStaticHandlerFS(nil) })
+}
+
+func TestStaticHandler_ETag(t *testing.T) {
+	handler := NewStaticHandlerFS(testStaticFS())
+
+	rr := newStaticRequest(t, handler, "/assets/style.css", nil)
+	etag := rr.Header().Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag, "strong ETag")
+
+	rr = newStaticRequest(t, handler, "/assets/style.css", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+
+	rr = newStaticRequest(t, handler, "/assets/style.css", map[string]string{"If-None-Match": `"other"`})
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = newStaticRequest(t, handler, "/assets/style.css", map[string]string{"Range": "bytes=0-3", "If-Range": etag})
+	assert.Equal(t, http.StatusPartialContent, rr.Code)
+	assert.Equal(t, "body", rr.Body.String())
+
+	t.Run("changed file", func(t *testing.T) {
+		dir := t.TempDir()
+		filePath := filepath.Join(dir, "data.txt")
+		require.NoError(t, os.WriteFile(filePath, []byte("v1"), 0644))
+		handler := NewStaticHandler(dir)
+		first := newStaticRequest(t, handler, "/data.txt", nil).Header().Get("ETag")
+
+		require.NoError(t, os.WriteFile(filePath, []byte("v2"), 0644))
+		require.NoError(t, os.Chtimes(filePath, time.Now(), time.Now().Add(time.Hour)))
+		second := newStaticRequest(t, handler, "/data.txt", nil).Header().Get("ETag")
+		assert.NotEqual(t, first, second)
+	})
+}
+
+func TestStaticHandler_Precompressed(t *testing.T) {
+	handler := NewStaticHandlerFS(testStaticFS())
+
+	tests := []struct {
+		acceptEncoding string
+		wantEncoding   string
+		wantBody       string
+	}{
+		{"gzip, deflate, br", "br", "brotli"},
+		{"gzip", "gzip", "gzip"},
+		{"br;q=0, gzip;q=0.5", "gzip", "gzip"},
+		{"*", "br", "brotli"},
+		{"br;q=0, *", "gzip", "gzip"},
+		{"identity", "", "console.log('app')"},
+		{"", "", "console.log('app')"},
+	}
+	etags := map[string]string{}
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			rr := newStaticRequest(t, handler, "/assets/app.3f9a2c.js", map[string]string{"Accept-Encoding": tt.acceptEncoding})
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.wantBody, rr.Body.String())
+			assert.Equal(t, tt.wantEncoding, rr.Header().Get("Content-Encoding"))
+			assert.Equal(t, "text/javascript; charset=utf-8", rr.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+			etags[tt.wantEncoding] = rr.Header().Get("ETag")
+		})
+	}
+	assert.Len(t, etags, 3, "each encoding has its own ETag")
+}
+
+func TestStaticHandler_IndexAndSPAFallback(t *testing.T) {
+	handler := NewStaticHandlerFS(testStaticFS()).WithIndex().WithSPAFallback("index.html")
+
+	rr := newStaticRequest(t, handler, "/docs/", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "<p>docs</p>", rr.Body.String())
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+
+	rr = newStaticRequest(t, handler, "/docs?page=2", nil)
+	assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+	assert.Equal(t, "/docs/?page=2", rr.Header().Get("Location"))
+
+	rr = newStaticRequest(t, handler, "/", nil)
+	assert.Equal(t, "<!doctype html><title>app</title>", rr.Body.String())
+
+	rr = newStaticRequest(t, handler, "/users/42/settings", nil)
+	require.Equal(t, http.StatusOK, rr.Code, "client-side routes fall back to the SPA")
+	assert.Equal(t, "<!doctype html><title>app</title>", rr.Body.String())
+
+	rr = newStaticRequest(t, handler, "/assets/missing.js", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code, "missing assets are not replaced by the SPA")
+
+	rr = newStaticRequest(t, NewStaticHandlerFS(fstest.MapFS{"empty/.keep": {}}).WithIndex(), "/empty/", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code, "a directory without index.html")
+
+	server := NewServer(testdata.NewApplication())
+	server.Handle("/{path:.*}", handler)
+	for _, target := range []string{"/", "/users/42", "/assets/style.css"} {
+		rr = newStaticRequest(t, server.router, target, nil)
+		assert.Equal(t, http.StatusOK, rr.Code, target)
+	}
+}
+
+func TestStaticHandler_CachePolicies(t *testing.T) {
+	handler := NewStaticHandlerFS(testStaticFS()).
+		WithSPAFallback("index.html").
+		WithCachePolicy("assets/*.*.js", CacheControlImmutable).
+		WithCachePolicy("*.html", CacheControlNoCache)
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/assets/app.3f9a2c.js", CacheControlImmutable},
+		{"/assets/style.css", "public, max-age=86400"},
+		{"/index.html", CacheControlNoCache},
+		{"/docs/index.html", CacheControlNoCache},
+		{"/users/42", CacheControlNoCache},
+	}
+	for _, tt := range tests {
+		rr := newStaticRequest(t, handler, tt.target, map[string]string{"Accept-Encoding": "br"})
+		assert.Equal(t, tt.want, rr.Header().Get("Cache-Control"), tt.target)
+	}
+
+	assert.Panics(t, func() { handler.WithCachePolicy("[", CacheControlNoCache) })
+}
+
+// unseekableFS returns files that cannot seek, like the files of some archive file systems.
+type unseekableFS struct {
+	fs.FS
+}
+
+type unseekableFile struct {
+	fs.File
+}
+
+func (u unseekableFS) Open(name string) (fs.File, error) {
+	file, err := u.FS.Open(name)
+	return unseekableFile{file}, err
+}
+
+func TestStaticHandler_UnseekableFS(t *testing.T) {
+	handler := NewStaticHandlerFS(unseekableFS{testStaticFS()})
+
+	rr := newStaticRequest(t, handler, "/assets/style.css", map[string]string{"Range": "bytes=4-"})
+	assert.Equal(t, http.StatusPartialContent, rr.Code)
+	assert.Equal(t, "{}", rr.Body.String())
+}
@@ -0,0 +1,173 @@
+package httpmanager
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/gorilla/mux"
+)
+
+// tlsConfig builds the TLS configuration of the server from the SSL options.
+func (o *Option) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	switch {
+	case o.certData != "" || o.keyData != "":
+		certificate, err := tls.X509KeyPair([]byte(o.certData), []byte(o.keyData))
+		if err != nil {
+			return nil, fmt.Errorf("load certificate data: %w", err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	case o.certFile != "" && o.keyFile != "":
+		reloader, err := newCertReloader(o.certFile, o.keyFile, o.certReloadInterval)
+		if err != nil {
+			return nil, err
+		}
+		config.GetCertificate = reloader.getCertificate
+	default:
+		return nil, errors.New("SSL is enabled but no certificate is set: use WithCertFile and WithKeyFile, or WithCertData and WithKeyData")
+	}
+
+	if o.clientCAFile != "" || o.clientCAData != "" {
+		caData := []byte(o.clientCAData)
+		if o.clientCAFile != "" {
+			var err error
+			if caData, err = os.ReadFile(filepath.Clean(o.clientCAFile)); err != nil {
+				return nil, fmt.Errorf("read client CA file: %w", err)
+			}
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, errors.New("no certificates found in client CA bundle")
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	if o.clientAuth != tls.NoClientCert {
+		config.ClientAuth = o.clientAuth
+	}
+
+	return config, nil
+}
+
+// certReloader serves the certificate pair of the given files, reloading it when the files change.
+type certReloader struct {
+	certFile, keyFile string
+	interval          time.Duration
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	modTime     time.Time
+	checkedAt   time.Time
+}
+
+func newCertReloader(certFile, keyFile string, interval time.Duration) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, interval: interval}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// lastModified returns the latest modification time of the certificate and key files.
+func (r *certReloader) lastModified() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (r *certReloader) reload() error {
+	modTime, err := r.lastModified()
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.certificate = &certificate
+	r.modTime = modTime
+	return nil
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	certificate := r.certificate
+	due := r.interval > 0 && time.Since(r.checkedAt) >= r.interval
+	if due {
+		r.checkedAt = time.Now()
+	}
+	loadedAt := r.modTime
+	r.mu.Unlock()
+
+	if !due {
+		return certificate, nil
+	}
+	modTime, err := r.lastModified()
+	if err != nil || !modTime.After(loadedAt) {
+		return certificate, nil
+	}
+	// A failed reload, e.g. while the files are being replaced, keeps serving the current certificate.
+	if err := r.reload(); err != nil {
+		fmt.Println("[WARNING] certificate reload failed:", err)
+		return certificate, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.certificate, nil
+}
+
+// clientCertMiddleware records the subject of the verified client certificate in the logmanager transaction.
+func clientCertMiddleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+				if txn := logmanager.FromContext(r.Context()); txn != nil {
+					txn.AddTags("client_cert:" + r.TLS.VerifiedChains[0][0].Subject.String())
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// httpsRedirectHandler redirects plain HTTP requests to the HTTPS address of the server.
+func httpsRedirectHandler(tlsAddr string) http.Handler {
+	_, tlsPort, _ := net.SplitHostPort(tlsAddr)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if tlsPort != "" && tlsPort != "443" {
+			host = net.JoinHostPort(host, tlsPort)
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
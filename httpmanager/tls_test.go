@@ -0,0 +1,334 @@
+package httpmanager
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/httpmanager/internal/testdata"
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key signed by the CA.
+func (ca testCA) issue(t *testing.T, commonName string, server bool) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"SALT"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return addr
+}
+
+// startServer starts the server in the background and stops it when the test ends.
+func startServer(t *testing.T, server *Server) {
+	t.Helper()
+	server.EnableCORS(nil, nil, nil, false)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Start()
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = server.Stop(ctx)
+	})
+
+	require.Eventually(t, func() bool {
+		select {
+		case err := <-errs:
+			require.NoError(t, err)
+		default:
+		}
+		conn, err := net.Dial("tcp", server.server.Addr)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestOption_tlsConfig(t *testing.T) {
+	ca := newTestCA(t)
+	cert, key := ca.issue(t, "server", true)
+
+	t.Run("defaults to TLS 1.2", func(t *testing.T) {
+		config, err := (&Option{certData: cert, keyData: key}).tlsConfig()
+
+		require.NoError(t, err)
+		assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+		assert.Len(t, config.Certificates, 1)
+		assert.Equal(t, tls.NoClientCert, config.ClientAuth)
+	})
+
+	t.Run("client CA requires client certificates", func(t *testing.T) {
+		config, err := (&Option{certData: cert, keyData: key, clientCAData: string(ca.pem)}).tlsConfig()
+
+		require.NoError(t, err)
+		assert.Equal(t, tls.RequireAndVerifyClientCert, config.ClientAuth)
+		assert.NotNil(t, config.ClientCAs)
+	})
+
+	t.Run("client auth override", func(t *testing.T) {
+		config, err := (&Option{
+			certData:     cert,
+			keyData:      key,
+			clientCAData: string(ca.pem),
+			clientAuth:   tls.VerifyClientCertIfGiven,
+		}).tlsConfig()
+
+		require.NoError(t, err)
+		assert.Equal(t, tls.VerifyClientCertIfGiven, config.ClientAuth)
+	})
+
+	tests := []struct {
+		name    string
+		option  *Option
+		wantErr string
+	}{
+		{name: "no certificate", option: &Option{}, wantErr: "no certificate is set"},
+		{name: "invalid certificate data", option: &Option{certData: "cert", keyData: key}, wantErr: "load certificate data"},
+		{name: "missing certificate file", option: &Option{certFile: "missing.crt", keyFile: "missing.key"}, wantErr: "load certificate"},
+		{name: "invalid client CA", option: &Option{certData: cert, keyData: key, clientCAData: "ca"}, wantErr: "no certificates found"},
+		{name: "missing client CA file", option: &Option{certData: cert, keyData: key, clientCAFile: "missing.pem"}, wantErr: "read client CA file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.option.tlsConfig()
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	write := func(commonName string, modTime time.Time) {
+		cert, key := ca.issue(t, commonName, true)
+		require.NoError(t, os.WriteFile(certFile, []byte(cert), 0o600))
+		require.NoError(t, os.WriteFile(keyFile, []byte(key), 0o600))
+		require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+		require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+	}
+	commonName := func(certificate *tls.Certificate) string {
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		require.NoError(t, err)
+		return leaf.Subject.CommonName
+	}
+
+	write("v1", time.Now().Add(-time.Minute))
+	reloader, err := newCertReloader(certFile, keyFile, time.Millisecond)
+	require.NoError(t, err)
+
+	certificate, err := reloader.getCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", commonName(certificate))
+
+	write("v2", time.Now())
+	time.Sleep(5 * time.Millisecond)
+	certificate, err = reloader.getCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", commonName(certificate))
+
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	time.Sleep(5 * time.Millisecond)
+	certificate, err = reloader.getCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", commonName(certificate))
+}
+
+func TestServer_StartTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "server", true)
+	clientCert, clientKey := ca.issue(t, "partner-a", false)
+	clientPair, err := tls.X509KeyPair([]byte(clientCert), []byte(clientKey))
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	addr, redirectAddr := freeAddr(t), freeAddr(t)
+	app := logmanager.NewTestableApplication()
+	server := NewServer(app.Application,
+		WithAddr(addr),
+		WithSSL(true),
+		WithCertData(serverCert),
+		WithKeyData(serverKey),
+		WithClientCAData(string(ca.pem)),
+		WithHTTPRedirect(redirectAddr),
+	)
+	server.GET("/whoami", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	startServer(t, server)
+
+	newClient := func(certificates ...tls.Certificate) *http.Client {
+		return &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				RootCAs:      roots,
+				Certificates: certificates,
+				MinVersion:   tls.VersionTLS12,
+			}},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	t.Run("verified client certificate", func(t *testing.T) {
+		res, err := newClient(clientPair).Get("https://" + addr + "/whoami")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "partner-a", string(body))
+		assert.Eventually(t, func() bool {
+			for _, entry := range app.GetLoggedEntries() {
+				if tags, ok := entry.Data["tags"].([]string); ok && slices.Contains(tags, "client_cert:CN=partner-a,O=SALT") {
+					return true
+				}
+			}
+			return false
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("missing client certificate", func(t *testing.T) {
+		res, err := newClient().Get("https://" + addr + "/whoami")
+		if err == nil {
+			_ = res.Body.Close()
+		}
+		assert.Error(t, err)
+	})
+
+	t.Run("TLS 1.1 is rejected", func(t *testing.T) {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{clientPair},
+			MaxVersion:   tls.VersionTLS11,
+			MinVersion:   tls.VersionTLS10, // #nosec G402 - asserting the server refuses old versions
+		})
+		if err == nil {
+			_ = conn.Close()
+		}
+		assert.Error(t, err)
+	})
+
+	t.Run("HTTP redirect", func(t *testing.T) {
+		require.Eventually(t, func() bool {
+			conn, err := net.Dial("tcp", redirectAddr)
+			if err == nil {
+				_ = conn.Close()
+			}
+			return err == nil
+		}, 2*time.Second, 10*time.Millisecond)
+
+		res, err := newClient().Get("http://" + redirectAddr + "/whoami?x=1")
+		require.NoError(t, err)
+		_ = res.Body.Close()
+
+		_, port, _ := net.SplitHostPort(addr)
+		assert.Equal(t, http.StatusPermanentRedirect, res.StatusCode)
+		assert.Equal(t, "https://127.0.0.1:"+port+"/whoami?x=1", res.Header.Get("Location"))
+	})
+}
+
+func TestServer_StartTLS_Errors(t *testing.T) {
+	server := NewServer(testdata.NewApplication(), WithAddr(freeAddr(t)), WithSSL(true))
+	server.EnableCORS(nil, nil, nil, false)
+
+	err := server.Start()
+	assert.ErrorContains(t, err, "no certificate is set")
+}
+
+func TestHTTPSRedirectHandler(t *testing.T) {
+	tests := []struct {
+		tlsAddr string
+		host    string
+		want    string
+	}{
+		{tlsAddr: ":443", host: "api.example.com", want: "https://api.example.com/a?b=c"},
+		{tlsAddr: ":443", host: "api.example.com:80", want: "https://api.example.com/a?b=c"},
+		{tlsAddr: ":8443", host: "api.example.com:8080", want: "https://api.example.com:8443/a?b=c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tlsAddr+" "+tt.host, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://"+tt.host+"/a?b=c", nil)
+			rr := httptest.NewRecorder()
+
+			httpsRedirectHandler(tt.tlsAddr).ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusPermanentRedirect, rr.Code)
+			assert.Equal(t, tt.want, rr.Header().Get("Location"))
+		})
+	}
+}
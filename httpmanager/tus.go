@@ -0,0 +1,453 @@
+package httpmanager
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,creation-with-upload,expiration,termination"
+	// tusChunkContentType is the content type of the PATCH requests.
+	tusChunkContentType = "application/offset+octet-stream"
+	// defaultTusExpiration is the time an upload can take to complete.
+	defaultTusExpiration = 24 * time.Hour
+)
+
+// TusCompleteFunc is called with the assembled file once all the bytes of an upload are received. The upload is
+// deleted from the store when it returns nil; an error is answered to the request of the last chunk.
+type TusCompleteFunc func(ctx context.Context, upload TusUpload, file io.Reader) error
+
+// TusHandler handles resumable uploads with the tus 1.0 protocol, with the creation, creation-with-upload,
+// expiration and termination extensions. It must be registered on the creation path and on a path with an
+// {id} parameter below it, e.g. "/files" and "/files/{id}".
+type TusHandler struct {
+	store       TusStore
+	onComplete  TusCompleteFunc
+	maxSize     int64
+	expiration  time.Duration
+	locks       sync.Map
+	middlewares []mux.MiddlewareFunc
+}
+
+// NewTusHandler creates a new handler keeping the uploads in store until they are completed
+func NewTusHandler(store TusStore, onComplete TusCompleteFunc) *TusHandler {
+	if store == nil {
+		panic("store cannot be nil")
+	}
+	if onComplete == nil {
+		panic("onComplete cannot be nil")
+	}
+
+	return &TusHandler{
+		store:       store,
+		onComplete:  onComplete,
+		expiration:  defaultTusExpiration,
+		middlewares: []mux.MiddlewareFunc{},
+	}
+}
+
+// WithMaxSize sets the maximum size of an upload. Zero, the default, allows any size
+func (h *TusHandler) WithMaxSize(maxSize int64) *TusHandler {
+	h.maxSize = maxSize
+	return h
+}
+
+// WithExpiration sets the time an upload can take to complete. Zero disables the expiration
+func (h *TusHandler) WithExpiration(expiration time.Duration) *TusHandler {
+	h.expiration = expiration
+	return h
+}
+
+// Use adds middleware to the handler
+func (h *TusHandler) Use(middleware ...mux.MiddlewareFunc) *TusHandler {
+	h.middlewares = append(h.middlewares, middleware...)
+	return h
+}
+
+// WithMiddleware returns an http.Handler with the middleware applied
+func (h *TusHandler) WithMiddleware() http.Handler {
+	var handler http.Handler = h
+
+	// Apply all middlewares in reverse order
+	for i := len(h.middlewares) - 1; i >= 0; i-- {
+		handler = h.middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// RemoveExpired deletes the expired uploads from the store and returns their number. Call it periodically.
+// Uploads with a chunk being written are left for the next call.
+func (h *TusHandler) RemoveExpired(ctx context.Context) (int, error) {
+	uploads, err := h.store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	removed := 0
+	for _, upload := range uploads {
+		if !upload.expired(now) {
+			continue
+		}
+		deleted, err := h.removeUnlocked(ctx, upload.ID)
+		if err != nil {
+			return removed, err
+		}
+		if deleted {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// removeUnlocked deletes the upload of id unless a request holds its lock, and reports whether it did.
+func (h *TusHandler) removeUnlocked(ctx context.Context, id string) (bool, error) {
+	lock := h.lock(id)
+	if !lock.TryLock() {
+		return false, nil
+	}
+	defer lock.Unlock()
+
+	if err := h.store.Delete(ctx, id); err != nil {
+		return false, err
+	}
+	h.locks.Delete(id)
+	return true, nil
+}
+
+// ServeHTTP dispatches the tus requests
+func (h *TusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	method := r.Method
+	if override := r.Header.Get("X-HTTP-Method-Override"); override != "" && method == http.MethodPost {
+		method = override
+	}
+
+	if method == http.MethodOptions {
+		h.serveOptions(w)
+		return
+	}
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		WriteError(w, r, tusError(http.StatusPreconditionFailed, "unsupported tus version", "Tus-Resumable must be "+tusVersion))
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	switch {
+	case id == "" && method == http.MethodPost:
+		h.serveCreate(w, r)
+	case id != "" && method == http.MethodHead:
+		h.serveHead(w, r, id)
+	case id != "" && method == http.MethodPatch:
+		h.servePatch(w, r, id)
+	case id != "" && method == http.MethodDelete:
+		h.serveDelete(w, r, id)
+	default:
+		WriteError(w, r, tusError(http.StatusMethodNotAllowed, "method not allowed", method+" is not a tus request of this path"))
+	}
+}
+
+func (h *TusHandler) serveOptions(w http.ResponseWriter) {
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	if h.maxSize > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.maxSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveCreate creates an upload, and writes the body of the request as its first chunk when it has one
+func (h *TusHandler) serveCreate(w http.ResponseWriter, r *http.Request) {
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		WriteError(w, r, tusError(http.StatusBadRequest, "invalid upload length", "Upload-Length must be a non-negative integer"))
+		return
+	}
+	if h.maxSize > 0 && size > h.maxSize {
+		WriteError(w, r, tusError(http.StatusRequestEntityTooLarge, "upload too large", fmt.Sprintf("Upload-Length exceeds %d bytes", h.maxSize)))
+		return
+	}
+	metadata, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		WriteError(w, r, tusError(http.StatusBadRequest, "invalid upload metadata", err.Error()))
+		return
+	}
+
+	now := time.Now()
+	upload := TusUpload{ID: uuid.NewString(), Size: size, Metadata: metadata, CreatedAt: now}
+	if h.expiration > 0 {
+		upload.ExpiresAt = now.Add(h.expiration)
+	}
+	ctx := r.Context()
+	if err := h.store.Create(ctx, upload); err != nil {
+		WriteError(w, r, internalError(fmt.Errorf("error creating tus upload: %w", err)))
+		return
+	}
+
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+upload.ID)
+	setTusExpires(w, upload)
+
+	if isTusChunk(r) {
+		lock := h.lock(upload.ID)
+		lock.Lock()
+		defer lock.Unlock()
+		if upload, err = h.writeChunk(ctx, r, upload); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if upload.Complete() {
+		if err := h.complete(ctx, upload); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *TusHandler) serveHead(w http.ResponseWriter, r *http.Request, id string) {
+	upload, err := h.get(r.Context(), id)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	if len(upload.Metadata) > 0 {
+		w.Header().Set("Upload-Metadata", formatTusMetadata(upload.Metadata))
+	}
+	setTusExpires(w, upload)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *TusHandler) servePatch(w http.ResponseWriter, r *http.Request, id string) {
+	if !isTusChunk(r) {
+		WriteError(w, r, tusError(http.StatusUnsupportedMediaType, "unsupported media type", "Content-Type must be "+tusChunkContentType))
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		WriteError(w, r, tusError(http.StatusBadRequest, "invalid upload offset", "Upload-Offset must be a non-negative integer"))
+		return
+	}
+
+	// Concurrent chunks of an upload are rejected rather than queued
+	lock := h.lock(id)
+	if !lock.TryLock() {
+		WriteError(w, r, tusError(http.StatusLocked, "upload locked", "another chunk of the upload is being written"))
+		return
+	}
+	defer lock.Unlock()
+
+	ctx := r.Context()
+	upload, err := h.get(ctx, id)
+	if err != nil {
+		h.locks.Delete(id)
+		WriteError(w, r, err)
+		return
+	}
+	if offset != upload.Offset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		WriteError(w, r, tusError(http.StatusConflict, "offset mismatch", fmt.Sprintf("Upload-Offset must be %d", upload.Offset)))
+		return
+	}
+
+	// The offset is sent on errors too, an interrupted chunk keeps the bytes it wrote
+	upload, err = h.writeChunk(ctx, r, upload)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	setTusExpires(w, upload)
+	if upload.Complete() {
+		if err := h.complete(ctx, upload); err != nil {
+			WriteError(w, r, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TusHandler) serveDelete(w http.ResponseWriter, r *http.Request, id string) {
+	// An upload is not deleted under a chunk being written
+	lock := h.lock(id)
+	if !lock.TryLock() {
+		WriteError(w, r, tusError(http.StatusLocked, "upload locked", "a chunk of the upload is being written"))
+		return
+	}
+	defer lock.Unlock()
+
+	ctx := r.Context()
+	if _, err := h.get(ctx, id); err != nil {
+		h.locks.Delete(id)
+		WriteError(w, r, err)
+		return
+	}
+	if err := h.store.Delete(ctx, id); err != nil {
+		WriteError(w, r, internalError(fmt.Errorf("error deleting tus upload: %w", err)))
+		return
+	}
+	h.locks.Delete(id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// get returns the upload of id, or the HTTP error of a missing or expired upload
+func (h *TusHandler) get(ctx context.Context, id string) (TusUpload, error) {
+	upload, err := h.store.Get(ctx, id)
+	if errors.Is(err, ErrTusUploadNotFound) {
+		return upload, tusError(http.StatusNotFound, "upload not found", "no upload with this id")
+	}
+	if err != nil {
+		return upload, internalError(fmt.Errorf("error reading tus upload: %w", err))
+	}
+	if upload.expired(time.Now()) {
+		return upload, tusError(http.StatusGone, "upload expired", "the upload expired at "+upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	return upload, nil
+}
+
+// writeChunk writes the body of the request at the offset of the upload, in a segment of the transaction
+// recording the offset and the number of bytes, never the content
+func (h *TusHandler) writeChunk(ctx context.Context, r *http.Request, upload TusUpload) (TusUpload, error) {
+	remaining := upload.Size - upload.Offset
+	if r.ContentLength > remaining {
+		return upload, tusError(http.StatusRequestEntityTooLarge, "chunk too large", fmt.Sprintf("the upload has %d bytes left", remaining))
+	}
+
+	segment := logmanager.StartOtherSegmentWithContext(ctx, logmanager.OtherSegment{
+		Name:  "tus chunk",
+		Extra: map[string]interface{}{"upload_id": upload.ID},
+	})
+	defer segment.End()
+	segment.SetRequestValue(map[string]interface{}{"offset": upload.Offset, "length": r.ContentLength})
+
+	body := &tusChunkReader{r: io.LimitReader(r.Body, remaining)}
+	n, err := h.store.WriteChunk(ctx, upload.ID, upload.Offset, body)
+	upload.Offset += n
+	segment.SetResponseValue(map[string]interface{}{"bytes": n, "offset": upload.Offset})
+
+	switch {
+	case err == nil:
+		return upload, nil
+	case body.err != nil:
+		// The bytes received before the client failed are kept, the client resumes from the new offset
+		segment.SetBusinessError(body.err)
+		return upload, tusError(http.StatusBadRequest, "incomplete chunk", "the chunk was interrupted after "+strconv.FormatInt(n, 10)+" bytes")
+	case errors.Is(err, ErrTusOffsetMismatch):
+		segment.SetBusinessError(err)
+		return upload, tusError(http.StatusConflict, "offset mismatch", "the upload offset changed")
+	default:
+		segment.NoticeError(err)
+		return upload, internalError(fmt.Errorf("error writing tus chunk: %w", err))
+	}
+}
+
+// complete passes the assembled file to the completion func and deletes the upload when it succeeds
+func (h *TusHandler) complete(ctx context.Context, upload TusUpload) error {
+	file, err := h.store.Open(ctx, upload.ID)
+	if err != nil {
+		return internalError(fmt.Errorf("error opening tus upload: %w", err))
+	}
+	err = h.onComplete(ctx, upload, file)
+	_ = file.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := h.store.Delete(ctx, upload.ID); err != nil {
+		return internalError(fmt.Errorf("error deleting tus upload: %w", err))
+	}
+	h.locks.Delete(upload.ID)
+	return nil
+}
+
+// lock returns the lock of the upload of id. Its entry is dropped when the upload cannot be read, so
+// requests for unknown ids do not grow the locks.
+func (h *TusHandler) lock(id string) *sync.Mutex {
+	lock, _ := h.locks.LoadOrStore(id, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// tusChunkReader keeps the error reading the request apart from the errors of the store.
+type tusChunkReader struct {
+	r   io.Reader
+	err error
+}
+
+func (c *tusChunkReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.err = err
+	}
+	return n, err
+}
+
+func isTusChunk(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == tusChunkContentType
+}
+
+func setTusExpires(w http.ResponseWriter, upload TusUpload) {
+	if !upload.ExpiresAt.IsZero() {
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// parseTusMetadata decodes an Upload-Metadata header, comma-separated keys each followed by an optional
+// base64 encoded value.
+func parseTusMetadata(header string) (map[string]string, error) {
+	if strings.TrimSpace(header) == "" {
+		return nil, nil
+	}
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("Upload-Metadata has an empty key")
+		}
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("Upload-Metadata value of %q is not base64", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+func formatTusMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		if value == "" {
+			pairs = append(pairs, key)
+			continue
+		}
+		pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(value)))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func tusError(statusCode int, title, detail string) *HTTPError {
+	return &HTTPError{StatusCode: statusCode, Title: title, Detail: detail}
+}
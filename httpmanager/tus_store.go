@@ -0,0 +1,309 @@
+package httpmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTusUploadNotFound is returned by a TusStore for an unknown upload.
+	ErrTusUploadNotFound = errors.New("tus upload not found")
+	// ErrTusOffsetMismatch is returned by a TusStore for a chunk that does not start at the offset of the upload.
+	ErrTusOffsetMismatch = errors.New("tus upload offset mismatch")
+)
+
+// TusUpload is the state of a resumable upload.
+type TusUpload struct {
+	ID string `json:"id"`
+	// Size is the total size of the upload, from the Upload-Length header.
+	Size int64 `json:"size"`
+	// Offset is the number of bytes received.
+	Offset int64 `json:"offset"`
+	// Metadata is the decoded Upload-Metadata header, e.g. the "filename" and "filetype" sent by most clients.
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	// ExpiresAt is the time the upload expires if it is not completed. Zero if it does not expire.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// Complete reports whether all the bytes of the upload were received.
+func (u TusUpload) Complete() bool {
+	return u.Offset == u.Size
+}
+
+func (u TusUpload) expired(now time.Time) bool {
+	return !u.ExpiresAt.IsZero() && now.After(u.ExpiresAt)
+}
+
+// TusStore keeps the uploads of a TusHandler until they are completed.
+type TusStore interface {
+	// Create stores a new upload without content.
+	Create(ctx context.Context, upload TusUpload) error
+	// Get returns the upload of id, or ErrTusUploadNotFound.
+	Get(ctx context.Context, id string) (TusUpload, error)
+	// WriteChunk appends the content read from r to the upload of id, which must be at offset, and returns the
+	// number of bytes written. The bytes written before an error are kept, so an interrupted chunk is resumed
+	// from the new offset.
+	WriteChunk(ctx context.Context, id string, offset int64, r io.Reader) (int64, error)
+	// Open returns the content of the upload of id.
+	Open(ctx context.Context, id string) (io.ReadCloser, error)
+	// Delete removes the upload of id. Deleting a missing upload is not an error.
+	Delete(ctx context.Context, id string) error
+	// List returns the uploads of the store.
+	List(ctx context.Context) ([]TusUpload, error)
+}
+
+// TusFileStore keeps the uploads in a directory, with the content of each upload in a .bin file and its state
+// in a .info JSON file.
+type TusFileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewTusFileStore creates a store in dir, which is created if it does not exist.
+func NewTusFileStore(dir string) (*TusFileStore, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	return &TusFileStore{dir: dir}, nil
+}
+
+// Create writes the state and an empty content file of the upload.
+func (s *TusFileStore) Create(_ context.Context, upload TusUpload) error {
+	if !validTusID(upload.ID) {
+		return fmt.Errorf("invalid tus upload id %q", upload.ID)
+	}
+	f, err := os.OpenFile(s.path(upload.ID, ".bin"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return s.writeInfo(upload)
+}
+
+// Get reads the state of the upload.
+func (s *TusFileStore) Get(_ context.Context, id string) (TusUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readInfo(id)
+}
+
+// WriteChunk writes the content at offset in the content file and updates the offset of the upload.
+func (s *TusFileStore) WriteChunk(_ context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	s.mu.Lock()
+	upload, err := s.readInfo(id)
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if upload.Offset != offset {
+		return 0, ErrTusOffsetMismatch
+	}
+
+	f, err := os.OpenFile(s.path(id, ".bin"), os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, err
+	}
+	// Bytes of a chunk whose state was not saved are overwritten
+	n, err := f.Seek(offset, io.SeekStart)
+	if err == nil {
+		n, err = io.Copy(f, r)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	upload.Offset += n
+	if saveErr := s.writeInfo(upload); err == nil {
+		err = saveErr
+	}
+	return n, err
+}
+
+// Open opens the content file of the upload.
+func (s *TusFileStore) Open(_ context.Context, id string) (io.ReadCloser, error) {
+	if !validTusID(id) {
+		return nil, ErrTusUploadNotFound
+	}
+	f, err := os.Open(s.path(id, ".bin"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrTusUploadNotFound
+	}
+	return f, err
+}
+
+// Delete removes the files of the upload.
+func (s *TusFileStore) Delete(_ context.Context, id string) error {
+	if !validTusID(id) {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ext := range []string{".bin", ".info"} {
+		if err := os.Remove(s.path(id, ext)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// List reads the state of every upload of the directory.
+func (s *TusFileStore) List(_ context.Context) ([]TusUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var uploads []TusUpload
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".info")
+		if !ok {
+			continue
+		}
+		upload, err := s.readInfo(id)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, nil
+}
+
+func (s *TusFileStore) path(id, ext string) string {
+	return filepath.Join(s.dir, id+ext)
+}
+
+func (s *TusFileStore) readInfo(id string) (TusUpload, error) {
+	if !validTusID(id) {
+		return TusUpload{}, ErrTusUploadNotFound
+	}
+	data, err := os.ReadFile(s.path(id, ".info"))
+	if errors.Is(err, os.ErrNotExist) {
+		return TusUpload{}, ErrTusUploadNotFound
+	}
+	if err != nil {
+		return TusUpload{}, err
+	}
+	var upload TusUpload
+	err = json.Unmarshal(data, &upload)
+	return upload, err
+}
+
+func (s *TusFileStore) writeInfo(upload TusUpload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(upload.ID, ".info"), data, 0600)
+}
+
+// validTusID reports whether id can name the files of an upload.
+func validTusID(id string) bool {
+	return id != "" && id != "." && id != ".." && !strings.ContainsAny(id, `/\`)
+}
+
+// TusMemoryStore keeps the uploads in memory, for tests and development.
+type TusMemoryStore struct {
+	mu      sync.Mutex
+	uploads map[string]*tusMemoryUpload
+}
+
+type tusMemoryUpload struct {
+	upload  TusUpload
+	content []byte
+}
+
+// NewTusMemoryStore creates an empty in-memory store.
+func NewTusMemoryStore() *TusMemoryStore {
+	return &TusMemoryStore{uploads: map[string]*tusMemoryUpload{}}
+}
+
+// Create keeps the new upload.
+func (s *TusMemoryStore) Create(_ context.Context, upload TusUpload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.uploads[upload.ID]; ok {
+		return fmt.Errorf("tus upload %q already exists", upload.ID)
+	}
+	s.uploads[upload.ID] = &tusMemoryUpload{upload: upload}
+	return nil
+}
+
+// Get returns the upload.
+func (s *TusMemoryStore) Get(_ context.Context, id string) (TusUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.uploads[id]
+	if !ok {
+		return TusUpload{}, ErrTusUploadNotFound
+	}
+	return stored.upload, nil
+}
+
+// WriteChunk reads the whole chunk and appends it to the upload.
+func (s *TusMemoryStore) WriteChunk(ctx context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	upload, err := s.Get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if upload.Offset != offset {
+		return 0, ErrTusOffsetMismatch
+	}
+
+	var chunk bytes.Buffer
+	n, err := io.Copy(&chunk, r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.uploads[id]
+	if !ok {
+		return 0, ErrTusUploadNotFound
+	}
+	stored.content = append(stored.content[:offset], chunk.Bytes()...)
+	stored.upload.Offset = offset + n
+	return n, err
+}
+
+// Open returns a reader of the content of the upload.
+func (s *TusMemoryStore) Open(_ context.Context, id string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.uploads[id]
+	if !ok {
+		return nil, ErrTusUploadNotFound
+	}
+	return io.NopCloser(bytes.NewReader(stored.content)), nil
+}
+
+// Delete removes the upload.
+func (s *TusMemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.uploads, id)
+	return nil
+}
+
+// List returns the uploads.
+func (s *TusMemoryStore) List(_ context.Context) ([]TusUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uploads := make([]TusUpload, 0, len(s.uploads))
+	for _, stored := range s.uploads {
+		uploads = append(uploads, stored.upload)
+	}
+	return uploads, nil
+}
@@ -0,0 +1,367 @@
+package httpmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// completedUploads records the uploads passed to the completion func.
+type completedUploads struct {
+	mu       sync.Mutex
+	uploads  []TusUpload
+	contents []string
+	err      error
+}
+
+func (c *completedUploads) complete(_ context.Context, upload TusUpload, file io.Reader) error {
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.uploads = append(c.uploads, upload)
+	c.contents = append(c.contents, string(content))
+	return c.err
+}
+
+func newTusServer(t *testing.T, handler *TusHandler) (*logmanager.TestableApplication, string) {
+	app := logmanager.NewTestableApplication()
+	server := NewServer(app.Application)
+	server.Handle("/files", handler)
+	server.Handle("/files/{id}", handler)
+	ts := httptest.NewServer(server.router)
+	t.Cleanup(ts.Close)
+	return app, ts.URL
+}
+
+func tusRequest(t *testing.T, method, url string, body []byte, header map[string]string) *http.Response {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	return resp
+}
+
+func patchChunk(t *testing.T, url string, offset int, chunk string) *http.Response {
+	return tusRequest(t, http.MethodPatch, url, []byte(chunk), map[string]string{
+		"Content-Type":  "application/offset+octet-stream",
+		"Upload-Offset": strconv.Itoa(offset),
+	})
+}
+
+func TestTusHandler(t *testing.T) {
+	completed := &completedUploads{}
+	_, baseURL := newTusServer(t, NewTusHandler(NewTusMemoryStore(), completed.complete).WithMaxSize(1<<10))
+
+	t.Run("options", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodOptions, baseURL+"/files", nil)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, "1.0.0", resp.Header.Get("Tus-Version"))
+		assert.Equal(t, "creation,creation-with-upload,expiration,termination", resp.Header.Get("Tus-Extension"))
+		assert.Equal(t, "1024", resp.Header.Get("Tus-Max-Size"))
+	})
+
+	t.Run("resumable upload", func(t *testing.T) {
+		metadata := "filename " + base64.StdEncoding.EncodeToString([]byte("kyc.mp4")) + ",is_private"
+		resp := tusRequest(t, http.MethodPost, baseURL+"/files", nil, map[string]string{"Upload-Length": "11", "Upload-Metadata": metadata})
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "1.0.0", resp.Header.Get("Tus-Resumable"))
+		assert.Equal(t, "0", resp.Header.Get("Upload-Offset"))
+		assert.NotEmpty(t, resp.Header.Get("Upload-Expires"))
+		location := resp.Header.Get("Location")
+		require.True(t, strings.HasPrefix(location, "/files/"), location)
+		uploadURL := baseURL + location
+
+		resp = patchChunk(t, uploadURL, 0, "hello ")
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, "6", resp.Header.Get("Upload-Offset"))
+
+		resp = tusRequest(t, http.MethodHead, uploadURL, nil, nil)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "6", resp.Header.Get("Upload-Offset"))
+		assert.Equal(t, "11", resp.Header.Get("Upload-Length"))
+		assert.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+		assert.Equal(t, "filename a3ljLm1wNA==,is_private", resp.Header.Get("Upload-Metadata"))
+
+		resp = patchChunk(t, uploadURL, 3, "lo world")
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		assert.Equal(t, "6", resp.Header.Get("Upload-Offset"))
+
+		resp = patchChunk(t, uploadURL, 6, "world")
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, "11", resp.Header.Get("Upload-Offset"))
+
+		require.Len(t, completed.uploads, 1)
+		assert.Equal(t, "hello world", completed.contents[0])
+		assert.Equal(t, map[string]string{"filename": "kyc.mp4", "is_private": ""}, completed.uploads[0].Metadata)
+
+		resp = tusRequest(t, http.MethodHead, uploadURL, nil, nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, "a completed upload is deleted from the store")
+	})
+
+	t.Run("creation with upload", func(t *testing.T) {
+		resp := tusRequest(t, http.MethodPost, baseURL+"/files", []byte("abc"), map[string]string{
+			"Upload-Length": "3",
+			"Content-Type":  "application/offset+octet-stream",
+		})
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "3", resp.Header.Get("Upload-Offset"))
+		assert.Equal(t, "abc", completed.contents[len(completed.contents)-1])
+	})
+
+	t.Run("termination", func(t *testing.T) {
+		resp := tusRequest(t, http.MethodPost, baseURL+"/files", nil, map[string]string{"Upload-Length": "10"})
+		uploadURL := baseURL + resp.Header.Get("Location")
+
+		resp = tusRequest(t, http.MethodDelete, uploadURL, nil, nil)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		resp = patchChunk(t, uploadURL, 0, "data")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		resp := tusRequest(t, http.MethodPost, baseURL+"/files", nil, map[string]string{"Upload-Length": "10"})
+		uploadURL := baseURL + resp.Header.Get("Location")
+
+		tests := []struct {
+			name   string
+			resp   *http.Response
+			status int
+		}{
+			{"missing upload length", tusRequest(t, http.MethodPost, baseURL+"/files", nil, nil), http.StatusBadRequest},
+			{"upload over the max size", tusRequest(t, http.MethodPost, baseURL+"/files", nil, map[string]string{"Upload-Length": "2048"}), http.StatusRequestEntityTooLarge},
+			{"invalid metadata", tusRequest(t, http.MethodPost, baseURL+"/files", nil, map[string]string{"Upload-Length": "1", "Upload-Metadata": "filename %%%"}), http.StatusBadRequest},
+			{"unsupported version", tusRequest(t, http.MethodHead, uploadURL, nil, map[string]string{"Tus-Resumable": "0.2.2"}), http.StatusPreconditionFailed},
+			{"chunk content type", tusRequest(t, http.MethodPatch, uploadURL, []byte("data"), map[string]string{"Upload-Offset": "0", "Content-Type": "text/plain"}), http.StatusUnsupportedMediaType},
+			{"chunk past the upload length", patchChunk(t, uploadURL, 0, "more than ten bytes"), http.StatusRequestEntityTooLarge},
+			{"unknown upload", patchChunk(t, baseURL+"/files/unknown", 0, "data"), http.StatusNotFound},
+			{"method", tusRequest(t, http.MethodGet, uploadURL, nil, nil), http.StatusMethodNotAllowed},
+		}
+		for _, tt := range tests {
+			assert.Equal(t, tt.status, tt.resp.StatusCode, tt.name)
+			assert.Equal(t, "1.0.0", tt.resp.Header.Get("Tus-Resumable"), tt.name)
+		}
+	})
+}
+
+func TestTusHandler_Expiration(t *testing.T) {
+	store := NewTusMemoryStore()
+	handler := NewTusHandler(store, (&completedUploads{}).complete).WithExpiration(20 * time.Millisecond)
+	_, baseURL := newTusServer(t, handler)
+
+	resp := tusRequest(t, http.MethodPost, baseURL+"/files", nil, map[string]string{"Upload-Length": "10"})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	expires, err := http.ParseTime(resp.Header.Get("Upload-Expires"))
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), expires, 2*time.Second)
+
+	time.Sleep(30 * time.Millisecond)
+	resp = patchChunk(t, baseURL+resp.Header.Get("Location"), 0, "data")
+	assert.Equal(t, http.StatusGone, resp.StatusCode)
+
+	removed, err := handler.RemoveExpired(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	uploads, err := store.List(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, uploads)
+}
+
+func TestTusHandler_LockedUpload(t *testing.T) {
+	store := NewTusMemoryStore()
+	handler := NewTusHandler(store, (&completedUploads{}).complete)
+	ctx := context.Background()
+	require.NoError(t, store.Create(ctx, TusUpload{ID: "video", Size: 10, CreatedAt: time.Now(), ExpiresAt: time.Now().Add(-time.Minute)}))
+
+	// A chunk of the upload is being written
+	lock := handler.lock("video")
+	lock.Lock()
+
+	req := httptest.NewRequest(http.MethodDelete, "/files/video", nil)
+	req.Header.Set("Tus-Resumable", "1.0.0")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, mux.SetURLVars(req, map[string]string{"id": "video"}))
+	assert.Equal(t, http.StatusLocked, rr.Code)
+
+	removed, err := handler.RemoveExpired(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, removed, "an upload being written is not removed")
+	_, err = store.Get(ctx, "video")
+	require.NoError(t, err)
+
+	lock.Unlock()
+	removed, err = handler.RemoveExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, ok := handler.locks.Load("video")
+	assert.False(t, ok, "the lock of a removed upload is released")
+}
+
+func TestTusHandler_UnknownUpload(t *testing.T) {
+	handler := NewTusHandler(NewTusMemoryStore(), (&completedUploads{}).complete)
+
+	for _, method := range []string{http.MethodPatch, http.MethodDelete} {
+		req := httptest.NewRequest(method, "/files/unknown", strings.NewReader("data"))
+		req.Header.Set("Tus-Resumable", "1.0.0")
+		req.Header.Set("Content-Type", "application/offset+octet-stream")
+		req.Header.Set("Upload-Offset", "0")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, mux.SetURLVars(req, map[string]string{"id": "unknown"}))
+		assert.Equal(t, http.StatusNotFound, rr.Code, method)
+	}
+
+	handler.locks.Range(func(id, _ any) bool {
+		t.Errorf("lock of %v is kept after a 404", id)
+		return true
+	})
+}
+
+func TestTusHandler_InterruptedChunk(t *testing.T) {
+	store, err := NewTusFileStore(t.TempDir())
+	require.NoError(t, err)
+	completed := &completedUploads{}
+	handler := NewTusHandler(store, completed.complete)
+
+	ctx := context.Background()
+	upload := TusUpload{ID: "video", Size: 10, CreatedAt: time.Now()}
+	require.NoError(t, store.Create(ctx, upload))
+
+	patch := func(offset int, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/files/video", body)
+		req.Header.Set("Tus-Resumable", "1.0.0")
+		req.Header.Set("Content-Type", "application/offset+octet-stream")
+		req.Header.Set("Upload-Offset", strconv.Itoa(offset))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, mux.SetURLVars(req, map[string]string{"id": "video"}))
+		return rr
+	}
+
+	// The connection drops after 4 bytes, which are kept
+	rr := patch(0, io.MultiReader(strings.NewReader("0123"), iotest.ErrReader(errors.New("connection reset"))))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "4", rr.Header().Get("Upload-Offset"))
+	stored, err := store.Get(ctx, "video")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), stored.Offset)
+
+	rr = patch(4, strings.NewReader("456789"))
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, []string{"0123456789"}, completed.contents)
+	_, err = store.Get(ctx, "video")
+	assert.ErrorIs(t, err, ErrTusUploadNotFound)
+}
+
+func TestTusHandler_CompletionError(t *testing.T) {
+	completed := &completedUploads{err: errors.New("virus scanner unavailable")}
+	store := NewTusMemoryStore()
+	_, baseURL := newTusServer(t, NewTusHandler(store, completed.complete))
+
+	resp := tusRequest(t, http.MethodPost, baseURL+"/files", nil, map[string]string{"Upload-Length": "4"})
+	uploadURL := baseURL + resp.Header.Get("Location")
+	resp = patchChunk(t, uploadURL, 0, "data")
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	resp = tusRequest(t, http.MethodHead, uploadURL, nil, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the upload is kept when the completion fails")
+	assert.Equal(t, "4", resp.Header.Get("Upload-Offset"))
+}
+
+func TestTusHandler_ChunkLogging(t *testing.T) {
+	app, baseURL := newTusServer(t, NewTusHandler(NewTusMemoryStore(), (&completedUploads{}).complete))
+
+	resp := tusRequest(t, http.MethodPost, baseURL+"/files", nil, map[string]string{"Upload-Length": "8"})
+	location := resp.Header.Get("Location")
+	id := strings.TrimPrefix(location, "/files/")
+	app.ResetLoggedEntries()
+
+	resp = patchChunk(t, baseURL+location, 0, "\x00\x01binary")
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	require.Eventually(t, func() bool { return app.CountLoggedEntries() >= 2 }, 2*time.Second, 10*time.Millisecond)
+	var segment, request map[string]interface{}
+	for _, entry := range app.GetLoggedEntries() {
+		switch entry.Data["type"] {
+		case logmanager.TxnTypeOther:
+			segment = entry.Data
+		case logmanager.TxnTypeHttp:
+			request = entry.Data
+		}
+	}
+	require.NotNil(t, segment)
+	assert.Equal(t, "tus chunk", segment["name"])
+	assert.Equal(t, id, segment["upload_id"])
+	assert.Equal(t, map[string]interface{}{"offset": int64(0), "length": int64(8)}, segment["request"])
+	assert.Equal(t, map[string]interface{}{"bytes": int64(8), "offset": int64(8)}, segment["response"])
+	require.NotNil(t, request)
+	assert.NotContains(t, request, "request", "the chunk content is never logged")
+}
+
+func TestTusStores(t *testing.T) {
+	fileStore, err := NewTusFileStore(t.TempDir())
+	require.NoError(t, err)
+	stores := map[string]TusStore{"file": fileStore, "memory": NewTusMemoryStore()}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			upload := TusUpload{ID: "doc", Size: 6, Metadata: map[string]string{"filename": "a.txt"}, CreatedAt: time.Now().UTC()}
+			require.NoError(t, store.Create(ctx, upload))
+			assert.Error(t, store.Create(ctx, upload), "ids are unique")
+
+			n, err := store.WriteChunk(ctx, "doc", 0, strings.NewReader("abc"))
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), n)
+			_, err = store.WriteChunk(ctx, "doc", 1, strings.NewReader("def"))
+			assert.ErrorIs(t, err, ErrTusOffsetMismatch)
+			_, err = store.WriteChunk(ctx, "doc", 3, strings.NewReader("def"))
+			require.NoError(t, err)
+
+			stored, err := store.Get(ctx, "doc")
+			require.NoError(t, err)
+			assert.True(t, stored.Complete())
+			assert.Equal(t, "a.txt", stored.Metadata["filename"])
+
+			file, err := store.Open(ctx, "doc")
+			require.NoError(t, err)
+			content, err := io.ReadAll(file)
+			require.NoError(t, err)
+			require.NoError(t, file.Close())
+			assert.Equal(t, "abcdef", string(content))
+
+			uploads, err := store.List(ctx)
+			require.NoError(t, err)
+			assert.Len(t, uploads, 1)
+
+			require.NoError(t, store.Delete(ctx, "doc"))
+			require.NoError(t, store.Delete(ctx, "doc"), "deleting a missing upload is not an error")
+			_, err = store.Get(ctx, "doc")
+			assert.ErrorIs(t, err, ErrTusUploadNotFound)
+			_, err = store.Get(ctx, "../doc")
+			assert.ErrorIs(t, err, ErrTusUploadNotFound)
+		})
+	}
+}
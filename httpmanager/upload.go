@@ -1,14 +1,17 @@
 package httpmanager
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"time"
 
@@ -20,33 +23,53 @@ type UploadedFile struct {
 	Filename    string
 	Size        int64
 	ContentType string
-	SavedPath   string
+	// Key is the key the file is stored under in the UploadStorage of the handler.
+	Key string
+	// SHA256 is the hex encoded SHA-256 hash of the content, computed while it is stored.
+	SHA256 string
+	// DetectedType is the MIME type detected from the content, unlike ContentType sent by the client.
+	DetectedType string
+	// Width and Height are the image dimensions, set for the fields with image dimension rules.
+	Width  int
+	Height int
+	// SavedPath is the path of the file when it is stored in a LocalStorage.
+	SavedPath string
 }
 
 // UploadHandler handles file uploads via multipart form data
 type UploadHandler struct {
 	handlerFunc func(ctx context.Context, files map[string][]*UploadedFile, form map[string][]string) (interface{}, error)
 	method      string
-	uploadDir   string
+	storage     UploadStorage
 	maxFileSize int64
+	rules       map[string]UploadRule
 	middlewares []mux.MiddlewareFunc
 }
 
-// NewUploadHandler creates a new handler for file uploads
+// NewUploadHandler creates a new handler for file uploads, storing the files in uploadDir
 func NewUploadHandler(method string, uploadDir string, handlerFunc func(ctx context.Context, files map[string][]*UploadedFile, form map[string][]string) (interface{}, error)) *UploadHandler {
+	// Create an upload directory if it doesn't exist
+	storage, err := NewLocalStorage(uploadDir)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create upload directory: %v", err))
+	}
+
+	return NewUploadHandlerWithStorage(method, storage, handlerFunc)
+}
+
+// NewUploadHandlerWithStorage creates a new handler for file uploads, streaming the files to storage
+func NewUploadHandlerWithStorage(method string, storage UploadStorage, handlerFunc func(ctx context.Context, files map[string][]*UploadedFile, form map[string][]string) (interface{}, error)) *UploadHandler {
 	if handlerFunc == nil {
 		panic("handlerFunc cannot be nil")
 	}
-
-	// Create an upload directory if it doesn't exist
-	if err := os.MkdirAll(uploadDir, 0750); err != nil {
-		panic(fmt.Sprintf("failed to create upload directory: %v", err))
+	if storage == nil {
+		panic("storage cannot be nil")
 	}
 
 	return &UploadHandler{
 		handlerFunc: handlerFunc,
 		method:      method,
-		uploadDir:   uploadDir,
+		storage:     storage,
 		maxFileSize: 10 << 20, // 10 MB default
 		middlewares: []mux.MiddlewareFunc{},
 	}
@@ -79,16 +102,17 @@ func (h *UploadHandler) WithMaxFileSize(maxSize int64) *UploadHandler {
 // ServeHTTP processes incoming HTTP requests with multipart form data
 func (h *UploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != h.method {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		WriteError(w, r, methodNotAllowedError(r))
 		return
 	}
 
 	// Limit the request body size
 	r.Body = http.MaxBytesReader(w, r.Body, h.maxFileSize)
 
-	// Parse the multipart form
-	if err := r.ParseMultipartForm(h.maxFileSize); err != nil {
-		http.Error(w, "Request too large or invalid multipart form", http.StatusBadRequest)
+	// Read the multipart form as a stream, so the files go to the storage without temporary copies
+	reader, err := r.MultipartReader()
+	if err != nil {
+		WriteError(w, r, invalidMultipartForm(err))
 		return
 	}
 
@@ -99,46 +123,22 @@ func (h *UploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	ctx = context.WithValue(ctx, pathParamsKey, pathParams)
 
-	// Process uploaded files
-	files, err := h.processUploadedFiles(r)
+	// Process uploaded files and form values
+	files, formValues, err := h.processUploadedFiles(ctx, reader)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error processing uploads: %v", err), http.StatusInternalServerError)
+		var ruleErr *uploadRuleError
+		if errors.As(err, &ruleErr) {
+			err = ruleErr.httpError(r)
+		}
+		WriteError(w, r, err)
 		return
 	}
 
-	// Get form values
-	formValues := make(map[string][]string)
-	for key, values := range r.MultipartForm.Value {
-		formValues[key] = values
-	}
-
 	// Call the handler function
 	resp, err := h.handlerFunc(ctx, files, formValues)
 	if err != nil {
-		// Check if the error is a ResponseError to use client-provided values
-		if isCustomV2, statusCode, body := checkCustomErrorV2(err); isCustomV2 {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(statusCode)
-			encoder := json.NewEncoder(w)
-			_ = encoder.Encode(body)
-			return
-		}
-
-		// Check if the error is a CustomError (deprecated) to use client-provided values
-		if detailedErr, ok := IsCustomError(err); ok {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(detailedErr.StatusCode)
-			encoder := json.NewEncoder(w)
-			_ = encoder.Encode(map[string]interface{}{
-				"code":  detailedErr.Code,
-				"title": detailedErr.Title,
-				"desc":  detailedErr.Desc,
-			})
-			return
-		}
-
-		// Default error response
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		// Write the error with the error handler of the server
+		WriteError(w, r, err)
 		return
 	}
 
@@ -155,38 +155,69 @@ func (h *UploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	_ = encoder.Encode(resp)
 }
 
-// processUploadedFiles processes all uploaded files from the request
-func (h *UploadHandler) processUploadedFiles(r *http.Request) (map[string][]*UploadedFile, error) {
-	if r.MultipartForm == nil || r.MultipartForm.File == nil {
-		return nil, nil
+// processUploadedFiles stores the files and reads the values of the multipart form. The files stored before
+// an error are deleted.
+func (h *UploadHandler) processUploadedFiles(ctx context.Context, reader *multipart.Reader) (map[string][]*UploadedFile, map[string][]string, error) {
+	files := make(map[string][]*UploadedFile)
+	form := make(map[string][]string)
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			if err = h.checkRequired(files); err == nil {
+				return files, form, nil
+			}
+		} else if err == nil {
+			err = h.processPart(ctx, part, files, form)
+		}
+		if err != nil {
+			// Clean up any files that were already saved
+			for _, fileList := range files {
+				for _, file := range fileList {
+					_ = h.storage.Delete(context.WithoutCancel(ctx), file.Key)
+				}
+			}
+			var httpErr *HTTPError
+			var ruleErr *uploadRuleError
+			if !errors.As(err, &httpErr) && !errors.As(err, &ruleErr) {
+				err = uploadReadError(err)
+			}
+			return nil, nil, err
+		}
 	}
+}
 
-	result := make(map[string][]*UploadedFile)
-
-	for fieldName, fileHeaders := range r.MultipartForm.File {
-		var files []*UploadedFile
+// processPart stores a file part in files, or adds a value part to form
+func (h *UploadHandler) processPart(ctx context.Context, part *multipart.Part, files map[string][]*UploadedFile, form map[string][]string) error {
+	defer func() {
+		_ = part.Close()
+	}()
 
-		for _, fileHeader := range fileHeaders {
-			uploadedFile, err := h.saveFile(fileHeader)
-			if err != nil {
-				// Clean up any files that were already saved
-				for _, fileList := range result {
-					for _, file := range fileList {
-						_ = os.Remove(file.SavedPath)
-					}
-				}
-				return nil, err
-			}
+	fieldName := part.FormName()
+	if fieldName == "" {
+		return nil
+	}
 
-			files = append(files, uploadedFile)
+	if part.FileName() == "" {
+		value, err := io.ReadAll(part)
+		if err != nil {
+			return err
 		}
+		form[fieldName] = append(form[fieldName], string(value))
+		return nil
+	}
 
-		if len(files) > 0 {
-			result[fieldName] = files
-		}
+	rule := h.rules[fieldName]
+	if err := rule.checkFile(fieldName, part.FileName(), len(files[fieldName])); err != nil {
+		return err
 	}
 
-	return result, nil
+	uploadedFile, err := h.saveFile(ctx, part)
+	if err != nil {
+		return err
+	}
+	files[fieldName] = append(files[fieldName], uploadedFile)
+	return nil
 }
 
 // GetFormValue retrieves the first value for a form field key.
@@ -207,43 +238,93 @@ func GetFormValues(form map[string][]string, key string) []string {
 	return nil
 }
 
-// saveFile saves an uploaded file to disk and returns metadata about the saved file
-func (h *UploadHandler) saveFile(fileHeader *multipart.FileHeader) (*UploadedFile, error) {
-	file, err := fileHeader.Open()
-	if err != nil {
-		return nil, errors.New("failed to open uploaded file")
+// saveFile streams an uploaded file to the storage and returns metadata about the saved file. The rule of
+// its field is checked before the file is stored, or while it is stored for the size limits.
+func (h *UploadHandler) saveFile(ctx context.Context, part *multipart.Part) (*UploadedFile, error) {
+	fieldName := part.FormName()
+	rule := h.rules[fieldName]
+
+	// Create a unique key to prevent overwriting
+	filename := filepath.Base(part.FileName())
+	key := fmt.Sprintf("%d_%s", time.Now().UnixNano(), filename)
+	contentType := part.Header.Get("Content-Type")
+	uploadedFile := &UploadedFile{
+		Filename:    filename,
+		ContentType: contentType,
+		Key:         key,
 	}
-	defer func() {
-		_ = file.Close()
-	}()
 
-	// Create a unique filename to prevent overwriting
-	filename := filepath.Base(fileHeader.Filename)
-	timestamp := time.Now().UnixNano()
-	uniqueFilename := fmt.Sprintf("%d_%s", timestamp, filename)
-	filePath := filepath.Join(h.uploadDir, uniqueFilename)
+	// Inspect the first bytes of the content without consuming them
+	buffered := bufio.NewReaderSize(part, rule.headerLen())
+	if err := rule.inspect(fieldName, buffered, uploadedFile); err != nil {
+		return nil, err
+	}
 
-	// Create the destination file
-	dst, err := os.Create(filepath.Clean(filePath)) // #nosec G304 - filePath is constructed from uploadDir and sanitized filename
-	if err != nil {
-		return nil, errors.New("failed to create destination file")
+	// Hash and count the content while it is stored
+	content := &uploadReader{r: buffered, hash: sha256.New(), field: fieldName, minSize: rule.MinSize, maxSize: rule.MaxSize}
+	if err := h.storage.Save(ctx, key, content, contentType); err != nil {
+		if content.err != nil {
+			return nil, content.err
+		}
+		return nil, internalError(fmt.Errorf("error saving upload: %w", err))
 	}
-	defer func() {
-		_ = dst.Close()
-	}()
 
-	// Copy the uploaded file to the destination file
-	if _, err := io.Copy(dst, file); err != nil {
-		return nil, errors.New("failed to save uploaded file")
+	uploadedFile.Size = content.size
+	uploadedFile.SHA256 = hex.EncodeToString(content.hash.Sum(nil))
+	if local, ok := h.storage.(*LocalStorage); ok {
+		uploadedFile.SavedPath = local.Path(key)
 	}
+	return uploadedFile, nil
+}
 
-	// Get content type
-	contentType := fileHeader.Header.Get("Content-Type")
+// uploadReader hashes and counts the content of an uploaded file, and keeps the error reading the request
+// apart from the errors of the storage. A file outside of the size limits of its rule fails with an
+// uploadRuleError, so the storage discards it.
+type uploadReader struct {
+	r       io.Reader
+	hash    hash.Hash
+	size    int64
+	field   string
+	minSize int64
+	maxSize int64
+	err     error
+}
 
-	return &UploadedFile{
-		Filename:    filename,
-		Size:        fileHeader.Size,
-		ContentType: contentType,
-		SavedPath:   filePath,
-	}, nil
+func (u *uploadReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	u.hash.Write(p[:n])
+	u.size += int64(n)
+	switch {
+	case u.maxSize > 0 && u.size > u.maxSize:
+		err = newUploadRuleError(http.StatusRequestEntityTooLarge, u.field, "max_size", u.maxSize)
+	case errors.Is(err, io.EOF) && u.size < u.minSize:
+		err = newUploadRuleError(http.StatusBadRequest, u.field, "min_size", u.minSize)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		u.err = err
+	}
+	return n, err
+}
+
+// uploadReadError returns the response of an error reading the request.
+func uploadReadError(err error) *HTTPError {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &HTTPError{
+			StatusCode: http.StatusRequestEntityTooLarge,
+			Title:      "request too large",
+			Detail:     fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit),
+			Err:        err,
+		}
+	}
+	return invalidMultipartForm(err)
+}
+
+func invalidMultipartForm(err error) *HTTPError {
+	return &HTTPError{
+		StatusCode: http.StatusBadRequest,
+		Title:      "invalid multipart form",
+		Detail:     "request too large or invalid multipart form",
+		Err:        err,
+	}
 }
@@ -0,0 +1,315 @@
+package httpmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	// s3MinPartSize is the smallest part S3 accepts in a multipart upload, except for the last part.
+	s3MinPartSize = 5 << 20
+	// defaultS3URLExpiry is the validity of the presigned URLs returned by S3Storage.URL.
+	defaultS3URLExpiry = 15 * time.Minute
+	// s3UnsignedPayload is the payload hash of the presigned URLs.
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// S3Config configures an S3Storage.
+type S3Config struct {
+	// Endpoint is the URL of the S3-compatible API, e.g. https://s3.ap-southeast-1.amazonaws.com or
+	// http://localhost:9000 for MinIO.
+	Endpoint string
+	// Region is the region of the bucket, used for signing. Defaults to us-east-1.
+	Region string
+	// Bucket is the bucket the files are stored in.
+	Bucket string
+	// AccessKeyID, SecretAccessKey and the optional SessionToken are the credentials signing the requests.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// PathStyle addresses the bucket in the path (http://host/bucket/key) instead of the host
+	// (http://bucket.host/key), as MinIO and most S3-compatible APIs require.
+	PathStyle bool
+	// KeyPrefix is prepended to the keys of the objects, e.g. "uploads/".
+	KeyPrefix string
+	// PublicURL is the base URL of publicly readable objects, e.g. a CDN. When empty, URL returns presigned URLs.
+	PublicURL string
+	// URLExpiry is the validity of the presigned URLs. Defaults to 15 minutes.
+	URLExpiry time.Duration
+	// PartSize is the size of the parts of a multipart upload, and so the memory used by each upload.
+	// Files smaller than a part are stored with a single request. Defaults to and cannot be lower than 5 MiB.
+	PartSize int64
+	// HTTPClient sends the requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// S3Error is an error response of the S3 API.
+type S3Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *S3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("s3: status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("s3: %s: %s", e.Code, e.Message)
+}
+
+// S3Storage stores the uploaded files in a bucket of an S3-compatible API, without an SDK client. The files
+// are streamed to the bucket one part at a time with a multipart upload.
+type S3Storage struct {
+	config   S3Config
+	endpoint *url.URL
+	signer   *v4.Signer
+}
+
+// NewS3Storage creates a storage for the bucket of config.
+func NewS3Storage(config S3Config) (*S3Storage, error) {
+	if config.Bucket == "" {
+		return nil, errors.New("s3: bucket is required")
+	}
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", config.Endpoint)
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	if config.URLExpiry <= 0 {
+		config.URLExpiry = defaultS3URLExpiry
+	}
+	if config.PartSize < s3MinPartSize {
+		config.PartSize = s3MinPartSize
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+
+	return &S3Storage{
+		config:   config,
+		endpoint: endpoint,
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			// S3 object keys are escaped once, by objectURL
+			o.DisableURIPathEscaping = true
+		}),
+	}, nil
+}
+
+// Save uploads the content to the object of key. Content fitting in one part is uploaded with a single PUT,
+// larger content with a multipart upload that is aborted when it fails.
+func (s *S3Storage) Save(ctx context.Context, key string, r io.Reader, contentType string) error {
+	part := make([]byte, s.config.PartSize)
+	n, err := io.ReadFull(r, part)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		header := http.Header{}
+		setS3ContentType(header, contentType)
+		_, err = s.do(ctx, http.MethodPut, key, nil, header, part[:n])
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	uploadID, err := s.createMultipartUpload(ctx, key, contentType)
+	if err != nil {
+		return err
+	}
+	if err := s.uploadParts(ctx, key, uploadID, r, part); err != nil {
+		query := url.Values{"uploadId": {uploadID}}
+		_, _ = s.do(context.WithoutCancel(ctx), http.MethodDelete, key, query, nil, nil)
+		return err
+	}
+	return nil
+}
+
+// Delete removes the object of key.
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	var s3Err *S3Error
+	if errors.As(err, &s3Err) && s3Err.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// URL returns the URL of the object under PublicURL, or a presigned GET URL valid for URLExpiry.
+func (s *S3Storage) URL(ctx context.Context, key string) (string, error) {
+	if s.config.PublicURL != "" {
+		return strings.TrimSuffix(s.config.PublicURL, "/") + "/" + escapeS3Key(s.config.KeyPrefix+key), nil
+	}
+
+	query := url.Values{"X-Amz-Expires": {strconv.Itoa(int(s.config.URLExpiry.Seconds()))}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key, query), nil)
+	if err != nil {
+		return "", err
+	}
+	signed, _, err := s.signer.PresignHTTP(ctx, s.credentials(), req, s3UnsignedPayload, "s3", s.config.Region, time.Now())
+	return signed, err
+}
+
+func (s *S3Storage) createMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	header := http.Header{}
+	setS3ContentType(header, contentType)
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(resp.body, &result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("s3: invalid create multipart upload response: %s", resp.body)
+	}
+	return result.UploadID, nil
+}
+
+// uploadParts uploads first, a full part, then the rest of r and completes the multipart upload.
+func (s *S3Storage) uploadParts(ctx context.Context, key, uploadID string, r io.Reader, first []byte) error {
+	type completedPart struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	}
+	var parts []completedPart
+
+	part := first
+	for n := len(first); n > 0; {
+		number := len(parts) + 1
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+		resp, err := s.do(ctx, http.MethodPut, key, query, nil, part[:n])
+		if err != nil {
+			return err
+		}
+		parts = append(parts, completedPart{PartNumber: number, ETag: resp.header.Get("ETag")})
+
+		n, err = io.ReadFull(r, part)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, nil, body)
+	if err != nil {
+		return err
+	}
+	// Completing a multipart upload can fail after the 200 status line was sent
+	if bytes.Contains(resp.body, []byte("<Error>")) {
+		return parseS3Error(http.StatusInternalServerError, resp.body)
+	}
+	return nil
+}
+
+type s3Response struct {
+	header http.Header
+	body   []byte
+}
+
+// do sends a signed request for the object of key and returns the response, or an S3Error for a non-2xx status.
+func (s *S3Storage) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*s3Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key, query), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err := s.signer.SignHTTP(ctx, s.credentials(), req, payloadHash, "s3", s.config.Region, time.Now()); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, parseS3Error(resp.StatusCode, respBody)
+	}
+	return &s3Response{header: resp.Header, body: respBody}, nil
+}
+
+// objectURL returns the URL of the object of key, in the path or the host of the endpoint.
+func (s *S3Storage) objectURL(key string, query url.Values) string {
+	host := s.endpoint.Host
+	objectPath := escapeS3Key(s.config.KeyPrefix + key)
+	if s.config.PathStyle {
+		objectPath = escapeS3Key(s.config.Bucket) + "/" + objectPath
+	} else {
+		host = s.config.Bucket + "." + host
+	}
+
+	u := s.endpoint.Scheme + "://" + host + strings.TrimSuffix(s.endpoint.EscapedPath(), "/") + "/" + objectPath
+	if len(query) > 0 {
+		u += "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+	}
+	return u
+}
+
+func (s *S3Storage) credentials() aws.Credentials {
+	return aws.Credentials{
+		AccessKeyID:     s.config.AccessKeyID,
+		SecretAccessKey: s.config.SecretAccessKey,
+		SessionToken:    s.config.SessionToken,
+	}
+}
+
+func setS3ContentType(header http.Header, contentType string) {
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+}
+
+func parseS3Error(statusCode int, body []byte) error {
+	s3Err := &S3Error{}
+	_ = xml.Unmarshal(body, s3Err)
+	s3Err.StatusCode = statusCode
+	return s3Err
+}
+
+// escapeS3Key escapes every byte of key but the unreserved characters and the slashes, as S3 signs the path.
+func escapeS3Key(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
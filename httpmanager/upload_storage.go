@@ -0,0 +1,143 @@
+package httpmanager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// UploadStorage stores the files received by an UploadHandler. The content is streamed from the request to
+// Save, so a storage must not need the size of the file up front.
+type UploadStorage interface {
+	// Save stores the content read from r under key. A failed Save leaves nothing stored under key.
+	Save(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Delete removes the file stored under key. Deleting a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the URL the file stored under key can be downloaded from.
+	URL(ctx context.Context, key string) (string, error)
+}
+
+// LocalStorage stores the uploaded files in a directory, the behaviour of NewUploadHandler.
+type LocalStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStorage creates a storage writing to dir, which is created if it does not exist.
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{dir: dir}, nil
+}
+
+// WithBaseURL sets the URL the directory is served from, for the URL method.
+func (s *LocalStorage) WithBaseURL(baseURL string) *LocalStorage {
+	s.baseURL = baseURL
+	return s
+}
+
+// Path returns the path of the file stored under key.
+func (s *LocalStorage) Path(key string) string {
+	return filepath.Join(s.dir, filepath.Base(key))
+}
+
+// Save writes the content to the file of key. A partially written file is removed.
+func (s *LocalStorage) Save(_ context.Context, key string, r io.Reader, _ string) error {
+	filePath := s.Path(key)
+	dst, err := os.Create(filepath.Clean(filePath)) // #nosec G304 - filePath is constructed from dir and the base name of key
+	if err != nil {
+		return fmt.Errorf("create destination file: %w", err)
+	}
+
+	_, err = io.Copy(dst, r)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(filePath)
+		return fmt.Errorf("write destination file: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the file of key.
+func (s *LocalStorage) Delete(_ context.Context, key string) error {
+	if err := os.Remove(s.Path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// URL returns the URL of the file under the base URL, or its path when no base URL is set.
+func (s *LocalStorage) URL(_ context.Context, key string) (string, error) {
+	if s.baseURL == "" {
+		return s.Path(key), nil
+	}
+	return url.JoinPath(s.baseURL, path.Base(key))
+}
+
+// MemoryStorage keeps the uploaded files in memory, for tests and development.
+type MemoryStorage struct {
+	mu    sync.RWMutex
+	files map[string]MemoryFile
+}
+
+// MemoryFile is a file kept by a MemoryStorage.
+type MemoryFile struct {
+	Content     []byte
+	ContentType string
+}
+
+// NewMemoryStorage creates an empty in-memory storage.
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{files: map[string]MemoryFile{}}
+}
+
+// Save reads the whole content and keeps it under key.
+func (s *MemoryStorage) Save(_ context.Context, key string, r io.Reader, contentType string) error {
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[key] = MemoryFile{Content: buf.Bytes(), ContentType: contentType}
+	return nil
+}
+
+// Delete removes the file of key.
+func (s *MemoryStorage) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, key)
+	return nil
+}
+
+// URL returns a memory:// URL naming the key.
+func (s *MemoryStorage) URL(_ context.Context, key string) (string, error) {
+	return "memory://" + key, nil
+}
+
+// Get returns the file stored under key.
+func (s *MemoryStorage) Get(key string) (MemoryFile, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	file, ok := s.files[key]
+	return file, ok
+}
+
+// Len returns the number of stored files.
+func (s *MemoryStorage) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.files)
+}
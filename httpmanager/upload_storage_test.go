@@ -0,0 +1,413 @@
+package httpmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testS3AccessKey = "minio"
+	testS3SecretKey = "minio-secret"
+	testS3Region    = "ap-southeast-1"
+)
+
+// fakeS3 is a stand-in for a MinIO server, storing the objects of a path-style bucket and checking the
+// signature and the payload hash of every request.
+type fakeS3 struct {
+	t        *testing.T
+	mu       sync.Mutex
+	objects  map[string][]byte
+	types    map[string]string
+	uploads  map[string]map[int][]byte
+	requests []string
+	failPart int
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	f := &fakeS3{t: t, objects: map[string][]byte{}, types: map[string]string{}, uploads: map[string]map[int][]byte{}}
+	ts := httptest.NewServer(f)
+	t.Cleanup(ts.Close)
+	return f, ts
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	f.requests = append(f.requests, strings.TrimSpace(r.Method+" "+strings.Join(keys, ",")))
+
+	if !f.verify(r, body) {
+		writeS3Error(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+
+	object := r.URL.EscapedPath()
+	uploadID := query.Get("uploadId")
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		uploadID = strconv.Itoa(len(f.uploads) + 1)
+		f.uploads[uploadID] = map[int][]byte{}
+		f.types[object] = r.Header.Get("Content-Type")
+		_, _ = fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", uploadID)
+	case r.Method == http.MethodPut && uploadID != "":
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		if number == f.failPart {
+			writeS3Error(w, http.StatusInternalServerError, "InternalError")
+			return
+		}
+		f.uploads[uploadID][number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+	case r.Method == http.MethodPost && uploadID != "":
+		var complete struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		require.NoError(f.t, xml.Unmarshal(body, &complete))
+		var content []byte
+		for i, part := range complete.Parts {
+			assert.Equal(f.t, fmt.Sprintf(`"etag-%d"`, i+1), part.ETag)
+			content = append(content, f.uploads[uploadID][part.PartNumber]...)
+		}
+		f.objects[object] = content
+		delete(f.uploads, uploadID)
+		_, _ = io.WriteString(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodDelete && uploadID != "":
+		delete(f.uploads, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.objects[object] = body
+		f.types[object] = r.Header.Get("Content-Type")
+	case r.Method == http.MethodDelete:
+		delete(f.objects, object)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// verify signs a copy of the request with the signed headers and the date of r and compares the signatures.
+func (f *fakeS3) verify(r *http.Request, body []byte) bool {
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	if r.Header.Get("X-Amz-Content-Sha256") != payloadHash {
+		return false
+	}
+
+	auth := r.Header.Get("Authorization")
+	_, signedHeaders, ok := strings.Cut(auth, "SignedHeaders=")
+	if !ok {
+		return false
+	}
+	signedHeaders, _, _ = strings.Cut(signedHeaders, ",")
+	date, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+
+	req, err := http.NewRequest(r.Method, "http://"+r.Host+r.URL.RequestURI(), nil)
+	require.NoError(f.t, err)
+	req.ContentLength = r.ContentLength
+	for _, name := range strings.Split(signedHeaders, ";") {
+		if name != "host" && name != "content-length" {
+			req.Header[http.CanonicalHeaderKey(name)] = r.Header.Values(name)
+		}
+	}
+	signer := v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true })
+	credentials := aws.Credentials{AccessKeyID: testS3AccessKey, SecretAccessKey: testS3SecretKey}
+	require.NoError(f.t, signer.SignHTTP(context.Background(), credentials, req, payloadHash, "s3", testS3Region, date))
+	return req.Header.Get("Authorization") == auth
+}
+
+func (f *fakeS3) object(path string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	content, ok := f.objects[path]
+	return content, ok
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func newTestS3Storage(t *testing.T, endpoint string, modify func(*S3Config)) *S3Storage {
+	config := S3Config{
+		Endpoint:        endpoint,
+		Region:          testS3Region,
+		Bucket:          "uploads",
+		AccessKeyID:     testS3AccessKey,
+		SecretAccessKey: testS3SecretKey,
+		PathStyle:       true,
+	}
+	if modify != nil {
+		modify(&config)
+	}
+	storage, err := NewS3Storage(config)
+	require.NoError(t, err)
+	return storage
+}
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	storage, err := NewLocalStorage(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.Save(ctx, "1_report.pdf", strings.NewReader("report"), "application/pdf"))
+	content, err := os.ReadFile(storage.Path("1_report.pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, "report", string(content))
+	assert.Equal(t, storage.Path("../1_report.pdf"), storage.Path("1_report.pdf"), "keys cannot leave the directory")
+
+	fileURL, err := storage.URL(ctx, "1_report.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, storage.Path("1_report.pdf"), fileURL)
+	fileURL, err = storage.WithBaseURL("https://cdn.example.com/files/").URL(ctx, "1_report.pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "https://cdn.example.com/files/1_report.pdf", fileURL)
+
+	require.NoError(t, storage.Delete(ctx, "1_report.pdf"))
+	assert.NoFileExists(t, storage.Path("1_report.pdf"))
+	assert.NoError(t, storage.Delete(ctx, "1_report.pdf"), "deleting a missing file is not an error")
+
+	t.Run("removes a partially written file", func(t *testing.T) {
+		failing := io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errors.New("connection reset")))
+		assert.Error(t, storage.Save(ctx, "2_partial.txt", failing, "text/plain"))
+		assert.NoFileExists(t, storage.Path("2_partial.txt"))
+	})
+}
+
+func TestMemoryStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryStorage()
+
+	require.NoError(t, storage.Save(ctx, "avatar.png", strings.NewReader("png"), "image/png"))
+	file, ok := storage.Get("avatar.png")
+	require.True(t, ok)
+	assert.Equal(t, MemoryFile{Content: []byte("png"), ContentType: "image/png"}, file)
+
+	fileURL, err := storage.URL(ctx, "avatar.png")
+	require.NoError(t, err)
+	assert.Equal(t, "memory://avatar.png", fileURL)
+
+	require.NoError(t, storage.Delete(ctx, "avatar.png"))
+	assert.Equal(t, 0, storage.Len())
+}
+
+func TestS3Storage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stores a small file with a single request", func(t *testing.T) {
+		fake, ts := newFakeS3(t)
+		storage := newTestS3Storage(t, ts.URL, func(c *S3Config) { c.KeyPrefix = "avatars/" })
+
+		require.NoError(t, storage.Save(ctx, "1_my photo.png", strings.NewReader("png"), "image/png"))
+		content, ok := fake.object("/uploads/avatars/1_my%20photo.png")
+		require.True(t, ok)
+		assert.Equal(t, "png", string(content))
+		assert.Equal(t, "image/png", fake.types["/uploads/avatars/1_my%20photo.png"])
+		assert.Equal(t, []string{"PUT"}, fake.requests)
+
+		require.NoError(t, storage.Delete(ctx, "1_my photo.png"))
+		_, ok = fake.object("/uploads/avatars/1_my%20photo.png")
+		assert.False(t, ok)
+	})
+
+	t.Run("streams a large file in parts", func(t *testing.T) {
+		fake, ts := newFakeS3(t)
+		storage := newTestS3Storage(t, ts.URL, nil)
+		content := bytes.Repeat([]byte("0123456789abcdef"), (11<<20)/16)
+
+		require.NoError(t, storage.Save(ctx, "video.mp4", bytes.NewReader(content), "video/mp4"))
+		stored, ok := fake.object("/uploads/video.mp4")
+		require.True(t, ok)
+		assert.Equal(t, content, stored)
+		assert.Equal(t, []string{"POST uploads", "PUT partNumber,uploadId", "PUT partNumber,uploadId", "PUT partNumber,uploadId", "POST uploadId"}, fake.requests)
+	})
+
+	t.Run("aborts a failed multipart upload", func(t *testing.T) {
+		fake, ts := newFakeS3(t)
+		fake.failPart = 2
+		storage := newTestS3Storage(t, ts.URL, nil)
+
+		err := storage.Save(ctx, "video.mp4", bytes.NewReader(make([]byte, 11<<20)), "video/mp4")
+		var s3Err *S3Error
+		require.ErrorAs(t, err, &s3Err)
+		assert.Equal(t, "InternalError", s3Err.Code)
+		assert.Equal(t, "DELETE uploadId", fake.requests[len(fake.requests)-1])
+		assert.Empty(t, fake.uploads)
+		_, ok := fake.object("/uploads/video.mp4")
+		assert.False(t, ok)
+	})
+
+	t.Run("returns the error of the API", func(t *testing.T) {
+		_, ts := newFakeS3(t)
+		storage := newTestS3Storage(t, ts.URL, func(c *S3Config) { c.SecretAccessKey = "wrong" })
+
+		err := storage.Save(ctx, "avatar.png", strings.NewReader("png"), "image/png")
+		var s3Err *S3Error
+		require.ErrorAs(t, err, &s3Err)
+		assert.Equal(t, http.StatusForbidden, s3Err.StatusCode)
+		assert.Equal(t, "SignatureDoesNotMatch", s3Err.Code)
+	})
+
+	t.Run("URL", func(t *testing.T) {
+		storage := newTestS3Storage(t, "https://s3.example.com", func(c *S3Config) { c.PathStyle = false })
+		fileURL, err := storage.URL(ctx, "avatar.png")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(fileURL, "https://uploads.s3.example.com/avatar.png?"), fileURL)
+		assert.Contains(t, fileURL, "X-Amz-Expires=900")
+		assert.Contains(t, fileURL, "X-Amz-Signature=")
+
+		storage = newTestS3Storage(t, "https://s3.example.com", func(c *S3Config) { c.PublicURL = "https://cdn.example.com/" })
+		fileURL, err = storage.URL(ctx, "my avatar.png")
+		require.NoError(t, err)
+		assert.Equal(t, "https://cdn.example.com/my%20avatar.png", fileURL)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := NewS3Storage(S3Config{Endpoint: "https://s3.example.com"})
+		assert.Error(t, err)
+		_, err = NewS3Storage(S3Config{Endpoint: "s3.example.com", Bucket: "uploads"})
+		assert.Error(t, err)
+	})
+}
+
+// failingStorage fails to save the files named fail.txt.
+type failingStorage struct {
+	*MemoryStorage
+}
+
+func (s failingStorage) Save(ctx context.Context, key string, r io.Reader, contentType string) error {
+	if strings.HasSuffix(key, "_fail.txt") {
+		return errors.New("disk full")
+	}
+	return s.MemoryStorage.Save(ctx, key, r, contentType)
+}
+
+func newUploadRequest(t *testing.T, files map[string]string, values map[string]string) *http.Request {
+	var b bytes.Buffer
+	writer := multipart.NewWriter(&b)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fileWriter, err := writer.CreateFormFile("file", name)
+		require.NoError(t, err)
+		_, err = io.WriteString(fileWriter, files[name])
+		require.NoError(t, err)
+	}
+	for key, value := range values {
+		require.NoError(t, writer.WriteField(key, value))
+	}
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &b)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestUploadHandler_Storage(t *testing.T) {
+	t.Run("streams the files to the storage", func(t *testing.T) {
+		storage := NewMemoryStorage()
+		var uploaded []*UploadedFile
+		handler := NewUploadHandlerWithStorage(http.MethodPost, storage, func(ctx context.Context, files map[string][]*UploadedFile, form map[string][]string) (interface{}, error) {
+			uploaded = files["file"]
+			return nil, nil
+		})
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, newUploadRequest(t, map[string]string{"a.txt": "first", "b.txt": "second"}, nil))
+
+		require.Equal(t, http.StatusNoContent, rr.Code)
+		require.Len(t, uploaded, 2)
+		sum := sha256.Sum256([]byte("first"))
+		assert.Equal(t, "a.txt", uploaded[0].Filename)
+		assert.Equal(t, int64(5), uploaded[0].Size)
+		assert.Equal(t, hex.EncodeToString(sum[:]), uploaded[0].SHA256)
+		assert.True(t, strings.HasSuffix(uploaded[0].Key, "_a.txt"))
+		assert.Empty(t, uploaded[0].SavedPath)
+		file, ok := storage.Get(uploaded[1].Key)
+		require.True(t, ok)
+		assert.Equal(t, "second", string(file.Content))
+	})
+
+	t.Run("deletes the stored files when a file fails", func(t *testing.T) {
+		storage := failingStorage{NewMemoryStorage()}
+		handler := NewUploadHandlerWithStorage(http.MethodPost, storage, func(ctx context.Context, files map[string][]*UploadedFile, form map[string][]string) (interface{}, error) {
+			t.Error("the handler func should not be called")
+			return nil, nil
+		})
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, newUploadRequest(t, map[string]string{"a.txt": "first", "fail.txt": "second"}, nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Equal(t, 0, storage.Len())
+	})
+
+	t.Run("rejects a request over the max size without storing it", func(t *testing.T) {
+		storage := NewMemoryStorage()
+		handler := NewUploadHandlerWithStorage(http.MethodPost, storage, func(ctx context.Context, files map[string][]*UploadedFile, form map[string][]string) (interface{}, error) {
+			return nil, nil
+		}).WithMaxFileSize(1 << 10)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, newUploadRequest(t, map[string]string{"a.txt": "first", "b.txt": strings.Repeat("b", 2<<10)}, nil))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, 0, storage.Len())
+	})
+
+	t.Run("S3", func(t *testing.T) {
+		fake, ts := newFakeS3(t)
+		storage := newTestS3Storage(t, ts.URL, nil)
+		var key string
+		handler := NewUploadHandlerWithStorage(http.MethodPost, storage, func(ctx context.Context, files map[string][]*UploadedFile, form map[string][]string) (interface{}, error) {
+			key = files["file"][0].Key
+			return map[string]string{"title": GetFormValue(form, "title")}, nil
+		})
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, newUploadRequest(t, map[string]string{"report.csv": "a,b\n1,2\n"}, map[string]string{"title": "Q3"}))
+
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.JSONEq(t, `{"title":"Q3"}`, rr.Body.String())
+		content, ok := fake.object("/uploads/" + key)
+		require.True(t, ok)
+		assert.Equal(t, "a,b\n1,2\n", string(content))
+	})
+}
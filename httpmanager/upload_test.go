@@ -33,8 +33,8 @@ func TestNewUploadHandler(t *testing.T) {
 		if handler.method != "POST" {
 			t.Errorf("Expected method to be POST, got %s", handler.method)
 		}
-		if handler.uploadDir != tempDir {
-			t.Errorf("Expected uploadDir to be %s, got %s", tempDir, handler.uploadDir)
+		if storage, ok := handler.storage.(*LocalStorage); !ok || storage.dir != tempDir {
+			t.Errorf("Expected a local storage in %s, got %v", tempDir, handler.storage)
 		}
 		if handler.maxFileSize != 10<<20 {
 			t.Errorf("Expected maxFileSize to be %d, got %d", 10<<20, handler.maxFileSize)
@@ -231,6 +231,9 @@ func TestUploadHandler_ServeHTTP_MethodNotAllowed(t *testing.T) {
 	if status := rr.Code; status != http.StatusMethodNotAllowed {
 		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
 	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected the error encoder content type, got %q", contentType)
+	}
 }
 
 func TestUploadHandler_ServeHTTP_InvalidMultipartForm(t *testing.T) {
@@ -375,17 +378,19 @@ func TestUploadHandler_processUploadedFiles(t *testing.T) {
 		return nil, nil
 	})
 
-	t.Run("nil_multipart_form", func(t *testing.T) {
-		req := httptest.NewRequest("POST", "/upload", nil)
+	t.Run("empty_multipart_form", func(t *testing.T) {
+		var b bytes.Buffer
+		writer := multipart.NewWriter(&b)
+		writer.Close()
 
-		files, err := handler.processUploadedFiles(req)
+		files, form, err := handler.processUploadedFiles(context.Background(), multipart.NewReader(&b, writer.Boundary()))
 
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
 		}
 
-		if files != nil {
-			t.Errorf("Expected nil files, got %v", files)
+		if len(files) != 0 || len(form) != 0 {
+			t.Errorf("Expected no files and values, got %v and %v", files, form)
 		}
 	})
 
@@ -404,19 +409,13 @@ func TestUploadHandler_processUploadedFiles(t *testing.T) {
 			t.Fatalf("Failed to write to form file: %v", err)
 		}
 
-		writer.Close()
-
-		// Create a test request
-		req := httptest.NewRequest("POST", "/upload", &b)
-		req.Header.Set("Content-Type", writer.FormDataContentType())
-
-		// Parse the multipart form
-		err = req.ParseMultipartForm(10 << 20)
-		if err != nil {
-			t.Fatalf("Failed to parse multipart form: %v", err)
+		if err := writer.WriteField("title", "test"); err != nil {
+			t.Fatalf("Failed to write form field: %v", err)
 		}
 
-		files, err := handler.processUploadedFiles(req)
+		writer.Close()
+
+		files, form, err := handler.processUploadedFiles(context.Background(), multipart.NewReader(&b, writer.Boundary()))
 
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
@@ -433,6 +432,10 @@ func TestUploadHandler_processUploadedFiles(t *testing.T) {
 		if fileList, ok := files["file"]; !ok || len(fileList) != 1 {
 			t.Errorf("Expected 1 file in 'file' field, got %v", files)
 		}
+
+		if GetFormValue(form, "title") != "test" {
+			t.Errorf("Expected form value 'test', got %v", form)
+		}
 	})
 }
 
@@ -459,21 +462,14 @@ func TestUploadHandler_saveFile(t *testing.T) {
 
 	writer.Close()
 
-	// Create a test request
-	req := httptest.NewRequest("POST", "/upload", &b)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	// Parse the multipart form
-	err = req.ParseMultipartForm(10 << 20)
+	// Get the file part
+	part, err := multipart.NewReader(&b, writer.Boundary()).NextPart()
 	if err != nil {
-		t.Fatalf("Failed to parse multipart form: %v", err)
+		t.Fatalf("Failed to read file part: %v", err)
 	}
 
-	// Get the file header
-	fileHeader := req.MultipartForm.File["file"][0]
-
 	// Test saving the file
-	uploadedFile, err := handler.saveFile(fileHeader)
+	uploadedFile, err := handler.saveFile(context.Background(), part)
 
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
@@ -539,21 +535,14 @@ func TestUploadHandler_saveFile_Errors(t *testing.T) {
 
 		writer.Close()
 
-		// Create a test request
-		req := httptest.NewRequest("POST", "/upload", &b)
-		req.Header.Set("Content-Type", writer.FormDataContentType())
-
-		// Parse the multipart form
-		err = req.ParseMultipartForm(10 << 20)
+		// Get the file part
+		part, err := multipart.NewReader(&b, writer.Boundary()).NextPart()
 		if err != nil {
-			t.Fatalf("Failed to parse multipart form: %v", err)
+			t.Fatalf("Failed to read file part: %v", err)
 		}
 
-		// Get the file header
-		fileHeader := req.MultipartForm.File["file"][0]
-
 		// Test saving the file - this should fail because the directory is read-only
-		_, err = readOnlyHandler.saveFile(fileHeader)
+		_, err = readOnlyHandler.saveFile(context.Background(), part)
 
 		if err == nil {
 			t.Error("Expected error when destination file cannot be created, got nil")
@@ -0,0 +1,237 @@
+package httpmanager
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // register the GIF decoder for the image dimension rules
+	_ "image/jpeg" // register the JPEG decoder for the image dimension rules
+	_ "image/png"  // register the PNG decoder for the image dimension rules
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+const (
+	// uploadSniffLen is the number of bytes of a file used to detect its MIME type.
+	uploadSniffLen = 3072
+	// uploadImageHeaderLen is the number of bytes of a file read to decode its image dimensions, enough for
+	// the metadata segments before the frame header of most JPEG files.
+	uploadImageHeaderLen = 64 << 10
+)
+
+// UploadRule declares the constraints of the files of a form field of an UploadHandler. Zero values disable
+// a constraint. The files are checked while they are streamed, so a rejected file is never stored.
+type UploadRule struct {
+	// Required rejects requests without a file in the field.
+	Required bool
+	// MaxFiles is the maximum number of files of the field.
+	MaxFiles int
+	// MinSize and MaxSize are the size limits of each file, in bytes.
+	MinSize int64
+	MaxSize int64
+	// AllowedTypes are the MIME types the files may have, detected from their content rather than the
+	// Content-Type sent by the client, e.g. "application/pdf" or "image/*".
+	AllowedTypes []string
+	// AllowedExtensions are the extensions the file names may have, e.g. ".jpg" or "png". Case-insensitive.
+	AllowedExtensions []string
+	// MinWidth, MinHeight, MaxWidth and MaxHeight are the image dimension limits, in pixels. A file that is not
+	// a PNG, JPEG or GIF image is rejected when one of them is set.
+	MinWidth  int
+	MinHeight int
+	MaxWidth  int
+	MaxHeight int
+}
+
+// WithFieldRule sets the rule of the files of a form field
+func (h *UploadHandler) WithFieldRule(field string, rule UploadRule) *UploadHandler {
+	if h.rules == nil {
+		h.rules = make(map[string]UploadRule)
+	}
+	h.rules[field] = rule
+	return h
+}
+
+// uploadRuleError is the error of an upload breaking the rules of its fields.
+type uploadRuleError struct {
+	statusCode int
+	fields     []FieldError
+}
+
+func (e *uploadRuleError) Error() string {
+	return (&ValidationError{Fields: e.fields}).Error()
+}
+
+// httpError returns the response of the error, a 413 for the size limits and a 400 otherwise, with the failed
+// rules in data. The messages come from the validation messages of the server when they are set.
+func (e *uploadRuleError) httpError(r *http.Request) *HTTPError {
+	messageFunc := validatorFromContext(r.Context()).messageFunc
+	fields := make([]FieldError, len(e.fields))
+	for i, field := range e.fields {
+		if messageFunc != nil {
+			field.Message = messageFunc(r, field)
+		}
+		if field.Message == "" {
+			field.Message = defaultUploadMessage(field)
+		}
+		fields[i] = field
+	}
+
+	validationErr := &ValidationError{Fields: fields}
+	title := "invalid upload"
+	if e.statusCode == http.StatusRequestEntityTooLarge {
+		title = "upload too large"
+	}
+	return &HTTPError{
+		StatusCode: e.statusCode,
+		Title:      title,
+		Detail:     validationErr.Error(),
+		Data:       fields,
+		Err:        validationErr,
+	}
+}
+
+func newUploadRuleError(statusCode int, field, rule string, param any) *uploadRuleError {
+	return &uploadRuleError{
+		statusCode: statusCode,
+		fields:     []FieldError{{Field: field, Rule: rule, Param: fmt.Sprint(param)}},
+	}
+}
+
+func defaultUploadMessage(field FieldError) string {
+	name := field.Field
+	switch field.Rule {
+	case "required":
+		return name + " is required"
+	case "max_files":
+		return fmt.Sprintf("%s accepts at most %s files", name, field.Param)
+	case "min_size":
+		return fmt.Sprintf("%s must be at least %s bytes", name, field.Param)
+	case "max_size":
+		return fmt.Sprintf("%s must be at most %s bytes", name, field.Param)
+	case "type":
+		return name + " must be one of the types: " + field.Param
+	case "extension":
+		return name + " must have one of the extensions: " + field.Param
+	case "image":
+		return name + " must be a PNG, JPEG or GIF image"
+	case "min_width":
+		return fmt.Sprintf("%s must be at least %s pixels wide", name, field.Param)
+	case "max_width":
+		return fmt.Sprintf("%s must be at most %s pixels wide", name, field.Param)
+	case "min_height":
+		return fmt.Sprintf("%s must be at least %s pixels high", name, field.Param)
+	case "max_height":
+		return fmt.Sprintf("%s must be at most %s pixels high", name, field.Param)
+	}
+	return name + " is invalid"
+}
+
+// checkRequired returns an error listing the required fields without files
+func (h *UploadHandler) checkRequired(files map[string][]*UploadedFile) error {
+	var fields []FieldError
+	for field, rule := range h.rules {
+		if rule.Required && len(files[field]) == 0 {
+			fields = append(fields, FieldError{Field: field, Rule: "required"})
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return &uploadRuleError{statusCode: http.StatusBadRequest, fields: fields}
+}
+
+// checkFile checks the rules known before the content of a file is read
+func (rule UploadRule) checkFile(field, filename string, count int) error {
+	if rule.MaxFiles > 0 && count >= rule.MaxFiles {
+		return newUploadRuleError(http.StatusBadRequest, field, "max_files", rule.MaxFiles)
+	}
+	if len(rule.AllowedExtensions) > 0 {
+		ext := strings.ToLower(filepath.Ext(filename))
+		allowed := false
+		for _, allowedExt := range rule.AllowedExtensions {
+			if ext != "" && ext == "."+strings.TrimPrefix(strings.ToLower(allowedExt), ".") {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return newUploadRuleError(http.StatusBadRequest, field, "extension", strings.Join(rule.AllowedExtensions, ", "))
+		}
+	}
+	return nil
+}
+
+// headerLen returns the number of bytes of a file needed to check its content
+func (rule UploadRule) headerLen() int {
+	if rule.MinWidth > 0 || rule.MinHeight > 0 || rule.MaxWidth > 0 || rule.MaxHeight > 0 {
+		return uploadImageHeaderLen
+	}
+	return uploadSniffLen
+}
+
+// inspect detects the MIME type and the image dimensions of a file from its first bytes, without consuming
+// them, and checks them against the rule
+func (rule UploadRule) inspect(field string, content *bufio.Reader, file *UploadedFile) error {
+	header, err := content.Peek(rule.headerLen())
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	detected := mimetype.Detect(header)
+	file.DetectedType = detected.String()
+	if len(rule.AllowedTypes) > 0 && !mimeTypeAllowed(detected, rule.AllowedTypes) {
+		return newUploadRuleError(http.StatusBadRequest, field, "type", strings.Join(rule.AllowedTypes, ", "))
+	}
+
+	if rule.headerLen() != uploadImageHeaderLen {
+		return nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(header))
+	if err != nil {
+		return newUploadRuleError(http.StatusBadRequest, field, "image", "")
+	}
+	file.Width, file.Height = config.Width, config.Height
+
+	limits := []struct {
+		rule  string
+		limit int
+		ok    bool
+	}{
+		{"min_width", rule.MinWidth, config.Width >= rule.MinWidth},
+		{"max_width", rule.MaxWidth, rule.MaxWidth == 0 || config.Width <= rule.MaxWidth},
+		{"min_height", rule.MinHeight, config.Height >= rule.MinHeight},
+		{"max_height", rule.MaxHeight, rule.MaxHeight == 0 || config.Height <= rule.MaxHeight},
+	}
+	for _, limit := range limits {
+		if !limit.ok {
+			return newUploadRuleError(http.StatusBadRequest, field, limit.rule, strconv.Itoa(limit.limit))
+		}
+	}
+	return nil
+}
+
+// mimeTypeAllowed reports whether the detected type matches one of the allowed types or "type/*" patterns.
+// The parents of the detected type are not matched, so a HTML file is not a text/plain file.
+func mimeTypeAllowed(detected *mimetype.MIME, allowed []string) bool {
+	for _, allowedType := range allowed {
+		if prefix, ok := strings.CutSuffix(allowedType, "/*"); ok {
+			if strings.HasPrefix(detected.String(), prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if detected.Is(allowedType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package httpmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type uploadPart struct {
+	field       string
+	filename    string
+	contentType string
+	content     []byte
+}
+
+func newRuleUploadRequest(t *testing.T, parts ...uploadPart) *http.Request {
+	var b bytes.Buffer
+	writer := multipart.NewWriter(&b)
+	for _, part := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="`+part.field+`"; filename="`+part.filename+`"`)
+		header.Set("Content-Type", part.contentType)
+		partWriter, err := writer.CreatePart(header)
+		require.NoError(t, err)
+		_, err = partWriter.Write(part.content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &b)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	var b bytes.Buffer
+	require.NoError(t, png.Encode(&b, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return b.Bytes()
+}
+
+func testGIF(t *testing.T) []byte {
+	var b bytes.Buffer
+	require.NoError(t, gif.Encode(&b, image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.Black, color.White}), nil))
+	return b.Bytes()
+}
+
+func TestUploadHandler_FieldRules(t *testing.T) {
+	pdf := []byte("%PDF-1.7\n1 0 obj\n<< /Type /Catalog >>\nendobj\n%%EOF\n")
+	exe := append([]byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff"), make([]byte, 64)...)
+	avatarRule := UploadRule{
+		Required:          true,
+		MaxFiles:          1,
+		AllowedTypes:      []string{"image/png", "image/jpeg"},
+		AllowedExtensions: []string{".png", "JPG"},
+		MaxWidth:          64,
+		MaxHeight:         64,
+	}
+	documentsRule := UploadRule{
+		MaxFiles:     2,
+		MinSize:      16,
+		MaxSize:      1 << 10,
+		AllowedTypes: []string{"application/pdf"},
+	}
+
+	tests := []struct {
+		name       string
+		parts      []uploadPart
+		wantStatus int
+		wantRule   string
+		wantField  string
+	}{
+		{
+			name:       "valid upload",
+			parts:      []uploadPart{{"avatar", "me.png", "image/png", testPNG(t, 32, 16)}, {"documents", "cv.pdf", "application/pdf", pdf}},
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "disguised executable",
+			parts:      []uploadPart{{"avatar", "me.png", "image/png", exe}},
+			wantStatus: http.StatusBadRequest, wantField: "avatar", wantRule: "type",
+		},
+		{
+			name:       "extension not allowed",
+			parts:      []uploadPart{{"avatar", "me.gif", "image/png", testPNG(t, 8, 8)}},
+			wantStatus: http.StatusBadRequest, wantField: "avatar", wantRule: "extension",
+		},
+		{
+			name:       "image too wide",
+			parts:      []uploadPart{{"avatar", "me.png", "image/png", testPNG(t, 65, 8)}},
+			wantStatus: http.StatusBadRequest, wantField: "avatar", wantRule: "max_width",
+		},
+		{
+			name:       "too many files",
+			parts:      []uploadPart{{"avatar", "a.png", "image/png", testPNG(t, 8, 8)}, {"avatar", "b.png", "image/png", testPNG(t, 8, 8)}},
+			wantStatus: http.StatusBadRequest, wantField: "avatar", wantRule: "max_files",
+		},
+		{
+			name:       "required field missing",
+			parts:      []uploadPart{{"documents", "cv.pdf", "application/pdf", pdf}},
+			wantStatus: http.StatusBadRequest, wantField: "avatar", wantRule: "required",
+		},
+		{
+			name:       "file too small",
+			parts:      []uploadPart{{"avatar", "me.png", "image/png", testPNG(t, 8, 8)}, {"documents", "empty.pdf", "application/pdf", []byte("%PDF-")}},
+			wantStatus: http.StatusBadRequest, wantField: "documents", wantRule: "min_size",
+		},
+		{
+			name:       "file too large",
+			parts:      []uploadPart{{"avatar", "me.png", "image/png", testPNG(t, 8, 8)}, {"documents", "big.pdf", "application/pdf", append(pdf, make([]byte, 2<<10)...)}},
+			wantStatus: http.StatusRequestEntityTooLarge, wantField: "documents", wantRule: "max_size",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := NewMemoryStorage()
+			var uploaded map[string][]*UploadedFile
+			handler := NewUploadHandlerWithStorage(http.MethodPost, storage, func(ctx context.Context, files map[string][]*UploadedFile, form map[string][]string) (interface{}, error) {
+				uploaded = files
+				return nil, nil
+			}).WithFieldRule("avatar", avatarRule).WithFieldRule("documents", documentsRule)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, newRuleUploadRequest(t, tt.parts...))
+
+			require.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			if tt.wantStatus == http.StatusNoContent {
+				assert.Equal(t, 2, storage.Len())
+				avatar := uploaded["avatar"][0]
+				assert.Equal(t, "image/png", avatar.DetectedType)
+				assert.Equal(t, [2]int{32, 16}, [2]int{avatar.Width, avatar.Height})
+				assert.Equal(t, "application/pdf", uploaded["documents"][0].DetectedType)
+				return
+			}
+
+			assert.Equal(t, 0, storage.Len(), "rejected uploads must not be stored")
+			var body struct {
+				Data []FieldError `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			require.Len(t, body.Data, 1)
+			assert.Equal(t, tt.wantField, body.Data[0].Field)
+			assert.Equal(t, tt.wantRule, body.Data[0].Rule)
+			assert.NotEmpty(t, body.Data[0].Message)
+		})
+	}
+}
+
+func TestUploadHandler_FieldRules_Images(t *testing.T) {
+	storage := NewMemoryStorage()
+	handler := NewUploadHandlerWithStorage(http.MethodPost, storage, func(ctx context.Context, files map[string][]*UploadedFile, form map[string][]string) (interface{}, error) {
+		return nil, nil
+	}).WithFieldRule("photo", UploadRule{AllowedTypes: []string{"image/*"}, MinWidth: 8})
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, newRuleUploadRequest(t, uploadPart{"photo", "anim.gif", "image/gif", testGIF(t)}))
+	assert.Equal(t, http.StatusNoContent, rr.Code, "image/* allows any image type")
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, newRuleUploadRequest(t, uploadPart{"photo", "small.png", "image/png", testPNG(t, 4, 4)}))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "photo must be at least 8 pixels wide")
+
+	rr = httptest.NewRecorder()
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="100" height="100"></svg>`)
+	handler.ServeHTTP(rr, newRuleUploadRequest(t, uploadPart{"photo", "logo.svg", "image/svg+xml", svg}))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "dimension rules only accept decodable images")
+	assert.Contains(t, rr.Body.String(), `"rule":"image"`)
+}
+
+func TestUploadReader_SizeLimits(t *testing.T) {
+	reader := &uploadReader{r: strings.NewReader("0123456789"), hash: sha256.New(), field: "file", maxSize: 4}
+	_, err := io.ReadAll(reader)
+	var ruleErr *uploadRuleError
+	require.ErrorAs(t, err, &ruleErr)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, ruleErr.statusCode)
+	assert.Equal(t, ruleErr, reader.err)
+
+	reader = &uploadReader{r: strings.NewReader("0123456789"), hash: sha256.New(), field: "file", minSize: 10, maxSize: 10}
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err, "the limits are inclusive")
+	assert.Equal(t, "0123456789", string(content))
+}
@@ -0,0 +1,246 @@
+package httpmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+// validatorKey is the context key for the request validator of the server
+const validatorKey contextKey = "validator"
+
+// embeddedFieldName names the embedded structs in the validator namespaces, so that they
+// can be removed from the field paths like JSON flattens them.
+const embeddedFieldName = "~"
+
+// FieldError describes a validation rule a request field failed.
+type FieldError struct {
+	// Field is the path of the field using its JSON names, e.g. "items[0].name", or the
+	// parameter name of the fields bound from the path, query, header or cookie.
+	Field string `json:"field"`
+	// Rule is the failed `validate` tag rule, e.g. "required" or "min".
+	Rule string `json:"rule"`
+	// Param is the rule parameter, e.g. "3" for "min=3".
+	Param string `json:"param,omitempty"`
+	// Message is the human-readable error message.
+	Message string `json:"message"`
+}
+
+// ValidationError is the error of a request that failed its `validate` tags.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// ValidationMessageFunc returns the message of a failed rule. The request gives access to
+// headers such as Accept-Language for translated messages.
+type ValidationMessageFunc func(r *http.Request, field FieldError) string
+
+// ValidationErrorHandler returns the status code and the JSON body of a validation error response.
+type ValidationErrorHandler func(r *http.Request, err *ValidationError) (statusCode int, body any)
+
+// requestValidator validates handler requests with go-playground/validator.
+type requestValidator struct {
+	validate     *validator.Validate
+	statusCode   int
+	messageFunc  ValidationMessageFunc
+	errorHandler ValidationErrorHandler
+}
+
+var (
+	defaultValidator     *requestValidator
+	defaultValidatorOnce sync.Once
+)
+
+func newRequestValidator(o *Option) *requestValidator {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			return ""
+		case "":
+			if field.Anonymous {
+				return embeddedFieldName
+			}
+			// Fields bound from the request parameters or a form body are named after the parameter
+			if _, param, _ := parseBindTag(field); param != "" {
+				return param
+			}
+			if param, _, _ := strings.Cut(field.Tag.Get(bindForm), ","); param != "" && param != "-" {
+				return param
+			}
+			return field.Name
+		}
+		return name
+	})
+
+	v := &requestValidator{validate: validate, statusCode: http.StatusBadRequest}
+	if o != nil {
+		if o.validationStatusCode != 0 {
+			v.statusCode = o.validationStatusCode
+		}
+		v.messageFunc = o.validationMessageFunc
+		v.errorHandler = o.validationErrorHandler
+	}
+	return v
+}
+
+// validatorFromContext returns the validator of the server handling the request,
+// or a default one when the handler is used without a Server.
+func validatorFromContext(ctx context.Context) *requestValidator {
+	if v, ok := ctx.Value(validatorKey).(*requestValidator); ok {
+		return v
+	}
+	defaultValidatorOnce.Do(func() {
+		defaultValidator = newRequestValidator(nil)
+	})
+	return defaultValidator
+}
+
+// validatorMiddleware makes the server validator available to the handlers.
+func validatorMiddleware(v *requestValidator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), validatorKey, v)))
+		})
+	}
+}
+
+// check validates req and returns a *ValidationError listing every failed rule.
+// Requests that are not structs are not validated.
+func (v *requestValidator) check(r *http.Request, req any) error {
+	err := v.validate.Struct(req)
+	if err == nil {
+		return nil
+	}
+	var invalidValidationError *validator.InvalidValidationError
+	if errors.As(err, &invalidValidationError) {
+		return nil
+	}
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	fields := make([]FieldError, len(validationErrors))
+	for i, fe := range validationErrors {
+		field := FieldError{
+			Field: fieldPath(fe.Namespace()),
+			Rule:  fe.Tag(),
+			Param: fe.Param(),
+		}
+		if v.messageFunc != nil {
+			field.Message = v.messageFunc(r, field)
+		}
+		if field.Message == "" {
+			field.Message = defaultValidationMessage(field, fe.Kind())
+		}
+		fields[i] = field
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// httpError returns the response of a validation error.
+func (v *requestValidator) httpError(r *http.Request, err *ValidationError) *HTTPError {
+	if v.errorHandler != nil {
+		statusCode, body := v.errorHandler(r, err)
+		return &HTTPError{StatusCode: statusCode, Body: body, Err: err}
+	}
+	return &HTTPError{
+		StatusCode: v.statusCode,
+		Title:      "validation failed",
+		Detail:     err.Error(),
+		Data:       err.Fields,
+		Err:        err,
+	}
+}
+
+// fieldPath removes the root struct name and the embedded structs from a validator namespace.
+func fieldPath(namespace string) string {
+	if _, path, found := strings.Cut(namespace, "."); found {
+		namespace = path
+	}
+	return strings.ReplaceAll(namespace, embeddedFieldName+".", "")
+}
+
+func defaultValidationMessage(field FieldError, kind reflect.Kind) string {
+	name := field.Field
+	var unit string
+	switch kind {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch field.Rule {
+	case "required", "required_if", "required_unless", "required_with", "required_without":
+		return name + " is required"
+	case "email":
+		return name + " must be a valid email address"
+	case "url", "http_url", "uri":
+		return name + " must be a valid URL"
+	case "uuid", "uuid4":
+		return name + " must be a valid UUID"
+	case "oneof":
+		return name + " must be one of: " + strings.Join(strings.Fields(field.Param), ", ")
+	case "len":
+		return fmt.Sprintf("%s must be exactly %s%s", name, field.Param, unit)
+	case "min":
+		if unit == "" {
+			return fmt.Sprintf("%s must be %s or greater", name, field.Param)
+		}
+		return fmt.Sprintf("%s must be at least %s%s", name, field.Param, unit)
+	case "max":
+		if unit == "" {
+			return fmt.Sprintf("%s must be %s or less", name, field.Param)
+		}
+		return fmt.Sprintf("%s must be at most %s%s", name, field.Param, unit)
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", name, field.Param)
+	case "gte":
+		return fmt.Sprintf("%s must be %s or greater", name, field.Param)
+	case "lt":
+		return fmt.Sprintf("%s must be less than %s", name, field.Param)
+	case "lte":
+		return fmt.Sprintf("%s must be %s or less", name, field.Param)
+	case "numeric", "number":
+		return name + " must be numeric"
+	case "alphanum":
+		return name + " must contain only letters and numbers"
+	}
+	return fmt.Sprintf("%s failed the %q rule", name, field.Rule)
+}
+
+// RegisterValidation registers a custom `validate` tag rule for the handlers of the server.
+//
+// Example:
+//
+//	_ = server.RegisterValidation("msisdn", func(fl validator.FieldLevel) bool {
+//	    return strings.HasPrefix(fl.Field().String(), "62")
+//	})
+func (s *Server) RegisterValidation(tag string, fn validator.Func) error {
+	return s.validator.validate.RegisterValidation(tag, fn)
+}
+
+// RegisterStructValidation registers a struct-level validation for the given types,
+// for rules spanning several fields.
+func (s *Server) RegisterStructValidation(fn validator.StructLevelFunc, types ...any) {
+	s.validator.validate.RegisterStructValidation(fn, types...)
+}
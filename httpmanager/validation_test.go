@@ -0,0 +1,184 @@
+package httpmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SALT-Indonesia/salt-pkg/httpmanager/internal/testdata"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validationItem struct {
+	SKU      string `json:"sku" validate:"required"`
+	Quantity int    `json:"quantity" validate:"gte=1"`
+}
+
+type validationRequest struct {
+	Name   string           `json:"name" validate:"required,min=3"`
+	Email  string           `json:"email" validate:"required,email"`
+	Status string           `json:"status" validate:"omitempty,oneof=active inactive"`
+	Items  []validationItem `json:"items" validate:"dive"`
+}
+
+func newValidationHandler(called *bool) *Handler[validationRequest, Response] {
+	return NewHandler(http.MethodPost, func(ctx context.Context, req *validationRequest) (*Response, error) {
+		*called = true
+		return &Response{Message: "ok"}, nil
+	})
+}
+
+func serveJSON(handler http.Handler, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_Validation(t *testing.T) {
+	t.Run("invalid request lists every field error", func(t *testing.T) {
+		var called bool
+		rec := serveJSON(newValidationHandler(&called), `{"name":"ab","email":"nope","status":"gone","items":[{"sku":"","quantity":0}]}`)
+
+		assert.False(t, called)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+		var body struct {
+			Status  bool         `json:"status"`
+			Code    string       `json:"code"`
+			Message MessageInfo  `json:"message"`
+			Data    []FieldError `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.False(t, body.Status)
+		assert.Equal(t, "400", body.Code)
+		assert.Equal(t, "validation failed", body.Message.Title)
+		assert.Equal(t, []FieldError{
+			{Field: "name", Rule: "min", Param: "3", Message: "name must be at least 3 characters"},
+			{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+			{Field: "status", Rule: "oneof", Param: "active inactive", Message: "status must be one of: active, inactive"},
+			{Field: "items[0].sku", Rule: "required", Message: "items[0].sku is required"},
+			{Field: "items[0].quantity", Rule: "gte", Param: "1", Message: "items[0].quantity must be 1 or greater"},
+		}, body.Data)
+	})
+
+	t.Run("valid request reaches the handler", func(t *testing.T) {
+		var called bool
+		rec := serveJSON(newValidationHandler(&called), `{"name":"alice","email":"alice@example.com","items":[{"sku":"A1","quantity":2}]}`)
+
+		assert.True(t, called)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("empty body is validated", func(t *testing.T) {
+		var called bool
+		rec := serveJSON(newValidationHandler(&called), ``)
+
+		assert.False(t, called)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "name is required")
+	})
+
+	t.Run("opt-out per handler", func(t *testing.T) {
+		var called bool
+		rec := serveJSON(newValidationHandler(&called).WithoutValidation(), `{}`)
+
+		assert.True(t, called)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("request without validate tags", func(t *testing.T) {
+		handler := NewHandler(http.MethodPost, func(ctx context.Context, req *Request) (*Response, error) {
+			return &Response{Message: req.Name}, nil
+		})
+		rec := serveJSON(handler, `{}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestServer_Validation(t *testing.T) {
+	t.Run("status code, messages and custom rule", func(t *testing.T) {
+		server := NewServer(testdata.NewApplication(),
+			WithValidationStatusCode(http.StatusUnprocessableEntity),
+			WithValidationMessages(func(r *http.Request, fe FieldError) string {
+				if r.Header.Get("Accept-Language") == "id" && fe.Rule == "required" {
+					return fe.Field + " wajib diisi"
+				}
+				return ""
+			}),
+		)
+		require.NoError(t, server.RegisterValidation("msisdn", func(fl validator.FieldLevel) bool {
+			return strings.HasPrefix(fl.Field().String(), "62")
+		}))
+		type contactRequest struct {
+			Email string `json:"email" validate:"required,email"`
+			Phone string `json:"phone" validate:"omitempty,msisdn"`
+		}
+		server.POST("/users", NewHandler(http.MethodPost, func(ctx context.Context, req *contactRequest) (*Response, error) {
+			return &Response{}, nil
+		}))
+
+		rec := serveJSON(server.router, `{"phone":"0812"}`, "Accept-Language", "id")
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		var body DetailedErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "422", body.Code)
+		assert.Equal(t, []any{
+			map[string]any{"field": "email", "rule": "required", "message": "email wajib diisi"},
+			map[string]any{"field": "phone", "rule": "msisdn", "message": `phone failed the "msisdn" rule`},
+		}, body.Data)
+	})
+
+	t.Run("custom error body", func(t *testing.T) {
+		server := NewServer(testdata.NewApplication(),
+			WithValidationErrorHandler(func(r *http.Request, err *ValidationError) (int, any) {
+				errs := map[string]string{}
+				for _, field := range err.Fields {
+					errs[field.Field] = field.Rule
+				}
+				return http.StatusUnprocessableEntity, map[string]any{"errors": errs}
+			}),
+		)
+		var called bool
+		server.POST("/users", newValidationHandler(&called))
+
+		rec := serveJSON(server.router, `{"name":"alice"}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.JSONEq(t, `{"errors":{"email":"required"}}`, rec.Body.String())
+	})
+
+	t.Run("struct level validation", func(t *testing.T) {
+		type passwordRequest struct {
+			Password string `json:"password"`
+			Confirm  string `json:"confirm"`
+		}
+		server := NewServer(testdata.NewApplication())
+		server.RegisterStructValidation(func(sl validator.StructLevel) {
+			req := sl.Current().Interface().(passwordRequest)
+			if req.Password != req.Confirm {
+				sl.ReportError(req.Confirm, "confirm", "Confirm", "eqfield", "password")
+			}
+		}, passwordRequest{})
+		server.POST("/users", NewHandler(http.MethodPost, func(ctx context.Context, req *passwordRequest) (*Response, error) {
+			return &Response{}, nil
+		}))
+
+		rec := serveJSON(server.router, `{"password":"a","confirm":"b"}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"field":"confirm","rule":"eqfield","param":"password"`)
+	})
+}
@@ -0,0 +1,543 @@
+package httpmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultWebSocketReadLimit    = 64 << 10
+	defaultWebSocketPingInterval = 30 * time.Second
+	defaultWebSocketWriteTimeout = 10 * time.Second
+
+	// webSocketCloseTimeout bounds the wait for the client to answer the close frame of the server.
+	webSocketCloseTimeout = time.Second
+	// maxCloseReason is the longest reason a close frame can carry.
+	maxCloseReason = 123
+)
+
+// ErrWebSocketClosed is returned when sending on a WebSocket connection that is closed or closing.
+var ErrWebSocketClosed = errors.New("websocket closed")
+
+// invalidMessageError is a message that does not decode into the message type or fails its validation.
+type invalidMessageError struct {
+	err error
+}
+
+func (e *invalidMessageError) Error() string { return e.err.Error() }
+
+func (e *invalidMessageError) Unwrap() error { return e.err }
+
+// WebSocketConn is a WebSocket connection sending typed messages as JSON. It is safe for concurrent use.
+type WebSocketConn[Out any] struct {
+	ws           *websocket.Conn
+	cancel       context.CancelFunc
+	writeTimeout time.Duration
+
+	mu       sync.Mutex
+	closing  bool
+	sent     int
+	received int
+}
+
+// Send sends the message as a JSON text message.
+func (c *WebSocketConn[Out]) Send(msg Out) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		return ErrWebSocketClosed
+	}
+	_ = c.ws.SetWriteDeadline(c.writeDeadline())
+	if err := c.ws.WriteMessage(websocket.TextMessage, data); err != nil {
+		// The client is gone or too slow, the handler sees its context canceled
+		c.closing = true
+		c.cancel()
+		return ErrWebSocketClosed
+	}
+	c.sent++
+	return nil
+}
+
+// Close starts the closing handshake with the status code, e.g. websocket.CloseNormalClosure, and the reason.
+// The connection is closed when the client answers, or after a second. Messages received in between are
+// discarded.
+func (c *WebSocketConn[Out]) Close(code int, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		return nil
+	}
+	c.closing = true
+
+	if len(reason) > maxCloseReason {
+		reason = strings.ToValidUTF8(reason[:maxCloseReason], "")
+	}
+	deadline := time.Now().Add(webSocketCloseTimeout)
+	err := c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+	// The read loop ends with the close frame of the client, or at the deadline
+	_ = c.ws.SetReadDeadline(deadline)
+	return err
+}
+
+// goingAway closes the connection when the server shuts down.
+func (c *WebSocketConn[Out]) goingAway() {
+	_ = c.Close(websocket.CloseGoingAway, "server shutting down")
+}
+
+// closeFor closes the connection for an error returned by the handler: a *websocket.CloseError with its code,
+// an invalid message with 1007 and any other error with 1011. It returns the error to record on the
+// transaction, nil when the connection was closed on purpose.
+func (c *WebSocketConn[Out]) closeFor(err error) error {
+	var closeErr *websocket.CloseError
+	var invalidErr *invalidMessageError
+	switch {
+	case errors.As(err, &closeErr):
+		_ = c.Close(closeErr.Code, closeErr.Text)
+		return nil
+	case errors.As(err, &invalidErr):
+		_ = c.Close(websocket.CloseInvalidFramePayloadData, invalidErr.Error())
+		return nil
+	case errors.Is(err, ErrWebSocketClosed), errors.Is(err, context.Canceled):
+		return nil
+	default:
+		_ = c.Close(websocket.CloseInternalServerErr, "internal error")
+		return err
+	}
+}
+
+// ping sends a ping, answered by the pong of the client.
+func (c *WebSocketConn[Out]) ping() {
+	c.mu.Lock()
+	closing := c.closing
+	c.mu.Unlock()
+	if !closing {
+		_ = c.ws.WriteControl(websocket.PingMessage, nil, c.writeDeadline())
+	}
+}
+
+// extendReadDeadline keeps the connection open for d after a message or a pong of the client.
+func (c *WebSocketConn[Out]) extendReadDeadline(d time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closing {
+		return nil
+	}
+	return c.ws.SetReadDeadline(time.Now().Add(d))
+}
+
+func (c *WebSocketConn[Out]) isClosing() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closing
+}
+
+// finish stops the sends once the read loop ended, and returns the number of messages received and sent.
+func (c *WebSocketConn[Out]) finish() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closing = true
+	return c.received + c.sent
+}
+
+func (c *WebSocketConn[Out]) writeDeadline() time.Time {
+	if c.writeTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(c.writeTimeout)
+}
+
+// WebSocketHandlerFunc handles a message received on a WebSocket connection. Messages are handled one at a
+// time, in the order they are received. ctx is canceled when the connection closes.
+//
+// Returning a *websocket.CloseError closes the connection with its code and text. Any other error closes it
+// with 1011 and is recorded on the logmanager transaction of the connection.
+type WebSocketHandlerFunc[In any, Out any] func(ctx context.Context, msg *In, conn *WebSocketConn[Out]) error
+
+// WebSocketHandler upgrades requests to WebSocket connections exchanging JSON messages. The connection
+// keeps the logmanager transaction of the upgrade request until it closes.
+type WebSocketHandler[In any, Out any] struct {
+	handlerFunc    WebSocketHandlerFunc[In, Out]
+	onConnect      func(ctx context.Context, conn *WebSocketConn[Out]) error
+	middlewares    []mux.MiddlewareFunc
+	origins        *corsPolicy
+	readLimit      int64
+	pingInterval   time.Duration
+	writeTimeout   time.Duration
+	logMessages    bool
+	masking        []logmanager.MaskingConfig
+	skipValidation bool
+}
+
+// NewWebSocketHandler creates a WebSocket handler, registered for GET requests.
+func NewWebSocketHandler[In any, Out any](handlerFunc WebSocketHandlerFunc[In, Out]) *WebSocketHandler[In, Out] {
+	if handlerFunc == nil {
+		panic("handlerFunc cannot be nil")
+	}
+	return &WebSocketHandler[In, Out]{
+		handlerFunc:  handlerFunc,
+		readLimit:    defaultWebSocketReadLimit,
+		pingInterval: defaultWebSocketPingInterval,
+		writeTimeout: defaultWebSocketWriteTimeout,
+	}
+}
+
+// WithOnConnect sets a function called once the connection is open, before the first message is read,
+// e.g. to send a welcome message or to start pushing messages from a goroutine bound to ctx.
+// An error closes the connection like an error of the handler.
+func (h *WebSocketHandler[In, Out]) WithOnConnect(fn func(ctx context.Context, conn *WebSocketConn[Out]) error) *WebSocketHandler[In, Out] {
+	h.onConnect = fn
+	return h
+}
+
+// WithAllowedOrigins sets the origins allowed to open connections, with the patterns of CORSConfig.AllowedOrigins.
+// By default, only the origin of the server itself is allowed. Requests without an Origin header, which
+// browsers always send, are allowed.
+func (h *WebSocketHandler[In, Out]) WithAllowedOrigins(origins ...string) *WebSocketHandler[In, Out] {
+	h.origins = newCORSPolicy(CORSConfig{AllowedOrigins: origins})
+	return h
+}
+
+// WithReadLimit sets the maximum size in bytes of a received message. A larger message closes the connection
+// with 1009. Zero disables the limit. Defaults to 64 KiB.
+func (h *WebSocketHandler[In, Out]) WithReadLimit(limit int64) *WebSocketHandler[In, Out] {
+	h.readLimit = limit
+	return h
+}
+
+// WithPingInterval sets the interval of the pings keeping the connection alive. A client that sends neither
+// a pong nor a message within two intervals is disconnected. Zero disables the pings. Defaults to 30s.
+func (h *WebSocketHandler[In, Out]) WithPingInterval(interval time.Duration) *WebSocketHandler[In, Out] {
+	h.pingInterval = interval
+	return h
+}
+
+// WithWriteTimeout sets the deadline of each write, replacing the WriteTimeout of the server for the
+// connection. A client that does not read within it is disconnected. Zero disables it. Defaults to 10s.
+func (h *WebSocketHandler[In, Out]) WithWriteTimeout(timeout time.Duration) *WebSocketHandler[In, Out] {
+	h.writeTimeout = timeout
+	return h
+}
+
+// WithMessageLogging logs each received message as a segment of the connection transaction, with the
+// masking configs applied to the message in addition to the masking of the application.
+func (h *WebSocketHandler[In, Out]) WithMessageLogging(masking ...logmanager.MaskingConfig) *WebSocketHandler[In, Out] {
+	h.logMessages = true
+	h.masking = masking
+	return h
+}
+
+// WithoutValidation disables the validation of the messages against their `validate` tags.
+func (h *WebSocketHandler[In, Out]) WithoutValidation() *WebSocketHandler[In, Out] {
+	h.skipValidation = true
+	return h
+}
+
+// Use adds middleware to the handler
+func (h *WebSocketHandler[In, Out]) Use(middleware ...mux.MiddlewareFunc) *WebSocketHandler[In, Out] {
+	h.middlewares = append(h.middlewares, middleware...)
+	return h
+}
+
+// WithMiddleware returns an http.Handler with the middleware applied
+func (h *WebSocketHandler[In, Out]) WithMiddleware() http.Handler {
+	var handler http.Handler = h
+	for i := len(h.middlewares) - 1; i >= 0; i-- {
+		handler = h.middlewares[i](handler)
+	}
+	return handler
+}
+
+// ServeHTTP upgrades the request and handles the messages of the connection until it closes.
+func (h *WebSocketHandler[In, Out]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteError(w, r, methodNotAllowedError(r))
+		return
+	}
+	if !h.checkOrigin(r) {
+		WriteError(w, r, &HTTPError{
+			StatusCode: http.StatusForbidden,
+			Title:      "forbidden",
+			Detail:     "origin not allowed",
+		})
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		// The origin is checked above, with the error handling of the server
+		CheckOrigin: func(*http.Request) bool { return true },
+		Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+			WriteError(w, r, &HTTPError{
+				StatusCode: status,
+				Title:      strings.ToLower(http.StatusText(status)),
+				Detail:     reason.Error(),
+				Err:        reason,
+			})
+		},
+	}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+
+	ctx := r.Context()
+	ctx = context.WithValue(ctx, queryParamsKey, QueryParams(r.URL.Query()))
+	ctx = context.WithValue(ctx, pathParamsKey, extractPathParams(r))
+	ctx = context.WithValue(ctx, RequestKey, r)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	conn := &WebSocketConn[Out]{ws: ws, cancel: cancel, writeTimeout: h.writeTimeout}
+	started := time.Now()
+	txn := logmanager.FromContext(r.Context())
+	if txn != nil {
+		txn.StartStream()
+	}
+
+	connections := webSocketsFromContext(r.Context())
+	connections.add(conn)
+	defer connections.remove(conn)
+
+	h.configure(conn)
+	stopPing := h.startPing(ctx, conn)
+	err = h.serve(ctx, r, conn)
+	stopPing()
+	cancel()
+
+	messages := conn.finish()
+	if txn != nil {
+		txn.NoticeStream(messages, time.Since(started), err)
+	}
+}
+
+// checkOrigin reports whether the Origin of the upgrade request is allowed.
+func (h *WebSocketHandler[In, Out]) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if h.origins != nil {
+		return h.origins.allowOrigin(origin)
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// configure sets the read limit and the read deadline of the connection. The deadlines set by the server
+// for the upgrade request are replaced.
+func (h *WebSocketHandler[In, Out]) configure(conn *WebSocketConn[Out]) {
+	conn.ws.SetReadLimit(h.readLimit)
+	if h.pingInterval <= 0 {
+		_ = conn.ws.SetReadDeadline(time.Time{})
+		return
+	}
+	_ = conn.extendReadDeadline(2 * h.pingInterval)
+	conn.ws.SetPongHandler(func(string) error {
+		return conn.extendReadDeadline(2 * h.pingInterval)
+	})
+}
+
+// startPing sends pings until the returned function is called.
+func (h *WebSocketHandler[In, Out]) startPing(ctx context.Context, conn *WebSocketConn[Out]) (stop func()) {
+	if h.pingInterval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(h.pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				conn.ping()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// serve reads the messages until the connection closes, and returns the error of the handler, if any.
+// A connection closed by the client, by the server or by a read failure is the normal end.
+func (h *WebSocketHandler[In, Out]) serve(ctx context.Context, r *http.Request, conn *WebSocketConn[Out]) error {
+	var handlerErr error
+	if h.onConnect != nil {
+		if err := h.onConnect(ctx, conn); err != nil {
+			handlerErr = conn.closeFor(err)
+		}
+	}
+
+	for {
+		messageType, data, err := conn.ws.ReadMessage()
+		if err != nil {
+			return handlerErr
+		}
+		if conn.isClosing() || (messageType != websocket.TextMessage && messageType != websocket.BinaryMessage) {
+			continue
+		}
+		conn.received++
+		if h.pingInterval > 0 {
+			_ = conn.extendReadDeadline(2 * h.pingInterval)
+		}
+
+		if err := h.handle(ctx, r, conn, data); err != nil {
+			if err = conn.closeFor(err); err != nil {
+				handlerErr = err
+			}
+		}
+	}
+}
+
+// handle decodes, validates and handles a message, in a segment of the transaction when messages are logged.
+func (h *WebSocketHandler[In, Out]) handle(ctx context.Context, r *http.Request, conn *WebSocketConn[Out], data []byte) error {
+	segment := h.startSegment(ctx, data)
+	defer segment.End()
+
+	var msg In
+	if err := json.Unmarshal(data, &msg); err != nil {
+		err = &invalidMessageError{err: fmt.Errorf("decode message: %w", err)}
+		segment.SetBusinessError(err)
+		return err
+	}
+	if !h.skipValidation {
+		if err := validatorFromContext(ctx).check(r, &msg); err != nil {
+			err = &invalidMessageError{err: err}
+			segment.SetBusinessError(err)
+			return err
+		}
+	}
+
+	err := h.handlerFunc(ctx, &msg, conn)
+	var closeErr *websocket.CloseError
+	if err != nil && !errors.As(err, &closeErr) {
+		segment.NoticeError(err)
+	}
+	return err
+}
+
+// startSegment starts the segment of a received message, with the masked message as its request.
+func (h *WebSocketHandler[In, Out]) startSegment(ctx context.Context, data []byte) *logmanager.TxnRecord {
+	if !h.logMessages {
+		return nil
+	}
+
+	segment := logmanager.StartOtherSegmentWithContext(ctx, logmanager.OtherSegment{Name: "websocket message"})
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		value = string(data)
+	}
+	segment.SetRequestValueMasked(value, h.masking)
+	return segment
+}
+
+// webSocketRegistry tracks the open WebSocket connections of a server, to close them when it shuts down.
+type webSocketRegistry struct {
+	mu       sync.Mutex
+	conns    map[webSocketCloser]struct{}
+	shutdown bool
+}
+
+// webSocketCloser is an open WebSocket connection.
+type webSocketCloser interface {
+	goingAway()
+}
+
+func newWebSocketRegistry() *webSocketRegistry {
+	return &webSocketRegistry{conns: map[webSocketCloser]struct{}{}}
+}
+
+// add tracks a connection. It is closed right away when the server is shutting down.
+func (reg *webSocketRegistry) add(conn webSocketCloser) {
+	if reg == nil {
+		return
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.conns[conn] = struct{}{}
+	if reg.shutdown {
+		conn.goingAway()
+	}
+}
+
+func (reg *webSocketRegistry) remove(conn webSocketCloser) {
+	if reg == nil {
+		return
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	delete(reg.conns, conn)
+}
+
+// closeAll closes the open connections with 1001 going away. http.Server.Shutdown calls it, since it does
+// not track the hijacked connections.
+func (reg *webSocketRegistry) closeAll() {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.shutdown = true
+	for conn := range reg.conns {
+		conn.goingAway()
+	}
+}
+
+// wait waits until the connections are closed or ctx is done.
+func (reg *webSocketRegistry) wait(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		reg.mu.Lock()
+		open := len(reg.conns)
+		reg.mu.Unlock()
+		if open == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("close websocket connections: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+const webSocketsKey contextKey = "webSockets"
+
+// webSocketsMiddleware makes the connection registry of the server available to the WebSocket handlers.
+func webSocketsMiddleware(reg *webSocketRegistry) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), webSocketsKey, reg)))
+		})
+	}
+}
+
+// webSocketsFromContext returns the connection registry of the server handling the request,
+// or nil when the handler is used without a Server.
+func webSocketsFromContext(ctx context.Context) *webSocketRegistry {
+	reg, _ := ctx.Value(webSocketsKey).(*webSocketRegistry)
+	return reg
+}
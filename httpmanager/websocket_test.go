@@ -0,0 +1,271 @@
+package httpmanager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SALT-Indonesia/salt-pkg/logmanager"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type chatMessage struct {
+	Room  string `json:"room" validate:"required"`
+	Text  string `json:"text"`
+	Token string `json:"token"`
+}
+
+type chatReply struct {
+	Room string `json:"room"`
+	Echo string `json:"echo"`
+}
+
+var errChatFailed = errors.New("chat storage unavailable")
+
+func chat(ctx context.Context, msg *chatMessage, conn *WebSocketConn[chatReply]) error {
+	switch msg.Text {
+	case "fail":
+		return errChatFailed
+	case "bye":
+		return &websocket.CloseError{Code: websocket.CloseNormalClosure, Text: "bye"}
+	}
+	return conn.Send(chatReply{Room: msg.Room, Echo: msg.Text})
+}
+
+func newChatServer(t *testing.T, handler *WebSocketHandler[chatMessage, chatReply]) (*logmanager.TestableApplication, *Server, string) {
+	app := logmanager.NewTestableApplication()
+	server := NewServer(app.Application)
+	server.GET("/chat", handler)
+	ts := httptest.NewServer(server.router)
+	t.Cleanup(ts.Close)
+	return app, server, "ws" + strings.TrimPrefix(ts.URL, "http") + "/chat"
+}
+
+func dialChat(t *testing.T, url string, header http.Header) *websocket.Conn {
+	ws, resp, err := websocket.DefaultDialer.Dial(url, header)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	t.Cleanup(func() { _ = ws.Close() })
+	return ws
+}
+
+// readClose reads until the connection closes and returns its close code.
+func readClose(t *testing.T, ws *websocket.Conn) int {
+	_ = ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := ws.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			require.ErrorAs(t, err, &closeErr)
+			return closeErr.Code
+		}
+	}
+}
+
+func waitLogged(t *testing.T, app *logmanager.TestableApplication, count int) {
+	require.Eventually(t, func() bool { return app.CountLoggedEntries() >= count }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	t.Run("exchanges typed messages", func(t *testing.T) {
+		app, _, url := newChatServer(t, NewWebSocketHandler(chat))
+		ws := dialChat(t, url, nil)
+
+		require.NoError(t, ws.WriteJSON(chatMessage{Room: "general", Text: "hello"}))
+		var reply chatReply
+		require.NoError(t, ws.ReadJSON(&reply))
+		assert.Equal(t, chatReply{Room: "general", Echo: "hello"}, reply)
+
+		require.NoError(t, ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+		assert.Equal(t, websocket.CloseNormalClosure, readClose(t, ws))
+
+		waitLogged(t, app, 1)
+		assert.Equal(t, "info", app.GetLoggedLevel().String())
+		assert.Equal(t, http.StatusSwitchingProtocols, app.GetLoggedField("status"))
+		assert.Equal(t, 2, app.GetLoggedField("stream_events"))
+	})
+
+	t.Run("closes on a message that does not decode", func(t *testing.T) {
+		_, _, url := newChatServer(t, NewWebSocketHandler(chat))
+		ws := dialChat(t, url, nil)
+
+		require.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte(`{"room":`)))
+		assert.Equal(t, websocket.CloseInvalidFramePayloadData, readClose(t, ws))
+	})
+
+	t.Run("closes on a message that fails validation", func(t *testing.T) {
+		app, _, url := newChatServer(t, NewWebSocketHandler(chat))
+		ws := dialChat(t, url, nil)
+
+		require.NoError(t, ws.WriteJSON(chatMessage{Text: "hello"}))
+		assert.Equal(t, websocket.CloseInvalidFramePayloadData, readClose(t, ws))
+
+		waitLogged(t, app, 1)
+		assert.Equal(t, "info", app.GetLoggedLevel().String(), "an invalid message is not an error of the server")
+	})
+
+	t.Run("closes with 1011 on a handler error", func(t *testing.T) {
+		app, _, url := newChatServer(t, NewWebSocketHandler(chat))
+		ws := dialChat(t, url, nil)
+
+		require.NoError(t, ws.WriteJSON(chatMessage{Room: "general", Text: "fail"}))
+		assert.Equal(t, websocket.CloseInternalServerErr, readClose(t, ws))
+
+		waitLogged(t, app, 1)
+		assert.Equal(t, "error", app.GetLoggedLevel().String())
+		assert.Equal(t, errChatFailed.Error(), app.GetLoggedMessage())
+	})
+
+	t.Run("closes with the code of a CloseError", func(t *testing.T) {
+		_, _, url := newChatServer(t, NewWebSocketHandler(chat))
+		ws := dialChat(t, url, nil)
+
+		require.NoError(t, ws.WriteJSON(chatMessage{Room: "general", Text: "bye"}))
+		assert.Equal(t, websocket.CloseNormalClosure, readClose(t, ws))
+	})
+
+	t.Run("read limit", func(t *testing.T) {
+		_, _, url := newChatServer(t, NewWebSocketHandler(chat).WithReadLimit(32))
+		ws := dialChat(t, url, nil)
+
+		require.NoError(t, ws.WriteJSON(chatMessage{Room: "general", Text: strings.Repeat("a", 64)}))
+		assert.Equal(t, websocket.CloseMessageTooBig, readClose(t, ws))
+	})
+
+	t.Run("on connect", func(t *testing.T) {
+		handler := NewWebSocketHandler(chat).WithOnConnect(func(ctx context.Context, conn *WebSocketConn[chatReply]) error {
+			return conn.Send(chatReply{Echo: "welcome"})
+		})
+		_, _, url := newChatServer(t, handler)
+		ws := dialChat(t, url, nil)
+
+		var reply chatReply
+		require.NoError(t, ws.ReadJSON(&reply))
+		assert.Equal(t, "welcome", reply.Echo)
+	})
+}
+
+func TestWebSocketHandler_MessageLogging(t *testing.T) {
+	handler := NewWebSocketHandler(chat).WithMessageLogging(logmanager.MaskingConfig{FieldPattern: "token", Type: logmanager.FullMask})
+	app, _, url := newChatServer(t, handler)
+	ws := dialChat(t, url, nil)
+
+	require.NoError(t, ws.WriteJSON(chatMessage{Room: "general", Text: "hello", Token: "secret"}))
+	var reply chatReply
+	require.NoError(t, ws.ReadJSON(&reply))
+
+	waitLogged(t, app, 1)
+	segment := app.GetLastLoggedEntry()
+	assert.Equal(t, "websocket message", segment.Data["name"])
+	assert.Equal(t, map[string]any{"room": "general", "text": "hello", "token": "******"}, segment.Data["request"])
+}
+
+func TestWebSocketHandler_Origin(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler *WebSocketHandler[chatMessage, chatReply]
+		origin  string
+		allowed bool
+	}{
+		{"no origin", NewWebSocketHandler(chat), "", true},
+		{"same origin", NewWebSocketHandler(chat), "same", true},
+		{"other origin", NewWebSocketHandler(chat), "https://evil.example.com", false},
+		{"allowed pattern", NewWebSocketHandler(chat).WithAllowedOrigins("https://*.example.com"), "https://app.example.com", true},
+		{"not in the allowed origins", NewWebSocketHandler(chat).WithAllowedOrigins("https://*.example.com"), "https://example.org", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, url := newChatServer(t, tt.handler)
+			header := http.Header{}
+			switch tt.origin {
+			case "":
+			case "same":
+				header.Set("Origin", "http"+strings.TrimPrefix(strings.TrimSuffix(url, "/chat"), "ws"))
+			default:
+				header.Set("Origin", tt.origin)
+			}
+
+			ws, resp, err := websocket.DefaultDialer.Dial(url, header)
+			if tt.allowed {
+				require.NoError(t, err)
+				_ = ws.Close()
+				return
+			}
+			require.ErrorIs(t, err, websocket.ErrBadHandshake)
+			assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		})
+	}
+}
+
+func TestWebSocketHandler_MethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewWebSocketHandler(chat).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+}
+
+func TestWebSocketHandler_Keepalive(t *testing.T) {
+	closed := make(chan struct{})
+	handler := NewWebSocketHandler(chat).WithPingInterval(20 * time.Millisecond).
+		WithOnConnect(func(ctx context.Context, conn *WebSocketConn[chatReply]) error {
+			go func() {
+				<-ctx.Done()
+				close(closed)
+			}()
+			return nil
+		})
+	_, _, url := newChatServer(t, handler)
+
+	// The client answers pings while it reads only
+	ws := dialChat(t, url, nil)
+	pings := 0
+	ws.SetPingHandler(func(data string) error {
+		pings++
+		return ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	_ = ws.SetReadDeadline(time.Now().Add(150 * time.Millisecond))
+	_, _, err := ws.ReadMessage()
+	require.Error(t, err)
+	assert.Greater(t, pings, 1)
+	select {
+	case <-closed:
+		t.Fatal("a client answering the pings should stay connected")
+	default:
+	}
+
+	// Without reads, the pings are not answered and the server drops the connection
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the server did not drop a client without pongs")
+	}
+}
+
+func TestServer_Stop_ClosesWebSockets(t *testing.T) {
+	_, server, url := newChatServer(t, NewWebSocketHandler(chat))
+	ws := dialChat(t, url, nil)
+	require.NoError(t, ws.WriteJSON(chatMessage{Room: "general", Text: "hello"}))
+	var reply chatReply
+	require.NoError(t, ws.ReadJSON(&reply))
+
+	stopped := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		stopped <- server.Stop(ctx)
+	}()
+
+	assert.Equal(t, websocket.CloseGoingAway, readClose(t, ws))
+	select {
+	case err := <-stopped:
+		assert.NoError(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("Stop did not return once the connection closed")
+	}
+}
@@ -209,6 +209,12 @@ func RequestBodyAttributes(a *Attributes, r *http.Request) {
 		return
 	}
 
+	// Binary streams, e.g. file chunks, are neither read into memory nor logged
+	if strings.Contains(contentType, "octet-stream") {
+		headerAttributes(a, r.Header)
+		return
+	}
+
 	// Handle JSON and other content types
 	if nil == r.Body {
 		return
@@ -83,6 +83,23 @@ func TestRequestBodyAttributes(t *testing.T) {
 	}
 }
 
+func TestRequestBodyAttributesOctetStream(t *testing.T) {
+	for _, contentType := range []string{"application/octet-stream", "application/offset+octet-stream"} {
+		t.Run(contentType, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			body.Write([]byte{0x00, 0xff, 0x10})
+			req, _ := http.NewRequest("PATCH", "http://example.com/files/1", body)
+			req.Header.Set("Content-Type", contentType)
+
+			attrs := internal.NewAttributes()
+			internal.RequestBodyAttributes(attrs, req)
+
+			assert.Nil(t, attrs.Value().Get(internal.AttributeRequestBody))
+			assert.Equal(t, 3, body.Len(), "the body should not be read")
+		})
+	}
+}
+
 func TestRequestBodyAttributesMultipartFormData(t *testing.T) {
 	t.Run("multipart form with text fields", func(t *testing.T) {
 		body := &bytes.Buffer{}
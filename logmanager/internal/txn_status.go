@@ -46,8 +46,10 @@ func HasErrorInternalFromHttpStatusCode(statusCode int) bool {
 	return !isWarningStatusCode(statusCode)
 }
 
+// isResponseSuccess reports whether the status code ends a request successfully. 101 Switching Protocols
+// is the response of a successful WebSocket upgrade.
 func isResponseSuccess(code int) bool {
-	return (code >= 200 && code <= 299) || code == http.StatusTemporaryRedirect
+	return (code >= 200 && code <= 299) || code == http.StatusTemporaryRedirect || code == http.StatusSwitchingProtocols
 }
 
 func isWarningStatusCode(code int) bool {
@@ -43,6 +43,11 @@ func TestHasErrorInternalFromHttpStatusCode(t *testing.T) {
 			statusCode: http.StatusTemporaryRedirect,
 			expected:   false,
 		},
+		{
+			name:       "switching protocols status code",
+			statusCode: http.StatusSwitchingProtocols,
+			expected:   false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -59,6 +59,11 @@ func (rw *replacementResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, erro
 	return nil, nil, errors.New("http.Hijacker interface not supported")
 }
 
+// Unwrap returns the original writer, so http.ResponseController reaches it, e.g. to set write deadlines
+func (rw *replacementResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.original
+}
+
 // Push implements http.Pusher interface for HTTP/2 server push
 func (rw *replacementResponseWriter) Push(target string, opts *http.PushOptions) error {
 	if pusher, ok := rw.original.(http.Pusher); ok {
@@ -81,7 +86,8 @@ func headersJustWritten(tx *TxnRecord, code int, _ http.Header) {
 }
 
 func bodyJustWritten(tx *TxnRecord, body []byte) {
-	if nil == tx {
+	// The body of a stream is not captured
+	if nil == tx || tx.streaming {
 		return
 	}
 
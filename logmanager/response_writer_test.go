@@ -280,6 +280,16 @@ func TestReplacementResponseWriter_InterfacePreservation(t *testing.T) {
 	})
 }
 
+func TestReplacementResponseWriter_Unwrap(t *testing.T) {
+	mock := httptest.NewRecorder()
+	rw := &replacementResponseWriter{
+		thd:      &TxnRecord{attrs: internal.NewAttributes()},
+		original: mock,
+	}
+
+	assert.Same(t, mock, rw.Unwrap(), "http.ResponseController should reach the original writer")
+}
+
 func TestReplacementResponseWriter_FlushBeforeWrite(t *testing.T) {
 	t.Run("Flush before Write writes headers with StatusOK", func(t *testing.T) {
 		mock := &mockFlusher{
@@ -62,6 +62,11 @@ type ApiSegment struct {
 
 	// Request is an HTTP request struct used for initiating transactions in the ApiSegment.
 	Request *http.Request
+
+	// Host overrides the host recorded for the segment. This field can be empty.
+	// It is useful when the request host does not identify the target, such as
+	// a request sent over a Unix domain socket.
+	Host string
 }
 
 // StartApiSegment initializes a new transaction record for an API segment using the provided ApiSegment struct.
@@ -83,6 +88,9 @@ func StartApiSegment(i ApiSegment) *TxnRecord {
 
 	txn := tx.AddTxn(i.Name, TxnTypeApi)
 	txn.SetWebRequest(i.Request)
+	if i.Host != "" {
+		txn.attrs.Value().AddString(internal.AttributeRequestHost, i.Host)
+	}
 
 	// Set OTel span attributes for HTTP client
 	if txn.otelSpan != nil && !txn.otelSpan.IsNil() && i.Request != nil {
@@ -302,6 +302,17 @@ func TestStartApiSegment(t *testing.T) {
 			checkTraceIdHeader: true,
 			expectedTraceId:    "1234567890", // This is the trace ID set in testdata.NewRequestWithCtx()
 		},
+		{
+			name: "it should be ok with host override",
+			i: logmanager.ApiSegment{
+				Name:    "a",
+				Request: testdata.NewRequestWithCtx(),
+				Host:    "/var/run/agent.sock",
+			},
+			wanNil:             false,
+			checkTraceIdHeader: true,
+			expectedTraceId:    "1234567890", // This is the trace ID set in testdata.NewRequestWithCtx()
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -9,7 +9,8 @@ import (
 func (txn *TxnRecord) writeLog() {
 	statusCode := txn.attrs.Value().GetInt(internal.AttributeResponseCode)
 	if txn.isHttp() {
-		if internal.HasErrorInternalFromHttpStatusCode(statusCode) {
+		// A recovered panic keeps its own error message
+		if internal.HasErrorInternalFromHttpStatusCode(statusCode) && txn.panicValue == "" {
 			txn.error = errors.New("internal server error")
 		}
 		if internal.HasErrorBusinessFromHttpStatusCode(statusCode) {
@@ -42,6 +43,11 @@ func (txn *TxnRecord) reset() {
 	txn.hasBusinessError = false
 	txn.skipRequest = false
 	txn.skipResponse = false
+	txn.panicValue = ""
+	txn.panicStack = ""
+	txn.streaming = false
+	txn.streamEvents = 0
+	txn.streamDuration = 0
 }
 
 func (txn *TxnRecord) extractValues() map[string]interface{} {
@@ -68,6 +74,16 @@ func (txn *TxnRecord) extractValues() map[string]interface{} {
 		values["tags"] = txn.tags
 	}
 
+	if txn.panicValue != "" {
+		values["panic"] = txn.panicValue
+		values["stack"] = txn.panicStack
+	}
+
+	if txn.streaming {
+		values["stream_events"] = txn.streamEvents
+		values["stream_duration"] = txn.streamDuration.Milliseconds()
+	}
+
 	if !txn.skipHeaders {
 		headers := internal.Header{
 			Data:           internal.ToMapString(txn.attrs.Value().Get(internal.AttributeRequestHeaders)),
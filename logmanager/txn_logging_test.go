@@ -5,7 +5,9 @@ import (
 	"github.com/SALT-Indonesia/salt-pkg/logmanager"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestTxnRecord_NoticeError(t *testing.T) {
@@ -44,6 +46,61 @@ func TestTxnRecord_NoticeError(t *testing.T) {
 	}
 }
 
+func TestTxnRecord_NoticePanic(t *testing.T) {
+	var nilTxn *logmanager.TxnRecord
+	nilTxn.NoticePanic("boom", "stack")
+
+	app := logmanager.NewTestableApplication()
+	app.ResetLoggedEntries()
+
+	txn := app.Application.StartHttp("panic-trace", "GET /orders")
+	txn.NoticePanic("boom", "main.handler\n\t/app/main.go:10")
+	txn.SetResponseBodyAndCode([]byte(`{"status":false}`), 500)
+	txn.End()
+
+	assert.Equal(t, 1, app.CountLoggedEntries())
+	assert.Equal(t, logrus.ErrorLevel, app.GetLoggedLevel())
+	assert.Equal(t, "panic: boom", app.GetLoggedMessage(), "Should keep the panic instead of the generic internal server error")
+	assert.Equal(t, "boom", app.GetLoggedField("panic"))
+	assert.Equal(t, "main.handler\n\t/app/main.go:10", app.GetLoggedField("stack"))
+}
+
+func TestTxnRecord_NoticeStream(t *testing.T) {
+	var nilTxn *logmanager.TxnRecord
+	nilTxn.StartStream()
+	nilTxn.NoticeStream(1, time.Second, nil)
+
+	app := logmanager.NewTestableApplication()
+
+	t.Run("records the events and the duration instead of the body", func(t *testing.T) {
+		app.ResetLoggedEntries()
+		txn := app.Application.StartHttp("stream-trace", "GET /orders/{id}/events")
+		w := txn.SetWebResponseHttp(httptest.NewRecorder())
+		txn.StartStream()
+		_, _ = w.Write([]byte("data: {\"status\":\"paid\"}\n\n"))
+		txn.NoticeStream(3, 1500*time.Millisecond, nil)
+		txn.End()
+
+		assert.Equal(t, logrus.InfoLevel, app.GetLoggedLevel())
+		assert.Equal(t, 3, app.GetLoggedField("stream_events"))
+		assert.Equal(t, int64(1500), app.GetLoggedField("stream_duration"))
+		assert.Nil(t, app.GetLoggedField("response"))
+	})
+
+	t.Run("records the error of a started stream", func(t *testing.T) {
+		app.ResetLoggedEntries()
+		txn := app.Application.StartHttp("stream-trace", "GET /orders/{id}/events")
+		w := txn.SetWebResponseHttp(httptest.NewRecorder())
+		txn.StartStream()
+		w.WriteHeader(200)
+		txn.NoticeStream(1, time.Second, errors.New("order feed closed"))
+		txn.End()
+
+		assert.Equal(t, logrus.ErrorLevel, app.GetLoggedLevel())
+		assert.Equal(t, "order feed closed", app.GetLoggedMessage())
+	})
+}
+
 func TestTxnRecord_End_APIWithBusinessError(t *testing.T) {
 	app := logmanager.NewTestableApplication()
 	app.ResetLoggedEntries()
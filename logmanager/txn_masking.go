@@ -50,6 +50,23 @@ func (txn *TxnRecord) SetWebRequestRawMasked(body interface{}, r WebRequest, mas
 	internal.RequestBodyAttribute(txn.attrs, maskedBody)
 }
 
+// SetRequestValueMasked adds a request body value to the transaction attributes with masking applied to it,
+// e.g. for the messages of a segment.
+func (txn *TxnRecord) SetRequestValueMasked(value interface{}, maskingConfigs []MaskingConfig) {
+	if nil == txn {
+		return
+	}
+
+	maskedValue := value
+	if len(maskingConfigs) > 0 && value != nil {
+		internalConfigs := ConvertMaskingConfigs(maskingConfigs)
+		jsonMasker := internal.NewJSONMasker(internalConfigs)
+		maskedValue = jsonMasker.MaskData(value)
+	}
+
+	txn.attrs.Value().Add(internal.AttributeRequestBody, maskedValue)
+}
+
 // Helper functions for JSON parsing and marshaling in masked methods
 func parseJSON(data []byte) (interface{}, error) {
 	var result interface{}
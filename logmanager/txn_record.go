@@ -1,6 +1,7 @@
 package logmanager
 
 import (
+	"fmt"
 	"github.com/SALT-Indonesia/salt-pkg/logmanager/internal"
 	otellog "github.com/SALT-Indonesia/salt-pkg/logmanager/otel"
 	"github.com/sirupsen/logrus"
@@ -25,6 +26,10 @@ type TxnRecord struct {
 	skipRequest, skipResponse bool
 	skipHeaders               bool
 	exposeAllHeader           bool
+	panicValue, panicStack    string
+	streaming                 bool
+	streamEvents              int
+	streamDuration            time.Duration
 	// OpenTelemetry span
 	otelSpan *otellog.Span
 }
@@ -69,6 +74,19 @@ func (txn *TxnRecord) NoticeError(err error) {
 	txn.End()
 }
 
+// NoticePanic records a recovered panic as the internal error of the transaction. The panic value and the
+// stack trace are added to the log entry as the "panic" and "stack" fields. Unlike NoticeError, it does not
+// end the transaction.
+func (txn *TxnRecord) NoticePanic(value interface{}, stack string) {
+	if nil == txn {
+		return
+	}
+
+	txn.panicValue = fmt.Sprint(value)
+	txn.panicStack = stack
+	txn.error = fmt.Errorf("panic: %v", value)
+}
+
 // End marks the end time of the transaction, calculates its duration, and writes a log entry for the transaction record.
 func (txn *TxnRecord) End() {
 	if nil == txn || txn.start.IsZero() {
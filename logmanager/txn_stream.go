@@ -0,0 +1,30 @@
+package logmanager
+
+import "time"
+
+// StartStream marks the response of the transaction as a stream, e.g. Server-Sent Events. The body written
+// afterwards is not captured, so a long-lived stream is not buffered in the log entry; the stream is
+// recorded with NoticeStream instead.
+func (txn *TxnRecord) StartStream() {
+	if nil == txn {
+		return
+	}
+	txn.streaming = true
+}
+
+// NoticeStream records the number of events sent on the stream and its duration, added to the log entry
+// as the "stream_events" and "stream_duration" (milliseconds) fields. A non-nil err is recorded as the
+// error of the transaction, since the status code of a started stream cannot report it. It does not end
+// the transaction.
+func (txn *TxnRecord) NoticeStream(events int, duration time.Duration, err error) {
+	if nil == txn {
+		return
+	}
+
+	txn.streaming = true
+	txn.streamEvents = events
+	txn.streamDuration = duration
+	if err != nil {
+		txn.error = err
+	}
+}
@@ -319,6 +319,24 @@ func TestTxnRecord_End_WithMaskedData(t *testing.T) {
 	assert.Equal(t, logrus.InfoLevel, app.GetLoggedLevel(), "Should log at Info level for successful transaction")
 }
 
+func TestTxnRecord_SetRequestValueMasked(t *testing.T) {
+	app := logmanager.NewTestableApplication()
+	app.ResetLoggedEntries()
+
+	tx := app.Application.StartHttp("masked-segment-trace", "GET /chat")
+	txn := tx.AddTxn("websocket message", logmanager.TxnTypeOther)
+
+	txn.SetRequestValueMasked(map[string]interface{}{"text": "hello", "card_number": "4111111111111111"},
+		[]logmanager.MaskingConfig{{FieldPattern: "card_number", Type: logmanager.PartialMask, ShowLast: 4}})
+	txn.End()
+
+	assert.Equal(t, 1, app.CountLoggedEntries())
+	assert.Equal(t, map[string]interface{}{"text": "hello", "card_number": "************1111"}, app.GetLoggedField("request"))
+
+	var nilTxn *logmanager.TxnRecord
+	assert.NotPanics(t, func() { nilTxn.SetRequestValueMasked("value", nil) })
+}
+
 func TestTxnRecord_SetWebRequest_MultipartFormData(t *testing.T) {
 	app := logmanager.NewTestableApplication()
 	app.ResetLoggedEntries()